@@ -38,6 +38,7 @@ type (
 	VarDeclarationStmt struct {
 		Token    token.Token // token.Mut or token.Const
 		Name     *Identifier
+		Type     *Identifier // optional, names the type whose zero value initializes the variable
 		Value    Expr
 		Constant bool
 	}
@@ -68,6 +69,42 @@ type (
 		Condition Expr
 		Body      *BlockStmt
 	}
+
+	ThrowStmt struct {
+		Token token.Token
+		Value Expr
+	}
+
+	// TryStmt has a Catch, a Finally or both. Param is bound to the thrown value in Catch
+	TryStmt struct {
+		Token   token.Token
+		Body    *BlockStmt
+		Param   *Identifier
+		Catch   *BlockStmt
+		Finally *BlockStmt
+	}
+
+	// ImportStmt binds the module at Path to Alias, or just the exports in Names for import { a, b } from "path"
+	ImportStmt struct {
+		Token token.Token
+		Path  string
+		Alias *Identifier
+		Names []*Identifier
+	}
+
+	// ExportStmt declares a variable that the modules importing this one can use
+	ExportStmt struct {
+		Token       token.Token
+		Declaration *VarDeclarationStmt
+	}
+
+	// FieldAssignmentStmt sets a field of the struct Object evaluates to, p.x = 3
+	FieldAssignmentStmt struct {
+		Token  token.Token
+		Object Expr
+		Field  *Identifier
+		Value  Expr
+	}
 )
 
 // Expressions and literals
@@ -143,19 +180,92 @@ type (
 		Token       token.Token
 		Condition   Expr
 		Consequence *BlockStmt
+		ElseIfs     []*ElseIfBranch // checked in order when Condition is not truthy
 		Alternative *BlockStmt
 	}
 
+	// ElseIfBranch is an `else if` or `elseif` branch of an IfExpr
+	ElseIfBranch struct {
+		Token       token.Token
+		Condition   Expr
+		Consequence *BlockStmt
+	}
+
 	CallExpr struct {
 		Token     token.Token
 		Function  Expr
 		Arguments []Expr
 	}
 
+	// MatchExpr is the value of the body of the first arm whose pattern matches Subject and whose guard holds, or
+	// null when no arm does
+	MatchExpr struct {
+		Token   token.Token
+		Subject Expr
+		Arms    []*MatchArm
+	}
+
+	// MatchArm is `pattern if guard => body`, Guard is nil for an arm without one
+	MatchArm struct {
+		Token   token.Token
+		Pattern Pattern
+		Guard   Expr
+		Body    *BlockStmt
+	}
+
+	// MethodCallExpr is Receiver.Method(Arguments). It calls the field, export or key Method of Receiver when there
+	// is one, and Method(Receiver, Arguments) otherwise
+	MethodCallExpr struct {
+		Token     token.Token
+		Receiver  Expr
+		Method    *Identifier
+		Arguments []Expr
+		Optional  bool // a?.f() evaluates to null instead of calling f on a null Receiver
+	}
+
 	IndexExpr struct {
-		Token token.Token
-		Left  Expr
-		Index Expr
+		Token    token.Token
+		Left     Expr
+		Index    Expr
+		Optional bool // ?[ and ?. evaluate to null instead of indexing a null Left
+		Strict   bool // set in strict-null mode, a missing index or key is an error instead of null
+	}
+
+	// SliceExpr is Left[Start:End], a bound that is left out is nil
+	SliceExpr struct {
+		Token    token.Token
+		Left     Expr
+		Start    Expr
+		End      Expr
+		Optional bool
+	}
+
+	// StructDefinition is a struct type. struct Point { x, y } declares the constant Point with one
+	StructDefinition struct {
+		Token  token.Token
+		Name   string
+		Fields []*Identifier
+	}
+
+	// EnumDefinition is an enum type. enum Result { Ok(value), Err(msg) } declares the constant Result with one
+	EnumDefinition struct {
+		Token    token.Token
+		Name     string
+		Variants []*EnumVariant
+	}
+
+	// EnumVariant is a variant of an enum definition, Fields is empty for a variant without a payload
+	EnumVariant struct {
+		Name   *Identifier
+		Fields []*Identifier
+	}
+
+	// StructLiteral builds a struct of the type Type evaluates to, Point{x: 1, y: 2}. Fields left out are null
+	StructLiteral struct {
+		Token  token.Token
+		Type   Expr
+		Fields []*Identifier
+		Values []Expr
 	}
 )
 
@@ -212,6 +322,18 @@ func (f *FunctionLiteral) TokenLiteral() string {
 	return f.Token.Literal
 }
 
+func (m *MatchExpr) TokenLiteral() string {
+	return m.Token.Literal
+}
+
+func (m *MatchArm) TokenLiteral() string {
+	return m.Token.Literal
+}
+
+func (m *MethodCallExpr) TokenLiteral() string {
+	return m.Token.Literal
+}
+
 func (c *CallExpr) TokenLiteral() string {
 	return c.Token.Literal
 }
@@ -232,6 +354,10 @@ func (i *IndexExpr) TokenLiteral() string {
 	return i.Token.Literal
 }
 
+func (s *SliceExpr) TokenLiteral() string {
+	return s.Token.Literal
+}
+
 func (n *NullLiteral) TokenLiteral() string {
 	return n.Token.Literal
 }
@@ -240,6 +366,38 @@ func (f *ForStmt) TokenLiteral() string {
 	return f.Token.Literal
 }
 
+func (t *ThrowStmt) TokenLiteral() string {
+	return t.Token.Literal
+}
+
+func (s *StructDefinition) TokenLiteral() string {
+	return s.Token.Literal
+}
+
+func (e *EnumDefinition) TokenLiteral() string {
+	return e.Token.Literal
+}
+
+func (s *StructLiteral) TokenLiteral() string {
+	return s.Token.Literal
+}
+
+func (f *FieldAssignmentStmt) TokenLiteral() string {
+	return f.Token.Literal
+}
+
+func (i *ImportStmt) TokenLiteral() string {
+	return i.Token.Literal
+}
+
+func (e *ExportStmt) TokenLiteral() string {
+	return e.Token.Literal
+}
+
+func (t *TryStmt) TokenLiteral() string {
+	return t.Token.Literal
+}
+
 func (h *HashLiteral) TokenLiteral() string {
 	return h.Token.Literal
 }
@@ -268,6 +426,9 @@ func (v *VarDeclarationStmt) String() string {
 
 	out.WriteString(v.TokenLiteral() + " ")
 	out.WriteString(v.Name.String())
+	if v.Type != nil {
+		out.WriteString(" " + v.Type.String())
+	}
 	out.WriteString(" = ")
 
 	if v.Value != nil {
@@ -322,6 +483,108 @@ func (f *ForStmt) String() string {
 	return out.String()
 }
 
+func (t *ThrowStmt) String() string {
+	var out bytes.Buffer
+
+	out.WriteString(t.TokenLiteral() + " ")
+	out.WriteString(t.Value.String())
+	out.WriteString(";")
+
+	return out.String()
+}
+
+func (s *StructDefinition) String() string {
+	fields := []string{}
+	for _, field := range s.Fields {
+		fields = append(fields, field.String())
+	}
+
+	return "struct " + s.Name + " {" + strings.Join(fields, ", ") + "}"
+}
+
+func (e *EnumDefinition) String() string {
+	variants := []string{}
+	for _, variant := range e.Variants {
+		variants = append(variants, variant.String())
+	}
+
+	return "enum " + e.Name + " {" + strings.Join(variants, ", ") + "}"
+}
+
+func (v *EnumVariant) String() string {
+	if len(v.Fields) == 0 {
+		return v.Name.String()
+	}
+
+	fields := []string{}
+	for _, field := range v.Fields {
+		fields = append(fields, field.String())
+	}
+
+	return v.Name.String() + "(" + strings.Join(fields, ", ") + ")"
+}
+
+func (s *StructLiteral) String() string {
+	fields := []string{}
+	for i, field := range s.Fields {
+		fields = append(fields, field.String()+": "+s.Values[i].String())
+	}
+
+	return s.Type.String() + "{" + strings.Join(fields, ", ") + "}"
+}
+
+func (f *FieldAssignmentStmt) String() string {
+	return f.Object.String() + "." + f.Field.String() + " = " + f.Value.String()
+}
+
+func (i *ImportStmt) String() string {
+	var out bytes.Buffer
+
+	out.WriteString("import ")
+	if i.Names != nil {
+		names := []string{}
+		for _, name := range i.Names {
+			names = append(names, name.String())
+		}
+		out.WriteString("{" + strings.Join(names, ", ") + "} from ")
+	}
+	out.WriteString(`"` + i.Path + `"`)
+	if i.Names == nil {
+		out.WriteString(" as " + i.Alias.String())
+	}
+	out.WriteString(";")
+
+	return out.String()
+}
+
+func (e *ExportStmt) String() string {
+	return "export " + e.Declaration.String()
+}
+
+func (t *TryStmt) String() string {
+	var out bytes.Buffer
+
+	out.WriteString("try {")
+	out.WriteString(t.Body.String())
+	out.WriteString("}")
+
+	if t.Catch != nil {
+		out.WriteString(" catch (")
+		out.WriteString(t.Param.String())
+		out.WriteString(") {")
+		out.WriteString(t.Catch.String())
+		out.WriteString("}")
+	}
+
+	if t.Finally != nil {
+		out.WriteString(" finally {")
+		out.WriteString(t.Finally.String())
+		out.WriteString("}")
+	}
+
+	return out.String()
+}
+
 // Expressions
 func (i *Identifier) String() string {
 	return i.Value
@@ -365,6 +628,43 @@ func (c *CallExpr) String() string {
 	return out.String()
 }
 
+func (m *MatchExpr) String() string {
+	arms := []string{}
+	for _, arm := range m.Arms {
+		arms = append(arms, arm.String())
+	}
+
+	return "match " + m.Subject.String() + " {" + strings.Join(arms, ", ") + "}"
+}
+
+func (m *MatchArm) String() string {
+	var out bytes.Buffer
+
+	out.WriteString(m.Pattern.String())
+	if m.Guard != nil {
+		out.WriteString(" if ")
+		out.WriteString(m.Guard.String())
+	}
+	out.WriteString(" => ")
+	out.WriteString(m.Body.String())
+
+	return out.String()
+}
+
+func (m *MethodCallExpr) String() string {
+	args := make([]string, 0)
+	for _, arg := range m.Arguments {
+		args = append(args, arg.String())
+	}
+
+	dot := "."
+	if m.Optional {
+		dot = "?."
+	}
+
+	return m.Receiver.String() + dot + m.Method.String() + "(" + strings.Join(args, ", ") + ")"
+}
+
 func (v *VarAssignmentStmt) String() string {
 	var out bytes.Buffer
 
@@ -380,6 +680,9 @@ func (i *IndexExpr) String() string {
 
 	out.WriteString("(")
 	out.WriteString(i.Left.String())
+	if i.Optional {
+		out.WriteString("?")
+	}
 	out.WriteString("[")
 	out.WriteString(i.Index.String())
 	out.WriteString("])")
@@ -387,6 +690,27 @@ func (i *IndexExpr) String() string {
 	return out.String()
 }
 
+func (s *SliceExpr) String() string {
+	var out bytes.Buffer
+
+	out.WriteString("(")
+	out.WriteString(s.Left.String())
+	if s.Optional {
+		out.WriteString("?")
+	}
+	out.WriteString("[")
+	if s.Start != nil {
+		out.WriteString(s.Start.String())
+	}
+	out.WriteString(":")
+	if s.End != nil {
+		out.WriteString(s.End.String())
+	}
+	out.WriteString("])")
+
+	return out.String()
+}
+
 func (i *IfExpr) String() string {
 	var out bytes.Buffer
 
@@ -395,6 +719,13 @@ func (i *IfExpr) String() string {
 	out.WriteString(" ")
 	out.WriteString(i.Consequence.String())
 
+	for _, branch := range i.ElseIfs {
+		out.WriteString("else if")
+		out.WriteString(branch.Condition.String())
+		out.WriteString(" ")
+		out.WriteString(branch.Consequence.String())
+	}
+
 	if i.Alternative != nil {
 		out.WriteString("else ")
 		out.WriteString(i.Alternative.String())
@@ -493,26 +824,37 @@ func (m *MacroLiteral) String() string {
 }
 
 // Statements
-func (v *VarDeclarationStmt) statementNode() {}
-func (r *ReturnStmt) statementNode()         {}
-func (e *ExpressionStmt) statementNode()     {}
-func (b *BlockStmt) statementNode()          {}
-func (v *VarAssignmentStmt) statementNode()  {}
-func (f *ForStmt) statementNode()            {}
+func (v *VarDeclarationStmt) statementNode()  {}
+func (r *ReturnStmt) statementNode()          {}
+func (e *ExpressionStmt) statementNode()      {}
+func (b *BlockStmt) statementNode()           {}
+func (v *VarAssignmentStmt) statementNode()   {}
+func (f *ForStmt) statementNode()             {}
+func (t *ThrowStmt) statementNode()           {}
+func (t *TryStmt) statementNode()             {}
+func (i *ImportStmt) statementNode()          {}
+func (f *FieldAssignmentStmt) statementNode() {}
+func (e *ExportStmt) statementNode()          {}
 
 // Expressions
-func (i *Identifier) expressionNode()      {}
-func (i *IntegerLiteral) expressionNode()  {}
-func (p *PrefixExpr) expressionNode()      {}
-func (i *InfixExpr) expressionNode()       {}
-func (b *BooleanLiteral) expressionNode()  {}
-func (i *IfExpr) expressionNode()          {}
-func (f *FunctionLiteral) expressionNode() {}
-func (c *CallExpr) expressionNode()        {}
-func (s *StringLiteral) expressionNode()   {}
-func (a *ArrayLiteral) expressionNode()    {}
-func (i *IndexExpr) expressionNode()       {}
-func (n *NullLiteral) expressionNode()     {}
-func (h *HashLiteral) expressionNode()     {}
-func (f *FloatLiteral) expressionNode()    {}
-func (m *MacroLiteral) expressionNode()    {}
+func (i *Identifier) expressionNode()       {}
+func (i *IntegerLiteral) expressionNode()   {}
+func (p *PrefixExpr) expressionNode()       {}
+func (i *InfixExpr) expressionNode()        {}
+func (b *BooleanLiteral) expressionNode()   {}
+func (i *IfExpr) expressionNode()           {}
+func (f *FunctionLiteral) expressionNode()  {}
+func (c *CallExpr) expressionNode()         {}
+func (m *MethodCallExpr) expressionNode()   {}
+func (m *MatchExpr) expressionNode()        {}
+func (s *StringLiteral) expressionNode()    {}
+func (a *ArrayLiteral) expressionNode()     {}
+func (i *IndexExpr) expressionNode()        {}
+func (s *SliceExpr) expressionNode()        {}
+func (s *StructDefinition) expressionNode() {}
+func (e *EnumDefinition) expressionNode()   {}
+func (s *StructLiteral) expressionNode()    {}
+func (n *NullLiteral) expressionNode()      {}
+func (h *HashLiteral) expressionNode()      {}
+func (f *FloatLiteral) expressionNode()     {}
+func (m *MacroLiteral) expressionNode()     {}
@@ -18,12 +18,32 @@ func Modify(node Node, modifier ModifierFunc) Node {
 	case *IndexExpr:
 		node.Left, _ = Modify(node.Left, modifier).(Expr)
 		node.Index, _ = Modify(node.Index, modifier).(Expr)
+	case *SliceExpr:
+		node.Left, _ = Modify(node.Left, modifier).(Expr)
+		if node.Start != nil {
+			node.Start, _ = Modify(node.Start, modifier).(Expr)
+		}
+		if node.End != nil {
+			node.End, _ = Modify(node.End, modifier).(Expr)
+		}
 	case *IfExpr:
 		node.Condition, _ = Modify(node.Condition, modifier).(Expr)
 		node.Consequence, _ = Modify(node.Consequence, modifier).(*BlockStmt)
+		for _, branch := range node.ElseIfs {
+			branch.Condition, _ = Modify(branch.Condition, modifier).(Expr)
+			branch.Consequence, _ = Modify(branch.Consequence, modifier).(*BlockStmt)
+		}
 		if node.Alternative != nil {
 			node.Alternative, _ = Modify(node.Alternative, modifier).(*BlockStmt)
 		}
+	case *MatchExpr:
+		node.Subject, _ = Modify(node.Subject, modifier).(Expr)
+		for _, arm := range node.Arms {
+			if arm.Guard != nil {
+				arm.Guard, _ = Modify(arm.Guard, modifier).(Expr)
+			}
+			arm.Body, _ = Modify(arm.Body, modifier).(*BlockStmt)
+		}
 	case *BlockStmt:
 		for i, _ := range node.Stmts {
 			node.Stmts[i], _ = Modify(node.Stmts[i], modifier).(Stmt)
@@ -37,6 +57,21 @@ func Modify(node Node, modifier ModifierFunc) Node {
 	case *ForStmt:
 		node.Condition, _ = Modify(node.Condition, modifier).(Expr)
 		node.Body, _ = Modify(node.Body, modifier).(*BlockStmt)
+	case *ThrowStmt:
+		node.Value, _ = Modify(node.Value, modifier).(Expr)
+	case *FieldAssignmentStmt:
+		node.Object, _ = Modify(node.Object, modifier).(Expr)
+		node.Value, _ = Modify(node.Value, modifier).(Expr)
+	case *ExportStmt:
+		node.Declaration.Value, _ = Modify(node.Declaration.Value, modifier).(Expr)
+	case *TryStmt:
+		node.Body, _ = Modify(node.Body, modifier).(*BlockStmt)
+		if node.Catch != nil {
+			node.Catch, _ = Modify(node.Catch, modifier).(*BlockStmt)
+		}
+		if node.Finally != nil {
+			node.Finally, _ = Modify(node.Finally, modifier).(*BlockStmt)
+		}
 	case *FunctionLiteral:
 		for i, _ := range node.Parameters {
 			node.Parameters[i], _ = Modify(node.Parameters[i], modifier).(*Identifier)
@@ -46,6 +81,11 @@ func Modify(node Node, modifier ModifierFunc) Node {
 		for i, _ := range node.Elements {
 			node.Elements[i], _ = Modify(node.Elements[i], modifier).(Expr)
 		}
+	case *StructLiteral:
+		node.Type, _ = Modify(node.Type, modifier).(Expr)
+		for i := range node.Values {
+			node.Values[i], _ = Modify(node.Values[i], modifier).(Expr)
+		}
 	case *HashLiteral:
 		newPairs := make(map[Expr]Expr)
 		for key, val := range node.Pairs {
@@ -0,0 +1,136 @@
+package ast
+
+import (
+	"quonk/token"
+	"strings"
+)
+
+// Pattern is what an arm of a match expression tests its subject against. A pattern can bind parts of the subject
+// to names that the guard and body of the arm see
+type Pattern interface {
+	Node
+	patternNode()
+}
+
+type (
+	// LiteralPattern matches a value equal to a number, string, boolean or null literal
+	LiteralPattern struct {
+		Token token.Token
+		Value Expr
+	}
+
+	// WildcardPattern is _, it matches anything and binds nothing
+	WildcardPattern struct {
+		Token token.Token
+	}
+
+	// BindingPattern matches anything and binds it to Name
+	BindingPattern struct {
+		Token token.Token
+		Name  *Identifier
+	}
+
+	// ArrayPattern matches an array or tuple with exactly as many elements, each matching the pattern in its place
+	ArrayPattern struct {
+		Token    token.Token
+		Elements []Pattern
+	}
+
+	// HashPattern matches a hash that has each of Keys, with a value matching the pattern of the key. Other keys of
+	// the hash are ignored
+	HashPattern struct {
+		Token  token.Token
+		Keys   []Expr
+		Values []Pattern
+	}
+
+	// ValuePattern matches a value equal to a dotted name such as Color.Red
+	ValuePattern struct {
+		Token token.Token
+		Value Expr
+	}
+
+	// VariantPattern matches a variant of Variant, such as Result.Ok(v), whose payload fields match Fields in order
+	VariantPattern struct {
+		Token   token.Token
+		Variant Expr
+		Fields  []Pattern
+	}
+)
+
+func (l *LiteralPattern) TokenLiteral() string {
+	return l.Token.Literal
+}
+
+func (w *WildcardPattern) TokenLiteral() string {
+	return w.Token.Literal
+}
+
+func (b *BindingPattern) TokenLiteral() string {
+	return b.Token.Literal
+}
+
+func (a *ArrayPattern) TokenLiteral() string {
+	return a.Token.Literal
+}
+
+func (h *HashPattern) TokenLiteral() string {
+	return h.Token.Literal
+}
+
+func (v *ValuePattern) TokenLiteral() string {
+	return v.Token.Literal
+}
+
+func (v *VariantPattern) TokenLiteral() string {
+	return v.Token.Literal
+}
+
+func (l *LiteralPattern) String() string {
+	return l.Value.String()
+}
+
+func (w *WildcardPattern) String() string {
+	return "_"
+}
+
+func (b *BindingPattern) String() string {
+	return b.Name.String()
+}
+
+func (a *ArrayPattern) String() string {
+	return "[" + joinPatterns(a.Elements) + "]"
+}
+
+func (h *HashPattern) String() string {
+	pairs := []string{}
+	for i, key := range h.Keys {
+		pairs = append(pairs, key.String()+": "+h.Values[i].String())
+	}
+
+	return "{" + strings.Join(pairs, ", ") + "}"
+}
+
+func (v *ValuePattern) String() string {
+	return v.Value.String()
+}
+
+func (v *VariantPattern) String() string {
+	return v.Variant.String() + "(" + joinPatterns(v.Fields) + ")"
+}
+
+func joinPatterns(patterns []Pattern) string {
+	strs := []string{}
+	for _, p := range patterns {
+		strs = append(strs, p.String())
+	}
+	return strings.Join(strs, ", ")
+}
+
+func (l *LiteralPattern) patternNode()  {}
+func (w *WildcardPattern) patternNode() {}
+func (b *BindingPattern) patternNode()  {}
+func (a *ArrayPattern) patternNode()    {}
+func (h *HashPattern) patternNode()     {}
+func (v *ValuePattern) patternNode()    {}
+func (v *VariantPattern) patternNode()  {}
@@ -10,6 +10,15 @@ type Instructions []byte
 
 type Opcode byte
 
+// SourceLine records the source line of the instruction at Offset
+type SourceLine struct {
+	Offset int
+	Line   int
+}
+
+// LineTable is ordered by Offset
+type LineTable []SourceLine
+
 const (
 	OpConstant Opcode = iota
 	OpPop
@@ -46,6 +55,25 @@ const (
 	OpClosure
 	OpGetFree
 	OpCurrentClosure
+	OpJumpNull
+	OpJumpNotNull
+	OpStrictIndex
+	OpThrow
+	OpCell
+	OpLoadCell
+	OpSetLocalCell
+	OpSetFree
+	OpSlice
+	OpModule
+	OpStruct
+	OpGetField
+	OpSetField
+	OpSetFieldByName
+	OpCallMethod
+	OpMatchLength
+	OpMatchKeys
+	OpMatchVariant
+	OpJumpTable
 )
 
 type (
@@ -91,6 +119,25 @@ var definitions = map[Opcode]*Definition{
 	OpClosure:            {"OpClosure", []int{2, 1}},
 	OpGetFree:            {"OpGetFree", []int{1}},
 	OpCurrentClosure:     {"OpCurrentClosure", []int{}},
+	OpJumpNull:           {"OpJumpNull", []int{2}},
+	OpJumpNotNull:        {"OpJumpNotNull", []int{2}},
+	OpStrictIndex:        {"OpStrictIndex", []int{}},
+	OpThrow:              {"OpThrow", []int{}},
+	OpCell:               {"OpCell", []int{}},
+	OpLoadCell:           {"OpLoadCell", []int{}},
+	OpSetLocalCell:       {"OpSetLocalCell", []int{1}},
+	OpSetFree:            {"OpSetFree", []int{1}},
+	OpSlice:              {"OpSlice", []int{}},
+	OpModule:             {"OpModule", []int{2}},
+	OpStruct:             {"OpStruct", []int{2}},
+	OpGetField:           {"OpGetField", []int{2}},
+	OpSetField:           {"OpSetField", []int{2}},
+	OpSetFieldByName:     {"OpSetFieldByName", []int{}},
+	OpCallMethod:         {"OpCallMethod", []int{2, 1}},
+	OpMatchLength:        {"OpMatchLength", []int{2}},
+	OpMatchKeys:          {"OpMatchKeys", []int{2}},
+	OpMatchVariant:       {"OpMatchVariant", []int{1}},
+	OpJumpTable:          {"OpJumpTable", []int{2}},
 }
 
 func Lookup(op byte) (*Definition, error) {
@@ -174,6 +221,19 @@ func (ins Instructions) String() string {
 	return out.String()
 }
 
+// LineAt returns the line of the closest recorded instruction at or before offset, 0 if there is none
+func (lt LineTable) LineAt(offset int) int {
+	line := 0
+	for _, sl := range lt {
+		if sl.Offset > offset {
+			break
+		}
+		line = sl.Line
+	}
+
+	return line
+}
+
 func ReadUint8(ins Instructions) uint8 {
 	return uint8(ins[0])
 }
@@ -55,6 +55,31 @@ func TestInstructionString(t *testing.T) {
 	}
 }
 
+func TestLineTable(t *testing.T) {
+	lines := LineTable{{Offset: 0, Line: 1}, {Offset: 5, Line: 3}, {Offset: 9, Line: 4}}
+
+	tests := []struct {
+		offset   int
+		expected int
+	}{
+		{0, 1},
+		{4, 1},
+		{5, 3},
+		{6, 3},
+		{12, 4},
+	}
+
+	for _, tt := range tests {
+		if line := lines.LineAt(tt.offset); line != tt.expected {
+			t.Errorf("wrong line at %d. want=%d, got=%d", tt.offset, tt.expected, line)
+		}
+	}
+
+	if line := (LineTable{}).LineAt(3); line != 0 {
+		t.Errorf("empty table should return 0. got=%d", line)
+	}
+}
+
 func TestReadOperands(t *testing.T) {
 	tests := []struct {
 		op        Opcode
@@ -0,0 +1,139 @@
+package compiler
+
+import "quonk/ast"
+
+// capturedNames collects every identifier referenced inside the function literals nested in body.
+// It over-approximates the variables a closure can capture, which at worst boxes a local that did not need it
+func capturedNames(body ast.Node) map[string]bool {
+	names := make(map[string]bool)
+	collectCaptured(body, false, names)
+	return names
+}
+
+func collectCaptured(node ast.Node, nested bool, names map[string]bool) {
+	switch node := node.(type) {
+	case *ast.Program:
+		for _, s := range node.Stmts {
+			collectCaptured(s, nested, names)
+		}
+	case *ast.BlockStmt:
+		for _, s := range node.Stmts {
+			collectCaptured(s, nested, names)
+		}
+	case *ast.ExpressionStmt:
+		collectCaptured(node.Expr, nested, names)
+	case *ast.VarDeclarationStmt:
+		collectCaptured(node.Value, nested, names)
+	case *ast.ExportStmt:
+		collectCaptured(node.Declaration.Value, nested, names)
+	case *ast.VarAssignmentStmt:
+		collectCaptured(node.Identifier, nested, names)
+		collectCaptured(node.Value, nested, names)
+	case *ast.FieldAssignmentStmt:
+		collectCaptured(node.Object, nested, names)
+		collectCaptured(node.Value, nested, names)
+	case *ast.ReturnStmt:
+		collectCaptured(node.ReturnValue, nested, names)
+	case *ast.ThrowStmt:
+		collectCaptured(node.Value, nested, names)
+	case *ast.ForStmt:
+		collectCaptured(node.Condition, nested, names)
+		collectCaptured(node.Body, nested, names)
+	case *ast.TryStmt:
+		collectCaptured(node.Body, nested, names)
+		if node.Catch != nil {
+			collectCaptured(node.Catch, nested, names)
+		}
+		if node.Finally != nil {
+			collectCaptured(node.Finally, nested, names)
+		}
+	case *ast.Identifier:
+		if nested {
+			names[node.Value] = true
+		}
+	case *ast.PrefixExpr:
+		collectCaptured(node.Right, nested, names)
+	case *ast.InfixExpr:
+		collectCaptured(node.Left, nested, names)
+		collectCaptured(node.Right, nested, names)
+	case *ast.IfExpr:
+		collectCaptured(node.Condition, nested, names)
+		collectCaptured(node.Consequence, nested, names)
+		for _, branch := range node.ElseIfs {
+			collectCaptured(branch.Condition, nested, names)
+			collectCaptured(branch.Consequence, nested, names)
+		}
+		if node.Alternative != nil {
+			collectCaptured(node.Alternative, nested, names)
+		}
+	case *ast.IndexExpr:
+		collectCaptured(node.Left, nested, names)
+		collectCaptured(node.Index, nested, names)
+	case *ast.SliceExpr:
+		collectCaptured(node.Left, nested, names)
+		if node.Start != nil {
+			collectCaptured(node.Start, nested, names)
+		}
+		if node.End != nil {
+			collectCaptured(node.End, nested, names)
+		}
+	case *ast.CallExpr:
+		collectCaptured(node.Function, nested, names)
+		for _, arg := range node.Arguments {
+			collectCaptured(arg, nested, names)
+		}
+	case *ast.MatchExpr:
+		collectCaptured(node.Subject, nested, names)
+		for _, arm := range node.Arms {
+			collectCapturedPattern(arm.Pattern, nested, names)
+			if arm.Guard != nil {
+				collectCaptured(arm.Guard, nested, names)
+			}
+			collectCaptured(arm.Body, nested, names)
+		}
+	case *ast.MethodCallExpr:
+		collectCaptured(node.Receiver, nested, names)
+		collectCaptured(node.Method, nested, names)
+		for _, arg := range node.Arguments {
+			collectCaptured(arg, nested, names)
+		}
+	case *ast.ArrayLiteral:
+		for _, el := range node.Elements {
+			collectCaptured(el, nested, names)
+		}
+	case *ast.HashLiteral:
+		for key, val := range node.Pairs {
+			collectCaptured(key, nested, names)
+			collectCaptured(val, nested, names)
+		}
+	case *ast.StructLiteral:
+		collectCaptured(node.Type, nested, names)
+		for _, val := range node.Values {
+			collectCaptured(val, nested, names)
+		}
+	case *ast.FunctionLiteral:
+		collectCaptured(node.Body, true, names)
+	}
+}
+
+// collectCapturedPattern collects the identifiers of the values a pattern compares with, the names it binds are
+// declared rather than referenced
+func collectCapturedPattern(pattern ast.Pattern, nested bool, names map[string]bool) {
+	switch pattern := pattern.(type) {
+	case *ast.ValuePattern:
+		collectCaptured(pattern.Value, nested, names)
+	case *ast.VariantPattern:
+		collectCaptured(pattern.Variant, nested, names)
+		for _, field := range pattern.Fields {
+			collectCapturedPattern(field, nested, names)
+		}
+	case *ast.ArrayPattern:
+		for _, element := range pattern.Elements {
+			collectCapturedPattern(element, nested, names)
+		}
+	case *ast.HashPattern:
+		for _, value := range pattern.Values {
+			collectCapturedPattern(value, nested, names)
+		}
+	}
+}
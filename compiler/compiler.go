@@ -4,7 +4,9 @@ import (
 	"fmt"
 	"quonk/ast"
 	"quonk/code"
+	"quonk/module"
 	"quonk/object"
+	"quonk/token"
 	"sort"
 )
 
@@ -15,11 +17,19 @@ type Compiler struct {
 
 	scopes     []CompilationScope
 	scopeIndex int
+
+	modules *Modules // shared with the compilers of the modules
+	exports []string // the names exported so far by the file being compiled
+
+	warnings []string
 }
 
 type Bytecode struct {
 	Instructions code.Instructions
 	Constants    []object.Object
+	Handlers     []object.ExceptionHandler
+	Lines        code.LineTable
+	NumLocals    int // slots of the main frame used by variables declared in blocks
 }
 
 type EmittedInstruction struct {
@@ -31,6 +41,11 @@ type CompilationScope struct {
 	instructions        code.Instructions
 	lastInstruction     EmittedInstruction
 	previousInstruction EmittedInstruction
+
+	handlers []object.ExceptionHandler
+	lines    code.LineTable
+	// finally blocks of the try statements being compiled, a return compiles them inline before leaving
+	finallyBlocks []*ast.BlockStmt
 }
 
 func New() *Compiler {
@@ -41,8 +56,8 @@ func New() *Compiler {
 	}
 
 	symbolTable := NewSymbolTable()
-	for i, v := range object.Builtins {
-		symbolTable.DefineBuiltin(i, v.Name)
+	for i, name := range object.BuiltinNames() {
+		symbolTable.DefineBuiltin(i, name)
 	}
 
 	return &Compiler{
@@ -50,19 +65,35 @@ func New() *Compiler {
 		symbolTable: symbolTable,
 		scopes:      []CompilationScope{mainScope},
 		scopeIndex:  0,
+		modules:     NewModules(),
 	}
 }
 
-func NewWithState(symbolTable *SymbolTable, constants []object.Object) *Compiler {
+// SetFile tells the compiler which file the program comes from, the files it imports are relative to it
+func (c *Compiler) SetFile(path string) {
+	c.modules.loader = module.NewLoader(path)
+}
+
+// Warnings are about code that compiles but is likely wrong, such as a match that misses a variant of its enum. The
+// warnings of imported modules are included
+func (c *Compiler) Warnings() []string {
+	return c.warnings
+}
+
+func NewWithState(symbolTable *SymbolTable, constants []object.Object, modules *Modules) *Compiler {
 	compiler := New()
 	compiler.symbolTable = symbolTable
 	compiler.constants = constants
+	compiler.modules = modules
 	return compiler
 }
 
 func (c *Compiler) Compile(node ast.Node) error {
 	switch node := node.(type) {
 	case *ast.Program:
+		// variables declared in blocks of the program are locals of the main frame, and can be captured too
+		c.symbolTable.captured = capturedNames(node)
+
 		for _, s := range node.Stmts {
 			err := c.Compile(s)
 			if err != nil {
@@ -88,13 +119,14 @@ func (c *Compiler) Compile(node ast.Node) error {
 		}
 		sym, fromOuter, ok := c.symbolTable.Resolve(node.Name.Value)
 
-		// if the variable exists in this scope, cannot redeclare
-		if ok && !fromOuter && sym.Scope != FunctionScope {
+		// if the variable exists in this scope, cannot redeclare. Builtins can be shadowed
+		if ok && !fromOuter && sym.Scope != FunctionScope && sym.Scope != BuiltinScope {
 			return fmt.Errorf("variable %s already declared on line %d", node.Name.Value, node.Token.Line)
 		}
 
 		if node.Constant {
 			symbol := c.symbolTable.DefineImmutable(node.Name.Value)
+			c.recordType(node.Name.Value, node.Value)
 
 			err := c.Compile(node.Value)
 			if err != nil {
@@ -114,11 +146,7 @@ func (c *Compiler) Compile(node ast.Node) error {
 				return err
 			}
 
-			if symbol.Scope == GlobalScope {
-				c.emit(code.OpSetMutableGlobal, symbol.Index)
-			} else {
-				c.emit(code.OpSetMutableLocal, symbol.Index)
-			}
+			c.storeSymbol(symbol, true)
 		}
 	case *ast.VarAssignmentStmt:
 		err := c.Compile(node.Value)
@@ -135,17 +163,96 @@ func (c *Compiler) Compile(node ast.Node) error {
 			return fmt.Errorf("cannot assign to constant %s on line %d", node.Identifier.Value, node.Token.Line)
 		}
 
-		if symbol.Scope == GlobalScope {
-			c.emit(code.OpSetMutableGlobal, symbol.Index)
-		} else {
-			c.emit(code.OpSetMutableLocal, symbol.Index)
-		}
+		c.storeSymbol(symbol, false)
 	case *ast.ReturnStmt:
 		err := c.Compile(node.ReturnValue)
 		if err != nil {
 			return err
 		}
+
+		// run the finally blocks of the enclosing try statements, innermost first
+		finallyBlocks := c.scopes[c.scopeIndex].finallyBlocks
+		for i := len(finallyBlocks) - 1; i >= 0; i-- {
+			// a return inside the finally block itself must not compile it again
+			c.scopes[c.scopeIndex].finallyBlocks = finallyBlocks[:i]
+
+			err := c.compileBlock(finallyBlocks[i])
+			if err != nil {
+				return err
+			}
+		}
+		c.scopes[c.scopeIndex].finallyBlocks = finallyBlocks
+
 		c.emit(code.OpReturnValue)
+	case *ast.ThrowStmt:
+		err := c.Compile(node.Value)
+		if err != nil {
+			return err
+		}
+		c.emit(code.OpThrow)
+	case *ast.TryStmt:
+		if node.Finally != nil {
+			c.scopes[c.scopeIndex].finallyBlocks = append(c.scopes[c.scopeIndex].finallyBlocks, node.Finally)
+		}
+
+		bodyStart := len(c.currentInstructions())
+		err := c.compileBlock(node.Body)
+		if err != nil {
+			return err
+		}
+		bodyEnd := len(c.currentInstructions())
+
+		// emit an OpJump past the catch block with operand to be replaced later
+		jumpPos := c.emit(code.OpJump, 9999)
+
+		catchStart, catchEnd := bodyStart, bodyEnd
+		if node.Catch != nil {
+			catchStart = len(c.currentInstructions())
+			c.addHandler(bodyStart, bodyEnd, catchStart)
+
+			// the vm pushes the thrown value before jumping here, so we bind it to the catch parameter, which is
+			// scoped to the catch block
+			c.symbolTable = NewBlockSymbolTable(c.symbolTable)
+			c.storeSymbol(c.symbolTable.DefineMutable(node.Param.Value), true)
+
+			err = c.Compile(node.Catch)
+			c.symbolTable = c.symbolTable.Outer
+			if err != nil {
+				return err
+			}
+			catchEnd = len(c.currentInstructions())
+		}
+
+		c.changeOperand(jumpPos, len(c.currentInstructions()))
+
+		if node.Finally != nil {
+			finallyBlocks := c.scopes[c.scopeIndex].finallyBlocks
+			c.scopes[c.scopeIndex].finallyBlocks = finallyBlocks[:len(finallyBlocks)-1]
+
+			err = c.compileBlock(node.Finally)
+			if err != nil {
+				return err
+			}
+
+			// emit an OpJump past the rethrowing copy of the finally block with operand to be replaced later
+			skipPos := c.emit(code.OpJump, 9999)
+
+			// anything thrown and not caught above runs the finally block and is then thrown again. The thrown value
+			// stays on the stack while the finally block runs
+			c.addHandler(catchStart, catchEnd, len(c.currentInstructions()))
+
+			err = c.compileBlock(node.Finally)
+			if err != nil {
+				return err
+			}
+			c.emit(code.OpThrow)
+
+			c.changeOperand(skipPos, len(c.currentInstructions()))
+		}
+
+		// like for loops, try statements produce null
+		c.emit(code.OpNull)
+		c.emit(code.OpPop)
 	case *ast.ForStmt:
 		conditionPos := len(c.currentInstructions())
 
@@ -156,7 +263,7 @@ func (c *Compiler) Compile(node ast.Node) error {
 		// emit with operand to be replaced later
 		jumpNotTruthyPos := c.emit(code.OpJumpNotTruthy, 9999)
 
-		err = c.Compile(node.Body)
+		err = c.compileBlock(node.Body)
 		if err != nil {
 			return err
 		}
@@ -166,6 +273,23 @@ func (c *Compiler) Compile(node ast.Node) error {
 		c.emit(code.OpPop) // this clears the condition value from the stack
 
 	case *ast.InfixExpr:
+		if node.Operator == "??" {
+			err := c.Compile(node.Left)
+			if err != nil {
+				return err
+			}
+			// keep the left value if it is not null, otherwise discard it and evaluate the right side
+			jumpNotNullPos := c.emit(code.OpJumpNotNull, 9999)
+
+			err = c.Compile(node.Right)
+			if err != nil {
+				return err
+			}
+
+			c.changeOperand(jumpNotNullPos, len(c.currentInstructions()))
+			return nil
+		}
+
 		if node.Operator == "<" || node.Operator == "<=" {
 			err := c.Compile(node.Right)
 			if err != nil {
@@ -235,59 +359,126 @@ func (c *Compiler) Compile(node ast.Node) error {
 			return fmt.Errorf("unknown operator %s on line %d", node.Operator, node.Token.Line)
 		}
 	case *ast.IfExpr:
-		// we don't need to update t here because we're not bubbling the value back up like in expressions
-		err := c.Compile(node.Condition)
-		if err != nil {
-			return err
-		}
-		// emit with operand to be replaced later
-		jumpNotTruthyPos := c.emit(code.OpJumpNotTruthy, 9999)
+		// the if branch and every else if branch share the same layout, and each one jumps to the end of the chain
+		branches := append([]*ast.ElseIfBranch{{Token: node.Token, Condition: node.Condition, Consequence: node.Consequence}}, node.ElseIfs...)
+		jumpPositions := make([]int, 0, len(branches))
 
-		err = c.Compile(node.Consequence)
-		if err != nil {
-			return err
-		}
+		for _, branch := range branches {
+			// we don't need to update t here because we're not bubbling the value back up like in expressions
+			err := c.Compile(branch.Condition)
+			if err != nil {
+				return err
+			}
+			// emit with operand to be replaced later
+			jumpNotTruthyPos := c.emit(code.OpJumpNotTruthy, 9999)
 
-		// remove last pop after compiling consequence so we don't inadvertently pop too many times
-		if c.lastInstructionIs(code.OpPop) {
-			c.removeLastPop()
-		}
+			err = c.compileBlock(branch.Consequence)
+			if err != nil {
+				return err
+			}
 
-		//emit an OpJump with operand to be replaced later
-		jumpPos := c.emit(code.OpJump, 9999)
+			// remove last pop after compiling consequence so we don't inadvertently pop too many times. A block that
+			// does not end with an expression produces null
+			if c.lastInstructionIs(code.OpPop) {
+				c.removeLastPop()
+			} else {
+				c.emit(code.OpNull)
+			}
 
-		afterConsequencePos := len(c.currentInstructions())
-		c.changeOperand(jumpNotTruthyPos, afterConsequencePos)
+			//emit an OpJump with operand to be replaced later
+			jumpPositions = append(jumpPositions, c.emit(code.OpJump, 9999))
 
-		// only if there is no alternative do we jump to immediately after the consequence
+			afterConsequencePos := len(c.currentInstructions())
+			c.changeOperand(jumpNotTruthyPos, afterConsequencePos)
+		}
+
+		// only if there is no alternative do we jump to immediately after the last consequence
 		if node.Alternative == nil {
 			c.emit(code.OpNull)
 		} else {
 
-			err = c.Compile(node.Alternative)
+			err := c.compileBlock(node.Alternative)
 			if err != nil {
 				return err
 			}
 
 			if c.lastInstructionIs(code.OpPop) {
 				c.removeLastPop()
+			} else {
+				c.emit(code.OpNull)
 			}
 		}
 
 		afterAlternativePos := len(c.currentInstructions())
-		c.changeOperand(jumpPos, afterAlternativePos)
+		for _, jumpPos := range jumpPositions {
+			c.changeOperand(jumpPos, afterAlternativePos)
+		}
 	case *ast.IndexExpr:
+		if node.Token.Type == token.Dot {
+			err := c.checkVariant(node.Left, node.Index.(*ast.StringLiteral).Value, node.Token.Line)
+			if err != nil {
+				return err
+			}
+
+			compiled, err := c.compileFieldRead(node)
+			if compiled || err != nil {
+				return err
+			}
+		}
+
 		err := c.Compile(node.Left)
 		if err != nil {
 			return err
 		}
 
+		jumpNullPos := -1
+		if node.Optional {
+			// a null left side is left on the stack as the result and the index is never evaluated
+			jumpNullPos = c.emit(code.OpJumpNull, 9999)
+		}
+
 		err = c.Compile(node.Index)
 		if err != nil {
 			return err
 		}
 
-		c.emit(code.OpIndex)
+		if node.Strict {
+			c.emit(code.OpStrictIndex)
+		} else {
+			c.emit(code.OpIndex)
+		}
+
+		if node.Optional {
+			c.changeOperand(jumpNullPos, len(c.currentInstructions()))
+		}
+	case *ast.SliceExpr:
+		err := c.Compile(node.Left)
+		if err != nil {
+			return err
+		}
+
+		jumpNullPos := -1
+		if node.Optional {
+			jumpNullPos = c.emit(code.OpJumpNull, 9999)
+		}
+
+		// a bound that is left out is pushed as null
+		for _, bound := range []ast.Expr{node.Start, node.End} {
+			if bound == nil {
+				c.emit(code.OpNull)
+				continue
+			}
+			err = c.Compile(bound)
+			if err != nil {
+				return err
+			}
+		}
+
+		c.emit(code.OpSlice)
+
+		if node.Optional {
+			c.changeOperand(jumpNullPos, len(c.currentInstructions()))
+		}
 	case *ast.Identifier:
 		symbol, _, ok := c.symbolTable.Resolve(node.Value)
 		if !ok {
@@ -310,7 +501,59 @@ func (c *Compiler) Compile(node ast.Node) error {
 			}
 		}
 
-		c.emit(code.OpCall, len(node.Arguments))
+		callPos := c.emit(code.OpCall, len(node.Arguments))
+		c.addLine(callPos, node.Token.Line)
+	case *ast.MatchExpr:
+		return c.compileMatch(node)
+	case *ast.MethodCallExpr:
+		err := c.checkVariant(node.Receiver, node.Method.Value, node.Token.Line)
+		if err != nil {
+			return err
+		}
+
+		// the function the call falls back on goes below the receiver, where a call expects its callee. It is null
+		// when the name resolves to nothing and the receiver may still have a member with it
+		symbol, _, ok := c.symbolTable.Resolve(node.Method.Value)
+		switch {
+		case ok:
+			c.loadSymbol(symbol)
+		case c.mayHaveMember(node.Receiver, node.Method.Value):
+			c.emit(code.OpNull)
+		default:
+			return fmt.Errorf("undefined variable %s on line %d", node.Method.Value, node.Token.Line)
+		}
+
+		err = c.Compile(node.Receiver)
+		if err != nil {
+			return err
+		}
+
+		jumpNullPos := -1
+		if node.Optional {
+			jumpNullPos = c.emit(code.OpJumpNull, 9999)
+		}
+
+		for _, arg := range node.Arguments {
+			err := c.Compile(arg)
+			if err != nil {
+				return err
+			}
+		}
+
+		name := c.addConstant(&object.String{Value: node.Method.Value})
+		callPos := c.emit(code.OpCallMethod, name, len(node.Arguments))
+		c.addLine(callPos, node.Token.Line)
+
+		if node.Optional {
+			// a null receiver skips the arguments and the call, and is left as the result in place of the function
+			// below it
+			skipPos := c.emit(code.OpJump, 9999)
+			c.changeOperand(jumpNullPos, len(c.currentInstructions()))
+			c.emit(code.OpPop)
+			c.emit(code.OpPop)
+			c.emit(code.OpNull)
+			c.changeOperand(skipPos, len(c.currentInstructions()))
+		}
 	case *ast.IntegerLiteral:
 		integer := &object.Integer{Value: node.Value}
 		c.emit(code.OpConstant, c.addConstant(integer))
@@ -360,8 +603,29 @@ func (c *Compiler) Compile(node ast.Node) error {
 		}
 
 		c.emit(code.OpHash, len(node.Pairs)*2)
+	case *ast.StructDefinition:
+		c.emit(code.OpConstant, c.addConstant(structType(node)))
+	case *ast.EnumDefinition:
+		c.emit(code.OpConstant, c.addConstant(enumType(node)))
+	case *ast.StructLiteral:
+		return c.compileStructLiteral(node)
+	case *ast.FieldAssignmentStmt:
+		return c.compileFieldAssignment(node)
+	case *ast.ImportStmt:
+		return c.compileImport(node)
+	case *ast.ExportStmt:
+		if !c.atTopLevel() {
+			return fmt.Errorf("export must be at the top level of a file on line %d", node.Token.Line)
+		}
+
+		err := c.Compile(node.Declaration)
+		if err != nil {
+			return err
+		}
+		c.exports = append(c.exports, node.Declaration.Name.Value)
 	case *ast.FunctionLiteral:
 		c.enterScope()
+		c.symbolTable.captured = capturedNames(node.Body)
 
 		if node.Name != "" {
 			c.symbolTable.DefineFunctionName(node.Name)
@@ -385,19 +649,23 @@ func (c *Compiler) Compile(node ast.Node) error {
 		}
 
 		freeSymbols := c.symbolTable.FreeSymbols
-		numLocals := c.symbolTable.numDefinitions
+		numLocals := c.symbolTable.NumLocals()
+		handlers := c.scopes[c.scopeIndex].handlers
+		lines := c.scopes[c.scopeIndex].lines
 		// leave scope so we can load free symbols into enclosing scope
 		instructions := c.leaveScope()
 
-		// iterate over free symbols and load them onto stack
+		// iterate over free symbols and load them onto stack, cells are captured as the cell itself
 		for _, s := range freeSymbols {
-			c.loadSymbol(s)
+			c.loadSymbolSlot(s)
 		}
 
 		compiledFn := &object.CompiledFunction{
 			Instructions:  instructions,
 			NumLocals:     numLocals,
 			NumParameters: len(node.Parameters),
+			Handlers:      handlers,
+			Lines:         lines,
 		}
 
 		fnIdx := c.addConstant(compiledFn)
@@ -411,6 +679,9 @@ func (c *Compiler) Bytecode() *Bytecode {
 	return &Bytecode{
 		Instructions: c.currentInstructions(),
 		Constants:    c.constants,
+		Handlers:     c.scopes[c.scopeIndex].handlers,
+		Lines:        c.scopes[c.scopeIndex].lines,
+		NumLocals:    c.symbolTable.NumLocals(),
 	}
 }
 
@@ -480,6 +751,15 @@ func (c *Compiler) changeOperand(opPos int, operand int) {
 	c.replaceInstruction(opPos, newInstruction)
 }
 
+func (c *Compiler) addHandler(start, end, target int) {
+	handler := object.ExceptionHandler{Start: start, End: end, Target: target}
+	c.scopes[c.scopeIndex].handlers = append(c.scopes[c.scopeIndex].handlers, handler)
+}
+
+func (c *Compiler) addLine(pos, line int) {
+	c.scopes[c.scopeIndex].lines = append(c.scopes[c.scopeIndex].lines, code.SourceLine{Offset: pos, Line: line})
+}
+
 func (c *Compiler) currentInstructions() code.Instructions {
 	return c.scopes[c.scopeIndex].instructions
 }
@@ -516,6 +796,15 @@ func (c *Compiler) replaceLastPopWithReturn() {
 }
 
 func (c *Compiler) loadSymbol(s Symbol) {
+	c.loadSymbolSlot(s)
+
+	if s.IsCell {
+		c.emit(code.OpLoadCell)
+	}
+}
+
+// loadSymbolSlot pushes what is stored for the symbol, which is the cell itself for a captured mutable local
+func (c *Compiler) loadSymbolSlot(s Symbol) {
 	switch s.Scope {
 	case GlobalScope:
 		c.emit(code.OpGetGlobal, s.Index)
@@ -529,3 +818,32 @@ func (c *Compiler) loadSymbol(s Symbol) {
 		c.emit(code.OpCurrentClosure)
 	}
 }
+
+// storeSymbol pops the top of the stack into the variable. Declaring a cell variable creates a new cell,
+// assigning to one writes through the cell so every closure sharing it sees the new value
+func (c *Compiler) storeSymbol(s Symbol, declaration bool) {
+	switch s.Scope {
+	case GlobalScope:
+		c.emit(code.OpSetMutableGlobal, s.Index)
+	case LocalScope:
+		if !s.IsCell {
+			c.emit(code.OpSetMutableLocal, s.Index)
+		} else if declaration {
+			c.emit(code.OpCell)
+			c.emit(code.OpSetMutableLocal, s.Index)
+		} else {
+			c.emit(code.OpSetLocalCell, s.Index)
+		}
+	case FreeScope:
+		c.emit(code.OpSetFree, s.Index)
+	}
+}
+
+// compileBlock compiles the block of an if, for or try in its own scope, so its declarations end with it
+func (c *Compiler) compileBlock(block *ast.BlockStmt) error {
+	c.symbolTable = NewBlockSymbolTable(c.symbolTable)
+	err := c.Compile(block)
+	c.symbolTable = c.symbolTable.Outer
+
+	return err
+}
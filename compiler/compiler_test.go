@@ -290,6 +290,46 @@ func TestConditionals(t *testing.T) {
 				code.Make(code.OpPop),
 			},
 		},
+		{
+			source: `
+			if (true) { 10 } else if (false) { 20 } elseif (true) { 30 }; 3333;
+			`,
+			expectedConstants: []interface{}{10, 20, 30, 3333},
+			expectedInstructions: []code.Instructions{
+				// 0000
+				code.Make(code.OpTrue),
+				// 0001
+				code.Make(code.OpJumpNotTruthy, 10),
+				// 0004
+				code.Make(code.OpConstant, 0),
+				// 0007
+				code.Make(code.OpJump, 31),
+				// 0010
+				code.Make(code.OpFalse),
+				// 0011
+				code.Make(code.OpJumpNotTruthy, 20),
+				// 0014
+				code.Make(code.OpConstant, 1),
+				// 0017
+				code.Make(code.OpJump, 31),
+				// 0020
+				code.Make(code.OpTrue),
+				// 0021
+				code.Make(code.OpJumpNotTruthy, 30),
+				// 0024
+				code.Make(code.OpConstant, 2),
+				// 0027
+				code.Make(code.OpJump, 31),
+				// 0030
+				code.Make(code.OpNull),
+				// 0031
+				code.Make(code.OpPop),
+				// 0032
+				code.Make(code.OpConstant, 3),
+				// 0035
+				code.Make(code.OpPop),
+			},
+		},
 	}
 
 	runCompilerTests(t, tests)
@@ -608,6 +648,350 @@ func TestHashLiterals(t *testing.T) {
 	runCompilerTests(t, tests)
 }
 
+func TestStructs(t *testing.T) {
+	tests := []compilerTestCase{
+		{
+			// the type of p is known, so its fields are read and written by slot
+			source:            "struct P { x, y } const p = P{y: 1}; p.x = 2; p.y",
+			expectedConstants: []interface{}{"struct P {x, y}", "y", 1, 2},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpConstant, 0),
+				code.Make(code.OpSetImmutableGlobal, 0),
+				code.Make(code.OpGetGlobal, 0),
+				code.Make(code.OpConstant, 1),
+				code.Make(code.OpConstant, 2),
+				code.Make(code.OpStruct, 1),
+				code.Make(code.OpSetImmutableGlobal, 1),
+				code.Make(code.OpGetGlobal, 1),
+				code.Make(code.OpConstant, 3),
+				code.Make(code.OpSetField, 0),
+				code.Make(code.OpGetGlobal, 1),
+				code.Make(code.OpGetField, 1),
+				code.Make(code.OpPop),
+			},
+		},
+		{
+			// a mutable variable could hold anything, so its fields are looked up by name
+			source:            "mut p = null; p.x = 1; p.x",
+			expectedConstants: []interface{}{"x", 1, "x"},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpNull),
+				code.Make(code.OpSetMutableGlobal, 0),
+				code.Make(code.OpGetGlobal, 0),
+				code.Make(code.OpConstant, 0),
+				code.Make(code.OpConstant, 1),
+				code.Make(code.OpSetFieldByName),
+				code.Make(code.OpGetGlobal, 0),
+				code.Make(code.OpConstant, 2),
+				code.Make(code.OpIndex),
+				code.Make(code.OpPop),
+			},
+		},
+	}
+
+	runCompilerTests(t, tests)
+
+	errorTests := []struct {
+		source   string
+		expected string
+	}{
+		{"struct P { x } P{z: 1}", "struct P has no field z on line 1"},
+		{"struct P { x } const p = P{x: 1}; p.z", "struct P has no field z on line 1"},
+		{"struct P { x } const p = P{x: 1}; func() { p.z = 1; }", "struct P has no field z on line 1"},
+	}
+
+	for _, tt := range errorTests {
+		compiler := New()
+		err := compiler.Compile(parse(tt.source))
+		if err == nil {
+			t.Fatalf("expected compiler error %q, got nil", tt.expected)
+		}
+
+		if err.Error() != tt.expected {
+			t.Errorf("wrong compiler error. want=%q, got=%q", tt.expected, err.Error())
+		}
+	}
+}
+
+func TestEnums(t *testing.T) {
+	tests := []compilerTestCase{
+		{
+			source:            "enum Result { Ok(value), Err(msg) } Result.Ok(1)",
+			expectedConstants: []interface{}{"enum Result {Ok(value), Err(msg)}", 1, "Ok"},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpConstant, 0),
+				code.Make(code.OpSetImmutableGlobal, 0),
+				code.Make(code.OpNull),
+				code.Make(code.OpGetGlobal, 0),
+				code.Make(code.OpConstant, 1),
+				code.Make(code.OpCallMethod, 2, 1),
+				code.Make(code.OpPop),
+			},
+		},
+	}
+
+	runCompilerTests(t, tests)
+
+	errorTests := []struct {
+		source   string
+		expected string
+	}{
+		{"enum Color { Red } Color.Purple", "enum Color has no variant Purple on line 1"},
+		{"enum Result { Ok(value) } Result.Nope(1)", "enum Result has no variant Nope on line 1"},
+		{"enum Color { Red } func() { Color.Purple }", "enum Color has no variant Purple on line 1"},
+	}
+
+	for _, tt := range errorTests {
+		compiler := New()
+		err := compiler.Compile(parse(tt.source))
+		if err == nil {
+			t.Fatalf("expected compiler error %q, got nil", tt.expected)
+		}
+
+		if err.Error() != tt.expected {
+			t.Errorf("wrong compiler error. want=%q, got=%q", tt.expected, err.Error())
+		}
+	}
+}
+
+func TestMatch(t *testing.T) {
+	tests := []compilerTestCase{
+		{
+			// too few literals for a jump table, the arms are tried in turn
+			source:            "match (1) { 1 => 2, _ => 3 }",
+			expectedConstants: []interface{}{1, 1, 2, 3},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpConstant, 0),
+				code.Make(code.OpSetMutableLocal, 0),
+				// 0005
+				code.Make(code.OpGetLocal, 0),
+				code.Make(code.OpConstant, 1),
+				code.Make(code.OpEqual),
+				code.Make(code.OpJumpNotTruthy, 20),
+				code.Make(code.OpConstant, 2),
+				code.Make(code.OpJump, 27),
+				// 0020
+				code.Make(code.OpConstant, 3),
+				code.Make(code.OpJump, 27),
+				code.Make(code.OpNull),
+				// 0027
+				code.Make(code.OpPop),
+			},
+		},
+		{
+			source:            "match ([1]) { [x] if x => x }",
+			expectedConstants: []interface{}{1, 0},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpConstant, 0),
+				code.Make(code.OpArray, 1),
+				code.Make(code.OpSetMutableLocal, 0),
+				// 0008
+				code.Make(code.OpGetLocal, 0),
+				code.Make(code.OpMatchLength, 1),
+				code.Make(code.OpJumpNotTruthy, 34),
+				code.Make(code.OpGetLocal, 0),
+				code.Make(code.OpConstant, 1),
+				code.Make(code.OpIndex),
+				code.Make(code.OpSetMutableLocal, 1),
+				code.Make(code.OpGetLocal, 1),
+				code.Make(code.OpJumpNotTruthy, 34),
+				code.Make(code.OpGetLocal, 1),
+				code.Make(code.OpJump, 35),
+				// 0034
+				code.Make(code.OpNull),
+				// 0035
+				code.Make(code.OpPop),
+			},
+		},
+		{
+			source:            "match (1) { 1 => 10, 2 => 20, 3 => 30, 4 => 40, _ => 0 }",
+			expectedConstants: []interface{}{1, map[int]int{1: 13, 2: 19, 3: 25, 4: 31}, 10, 20, 30, 40, 0},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpConstant, 0),
+				code.Make(code.OpSetMutableLocal, 0),
+				code.Make(code.OpGetLocal, 0),
+				code.Make(code.OpJumpTable, 1),
+				code.Make(code.OpJump, 37),
+				// 0013
+				code.Make(code.OpConstant, 2),
+				code.Make(code.OpJump, 40),
+				// 0019
+				code.Make(code.OpConstant, 3),
+				code.Make(code.OpJump, 40),
+				// 0025
+				code.Make(code.OpConstant, 4),
+				code.Make(code.OpJump, 40),
+				// 0031
+				code.Make(code.OpConstant, 5),
+				code.Make(code.OpJump, 40),
+				// 0037
+				code.Make(code.OpConstant, 6),
+				// 0040
+				code.Make(code.OpPop),
+			},
+		},
+	}
+
+	runCompilerTests(t, tests)
+
+	warningTests := []struct {
+		source   string
+		expected []string
+	}{
+		{"enum C { R, G, B } match (C.R) { C.R => 1, C.G => 2 }", []string{"match on C is not exhaustive, missing C.B on line 1"}},
+		{"enum C { R, G } match (C.R) { C.R => 1, C.G if false => 2 }", []string{"match on C is not exhaustive, missing C.G on line 1"}},
+		{"enum O { Some(v), None } match (O.None) { O.Some(1) => 1, O.None => 2 }", []string{"match on O is not exhaustive, missing O.Some on line 1"}},
+		{"match (true) { true => 1 }", []string{"match on a boolean is not exhaustive, missing false on line 1"}},
+		{"enum C { R, G } match (C.R) { C.R => 1, C.G => 2 }", nil},
+		{"enum O { Some(v), None } match (O.None) { O.Some(v) => v, O.None => 2 }", nil},
+		{"enum C { R, G } match (C.R) { C.R => 1, _ => 2 }", nil},
+		{"enum C { R, G } mut D = C; match (C.R) { D.R => 1 }", nil},
+		{"match (1) { 1 => 1 }", nil},
+	}
+
+	for _, tt := range warningTests {
+		compiler := New()
+		err := compiler.Compile(parse(tt.source))
+		if err != nil {
+			t.Fatalf("compiler error: %s", err)
+		}
+
+		warnings := compiler.Warnings()
+		if len(warnings) != len(tt.expected) {
+			t.Fatalf("wrong warnings for %q. want=%q, got=%q", tt.source, tt.expected, warnings)
+		}
+		for i, warning := range tt.expected {
+			if warnings[i] != warning {
+				t.Errorf("wrong warning. want=%q, got=%q", warning, warnings[i])
+			}
+		}
+	}
+
+	errorTests := []struct {
+		source   string
+		expected string
+	}{
+		{"enum R { Ok(v) } match (R.Ok(1)) { R.Ok(a, b) => a }", "wrong number of fields in pattern for R.Ok. want=1, got=2 on line 1"},
+		{"enum R { Ok(v) } match (R.Ok(1)) { R.Nope(a) => a }", "enum R has no variant Nope on line 1"},
+	}
+
+	for _, tt := range errorTests {
+		compiler := New()
+		err := compiler.Compile(parse(tt.source))
+		if err == nil {
+			t.Fatalf("expected compiler error %q, got nil", tt.expected)
+		}
+
+		if err.Error() != tt.expected {
+			t.Errorf("wrong compiler error. want=%q, got=%q", tt.expected, err.Error())
+		}
+	}
+}
+
+func TestMethodCalls(t *testing.T) {
+	tests := []compilerTestCase{
+		{
+			// the function the method falls back on is pushed below the receiver
+			source:            "[1].len()",
+			expectedConstants: []interface{}{1, "len"},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpGetBuiltIn, 0),
+				code.Make(code.OpConstant, 0),
+				code.Make(code.OpArray, 1),
+				code.Make(code.OpCallMethod, 1, 0),
+				code.Make(code.OpPop),
+			},
+		},
+		{
+			// a name that resolves to nothing can still be a member of the receiver
+			source:            "mut m = null; m.area(2)",
+			expectedConstants: []interface{}{2, "area"},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpNull),
+				code.Make(code.OpSetMutableGlobal, 0),
+				code.Make(code.OpNull),
+				code.Make(code.OpGetGlobal, 0),
+				code.Make(code.OpConstant, 0),
+				code.Make(code.OpCallMethod, 1, 1),
+				code.Make(code.OpPop),
+			},
+		},
+		{
+			// a null receiver jumps past the call, and the function below it is replaced by null
+			source:            "mut m = null; m?.area(2)",
+			expectedConstants: []interface{}{2, "area"},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpNull),
+				code.Make(code.OpSetMutableGlobal, 0),
+				code.Make(code.OpNull),
+				code.Make(code.OpGetGlobal, 0),
+				code.Make(code.OpJumpNull, 21),
+				code.Make(code.OpConstant, 0),
+				code.Make(code.OpCallMethod, 1, 1),
+				code.Make(code.OpJump, 24),
+				code.Make(code.OpPop),
+				code.Make(code.OpPop),
+				code.Make(code.OpNull),
+				code.Make(code.OpPop),
+			},
+		},
+	}
+
+	runCompilerTests(t, tests)
+
+	errorTests := []struct {
+		source   string
+		expected string
+	}{
+		{"5.nope()", "undefined variable nope on line 1"},
+		{"const g = func() { [1].h() }; const h = func(a) { len(a) }; g()", "undefined variable h on line 1"},
+		{"struct P { x } const p = P{x: 1}; p.y()", "undefined variable y on line 1"},
+	}
+
+	for _, tt := range errorTests {
+		compiler := New()
+		err := compiler.Compile(parse(tt.source))
+		if err == nil {
+			t.Fatalf("expected compiler error %q, got nil", tt.expected)
+		}
+
+		if err.Error() != tt.expected {
+			t.Errorf("wrong compiler error. want=%q, got=%q", tt.expected, err.Error())
+		}
+	}
+}
+
+func TestSliceExpressions(t *testing.T) {
+	tests := []compilerTestCase{
+		{
+			source:            "[1, 2][1:2]",
+			expectedConstants: []interface{}{1, 2, 1, 2},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpConstant, 0),
+				code.Make(code.OpConstant, 1),
+				code.Make(code.OpArray, 2),
+				code.Make(code.OpConstant, 2),
+				code.Make(code.OpConstant, 3),
+				code.Make(code.OpSlice),
+				code.Make(code.OpPop),
+			},
+		},
+		{
+			source:            `"ab"[:1]`,
+			expectedConstants: []interface{}{"ab", 1},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpConstant, 0),
+				code.Make(code.OpNull),
+				code.Make(code.OpConstant, 1),
+				code.Make(code.OpSlice),
+				code.Make(code.OpPop),
+			},
+		},
+	}
+
+	runCompilerTests(t, tests)
+}
+
 func TestIndexExpressions(t *testing.T) {
 	tests := []compilerTestCase{
 		{
@@ -691,6 +1075,94 @@ func TestIndexExpressions(t *testing.T) {
 	runCompilerTests(t, tests)
 }
 
+func TestNullSafetyOperators(t *testing.T) {
+	tests := []compilerTestCase{
+		{
+			source:            "1 ?? 2",
+			expectedConstants: []interface{}{1, 2},
+			expectedInstructions: []code.Instructions{
+				// 0000
+				code.Make(code.OpConstant, 0),
+				// 0003
+				code.Make(code.OpJumpNotNull, 9),
+				// 0006
+				code.Make(code.OpConstant, 1),
+				// 0009
+				code.Make(code.OpPop),
+			},
+		},
+		{
+			"null?[1]",
+			[]interface{}{1},
+			[]code.Instructions{
+				// 0000
+				code.Make(code.OpNull),
+				// 0001
+				code.Make(code.OpJumpNull, 8),
+				// 0004
+				code.Make(code.OpConstant, 0),
+				// 0007
+				code.Make(code.OpIndex),
+				// 0008
+				code.Make(code.OpPop),
+			},
+		},
+	}
+
+	runCompilerTests(t, tests)
+}
+
+func TestTryStmts(t *testing.T) {
+	tests := []compilerTestCase{
+		{
+			source:            "try { throw 1; } catch (e) { e; }",
+			expectedConstants: []interface{}{1},
+			expectedInstructions: []code.Instructions{
+				// 0000
+				code.Make(code.OpConstant, 0),
+				// 0003
+				code.Make(code.OpThrow),
+				// 0004
+				code.Make(code.OpJump, 12),
+				// 0007
+				code.Make(code.OpSetMutableLocal, 0), // the catch parameter is scoped to the catch block
+				// 0009
+				code.Make(code.OpGetLocal, 0),
+				// 0011
+				code.Make(code.OpPop),
+				// 0012
+				code.Make(code.OpNull),
+				// 0013
+				code.Make(code.OpPop),
+			},
+		},
+	}
+
+	runCompilerTests(t, tests)
+
+	program := parse("try { 1; } catch (e) { 2; } finally { 3; }")
+	compiler := New()
+	err := compiler.Compile(program)
+	if err != nil {
+		t.Fatalf("compiler error: %s", err)
+	}
+
+	expected := []object.ExceptionHandler{
+		{Start: 0, End: 4, Target: 7},
+		{Start: 7, End: 13, Target: 20},
+	}
+	handlers := compiler.Bytecode().Handlers
+	if len(handlers) != len(expected) {
+		t.Fatalf("wrong number of handlers. want=%d, got=%d", len(expected), len(handlers))
+	}
+
+	for i, handler := range expected {
+		if handlers[i] != handler {
+			t.Errorf("wrong handler %d. want=%+v, got=%+v", i, handler, handlers[i])
+		}
+	}
+}
+
 func TestFunctions(t *testing.T) {
 	tests := []compilerTestCase{
 		{
@@ -1235,6 +1707,145 @@ func TestClosures(t *testing.T) {
 	runCompilerTests(t, tests)
 }
 
+func TestCapturedMutableLocals(t *testing.T) {
+	tests := []compilerTestCase{
+		{
+			source: `
+			func() {
+				mut count = 0;
+				const inc = func() { count = count + 1; count };
+				count = 10;
+				inc
+			}
+			`,
+			expectedConstants: []interface{}{
+				0,
+				1,
+				[]code.Instructions{
+					code.Make(code.OpGetFree, 0),
+					code.Make(code.OpLoadCell),
+					code.Make(code.OpConstant, 1),
+					code.Make(code.OpAdd),
+					code.Make(code.OpSetFree, 0),
+					code.Make(code.OpGetFree, 0),
+					code.Make(code.OpLoadCell),
+					code.Make(code.OpReturnValue),
+				},
+				10,
+				[]code.Instructions{
+					code.Make(code.OpConstant, 0),
+					code.Make(code.OpCell),
+					code.Make(code.OpSetMutableLocal, 0),
+					code.Make(code.OpGetLocal, 0), // the cell itself is captured
+					code.Make(code.OpClosure, 2, 1),
+					code.Make(code.OpSetImmutableLocal, 1),
+					code.Make(code.OpConstant, 3),
+					code.Make(code.OpSetLocalCell, 0),
+					code.Make(code.OpGetLocal, 1),
+					code.Make(code.OpReturnValue),
+				},
+			},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpClosure, 4, 0),
+				code.Make(code.OpPop),
+			},
+		},
+		{
+			// locals that no closure references are not boxed
+			source: `
+			func() {
+				mut count = 0;
+				count = 1;
+			}
+			`,
+			expectedConstants: []interface{}{
+				0,
+				1,
+				[]code.Instructions{
+					code.Make(code.OpConstant, 0),
+					code.Make(code.OpSetMutableLocal, 0),
+					code.Make(code.OpConstant, 1),
+					code.Make(code.OpSetMutableLocal, 0),
+					code.Make(code.OpReturn),
+				},
+			},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpClosure, 2, 0),
+				code.Make(code.OpPop),
+			},
+		},
+	}
+
+	runCompilerTests(t, tests)
+}
+
+func TestBlockScopes(t *testing.T) {
+	tests := []compilerTestCase{
+		{
+			source: `
+			if (true) { mut a = 1; a } else { mut b = 2; b }
+			`,
+			expectedConstants: []interface{}{1, 2},
+			expectedInstructions: []code.Instructions{
+				// 0000
+				code.Make(code.OpTrue),
+				// 0001
+				code.Make(code.OpJumpNotTruthy, 14),
+				// 0004
+				code.Make(code.OpConstant, 0),
+				// 0007
+				code.Make(code.OpSetMutableLocal, 0),
+				// 0009
+				code.Make(code.OpGetLocal, 0),
+				// 0011
+				code.Make(code.OpJump, 21),
+				// 0014
+				code.Make(code.OpConstant, 1),
+				// 0017
+				code.Make(code.OpSetMutableLocal, 0), // the alternative reuses the slot of the consequence
+				// 0019
+				code.Make(code.OpGetLocal, 0),
+				// 0021
+				code.Make(code.OpPop),
+			},
+		},
+	}
+
+	runCompilerTests(t, tests)
+
+	errorTests := []struct {
+		source   string
+		expected string
+	}{
+		{"if (true) { mut x = 1; } x", "undefined variable x on line 1"},
+		{"mut x = 1; if (true) { mut y = 1; mut y = 2; }", "variable y already declared on line 1"},
+		{"mut log = 1; mut log = 2;", "variable log already declared on line 1"},
+	}
+
+	for _, tt := range errorTests {
+		compiler := New()
+		err := compiler.Compile(parse(tt.source))
+		if err == nil {
+			t.Fatalf("expected compiler error %q, got nil", tt.expected)
+		}
+
+		if err.Error() != tt.expected {
+			t.Errorf("wrong compiler error. want=%q, got=%q", tt.expected, err.Error())
+		}
+	}
+
+	compiler := New()
+	err := compiler.Compile(parse("func() { mut a = 1; if (true) { mut b = 2; } if (true) { mut c = 3; mut d = 4; } }"))
+	if err != nil {
+		t.Fatalf("compiler error: %s", err)
+	}
+
+	fn := compiler.Bytecode().Constants[4].(*object.CompiledFunction)
+	if fn.NumLocals != 3 {
+		t.Errorf("wrong number of locals. want=%d, got=%d", 3, fn.NumLocals)
+	}
+}
+
 func TestRecursiveFunctions(t *testing.T) {
 	tests := []compilerTestCase{
 		{
@@ -1371,10 +1982,36 @@ func testConstants(expected []interface{}, actual []object.Object) error {
 				return fmt.Errorf("constant %d - testIntegerObject failed: %s", i, err)
 			}
 		case string:
+			// struct and enum types are compared by how they print
+			switch typ := actual[i].(type) {
+			case *object.StructType, *object.EnumType:
+				if typ.Inspect() != constant {
+					return fmt.Errorf("constant %d - wrong type. want=%q, got=%q", i, constant, typ.Inspect())
+				}
+				continue
+			}
+
 			err := testStringObject(constant, actual[i])
 			if err != nil {
 				return fmt.Errorf("constant %d - testStringObject failed: %s", i, err)
 			}
+		case map[int]int:
+			// the jump table of a match, from literal to the position of its arm
+			table, ok := actual[i].(*object.Hash)
+			if !ok || len(table.Pairs) != len(constant) {
+				return fmt.Errorf("constant %d - wrong jump table: %+v", i, actual[i])
+			}
+
+			for literal, target := range constant {
+				pair, ok := table.Pairs[(&object.Integer{Value: int64(literal)}).HashKey()]
+				if !ok {
+					return fmt.Errorf("constant %d - no jump for %d", i, literal)
+				}
+				err := testIntegerObject(int64(target), pair.Value)
+				if err != nil {
+					return fmt.Errorf("constant %d - wrong jump for %d: %s", i, literal, err)
+				}
+			}
 		case []code.Instructions:
 			fn, ok := actual[i].(*object.CompiledFunction)
 			if !ok {
@@ -0,0 +1,394 @@
+package compiler
+
+import (
+	"fmt"
+	"quonk/ast"
+	"quonk/code"
+	"quonk/object"
+	"quonk/token"
+	"strings"
+)
+
+// jumpTableMin is the number of literal arms from which a match jumps straight to the arm for its subject, below it
+// comparing the subject with each literal in turn is as fast
+const jumpTableMin = 4
+
+// compileMatch keeps the subject in a slot of its own, every pattern test loads it from there
+func (c *Compiler) compileMatch(node *ast.MatchExpr) error {
+	c.checkExhaustive(node)
+
+	err := c.Compile(node.Subject)
+	if err != nil {
+		return err
+	}
+
+	c.symbolTable = NewBlockSymbolTable(c.symbolTable)
+	// the name is no identifier, so nothing in the arms can refer to the slot
+	subject := c.symbolTable.DefineMutable("match subject")
+	c.storeSymbol(subject, true)
+	load := func() { c.loadSymbol(subject) }
+
+	if table, ok := c.jumpTable(node); ok {
+		err = c.compileJumpTable(node, table, load)
+	} else {
+		err = c.compileArms(node, load)
+	}
+	c.symbolTable = c.symbolTable.Outer
+
+	return err
+}
+
+// compileArms compiles a chain of arms, each one tests its pattern and guard and jumps to the next arm if they fail
+func (c *Compiler) compileArms(node *ast.MatchExpr, load func()) error {
+	endJumps := []int{}
+
+	for _, arm := range node.Arms {
+		c.symbolTable = NewBlockSymbolTable(c.symbolTable)
+
+		failJumps, err := c.compilePattern(arm.Pattern, load)
+		if err == nil && arm.Guard != nil {
+			err = c.Compile(arm.Guard)
+			failJumps = append(failJumps, c.emit(code.OpJumpNotTruthy, 9999))
+		}
+		if err == nil {
+			err = c.compileArmBody(arm.Body)
+		}
+
+		c.symbolTable = c.symbolTable.Outer
+		if err != nil {
+			return err
+		}
+
+		endJumps = append(endJumps, c.emit(code.OpJump, 9999))
+
+		nextArm := len(c.currentInstructions())
+		for _, pos := range failJumps {
+			c.changeOperand(pos, nextArm)
+		}
+	}
+
+	// no arm matched
+	c.emit(code.OpNull)
+
+	end := len(c.currentInstructions())
+	for _, pos := range endJumps {
+		c.changeOperand(pos, end)
+	}
+	return nil
+}
+
+// compileArmBody leaves the value of the body on the stack, null for a body that does not end with an expression
+func (c *Compiler) compileArmBody(body *ast.BlockStmt) error {
+	err := c.compileBlock(body)
+	if err != nil {
+		return err
+	}
+
+	if c.lastInstructionIs(code.OpPop) {
+		c.removeLastPop()
+	} else {
+		c.emit(code.OpNull)
+	}
+	return nil
+}
+
+// compilePattern emits the tests of pattern against the value that load pushes, storing what the pattern binds as it
+// goes. It returns the jumps taken when a test fails, which the caller points at the next arm
+func (c *Compiler) compilePattern(pattern ast.Pattern, load func()) ([]int, error) {
+	switch pattern := pattern.(type) {
+	case *ast.WildcardPattern:
+		return nil, nil
+	case *ast.BindingPattern:
+		load()
+		c.storeSymbol(c.symbolTable.DefineMutable(pattern.Name.Value), true)
+		return nil, nil
+	case *ast.LiteralPattern:
+		return c.compileEqualityTest(pattern.Value, load)
+	case *ast.ValuePattern:
+		return c.compileEqualityTest(pattern.Value, load)
+	case *ast.ArrayPattern:
+		load()
+		c.emit(code.OpMatchLength, len(pattern.Elements))
+		failJumps := []int{c.emit(code.OpJumpNotTruthy, 9999)}
+
+		for i, element := range pattern.Elements {
+			jumps, err := c.compilePattern(element, c.indexLoader(load, &object.Integer{Value: int64(i)}))
+			if err != nil {
+				return nil, err
+			}
+			failJumps = append(failJumps, jumps...)
+		}
+		return failJumps, nil
+	case *ast.HashPattern:
+		keys := make([]object.Object, len(pattern.Keys))
+		load()
+		for i, key := range pattern.Keys {
+			keys[i] = literalConstant(key)
+			c.emit(code.OpConstant, c.addConstant(keys[i]))
+		}
+		c.emit(code.OpMatchKeys, len(pattern.Keys))
+		failJumps := []int{c.emit(code.OpJumpNotTruthy, 9999)}
+
+		for i, value := range pattern.Values {
+			jumps, err := c.compilePattern(value, c.indexLoader(load, keys[i]))
+			if err != nil {
+				return nil, err
+			}
+			failJumps = append(failJumps, jumps...)
+		}
+		return failJumps, nil
+	case *ast.VariantPattern:
+		err := c.checkVariantFields(pattern)
+		if err != nil {
+			return nil, err
+		}
+
+		load()
+		err = c.Compile(pattern.Variant)
+		if err != nil {
+			return nil, err
+		}
+		c.emit(code.OpMatchVariant, len(pattern.Fields))
+		failJumps := []int{c.emit(code.OpJumpNotTruthy, 9999)}
+
+		// the payload is indexed by position, the fields of a variant the compiler does not know have no known names
+		for i, field := range pattern.Fields {
+			jumps, err := c.compilePattern(field, c.indexLoader(load, &object.Integer{Value: int64(i)}))
+			if err != nil {
+				return nil, err
+			}
+			failJumps = append(failJumps, jumps...)
+		}
+		return failJumps, nil
+	}
+
+	return nil, fmt.Errorf("unknown pattern %s", pattern.String())
+}
+
+func (c *Compiler) compileEqualityTest(expected ast.Expr, load func()) ([]int, error) {
+	load()
+	err := c.Compile(expected)
+	if err != nil {
+		return nil, err
+	}
+	c.emit(code.OpEqual)
+
+	return []int{c.emit(code.OpJumpNotTruthy, 9999)}, nil
+}
+
+// indexLoader pushes the value load pushes indexed with index, the element, value or field a nested pattern tests
+func (c *Compiler) indexLoader(load func(), index object.Object) func() {
+	idx := c.addConstant(index)
+	return func() {
+		load()
+		c.emit(code.OpConstant, idx)
+		c.emit(code.OpIndex)
+	}
+}
+
+// checkVariantFields makes a pattern for a variant of a known enum with the wrong number of fields a compile error
+func (c *Compiler) checkVariantFields(pattern *ast.VariantPattern) error {
+	enum, name, ok := c.knownVariant(pattern.Variant)
+	if !ok {
+		return nil
+	}
+
+	variant, ok := enum.FindVariant(name)
+	if !ok || len(variant.Fields) == len(pattern.Fields) {
+		// a variant the enum does not have is reported when the name is compiled
+		return nil
+	}
+	return fmt.Errorf("wrong number of fields in pattern for %s.%s. want=%d, got=%d on line %d",
+		enum.Name, name, len(variant.Fields), len(pattern.Fields), pattern.Token.Line)
+}
+
+// knownVariant is the known enum and the variant name of E.name
+func (c *Compiler) knownVariant(expr ast.Expr) (*object.EnumType, string, bool) {
+	index, ok := expr.(*ast.IndexExpr)
+	if !ok || index.Token.Type != token.Dot {
+		return nil, "", false
+	}
+
+	known, ok := c.knownTypeOf(index.Left)
+	if !ok || known.enumType == nil {
+		return nil, "", false
+	}
+	return known.enumType, index.Index.(*ast.StringLiteral).Value, true
+}
+
+// literalConstant is the value of a number, string or boolean literal, and nil for any other expression
+func literalConstant(expr ast.Expr) object.Object {
+	switch expr := expr.(type) {
+	case *ast.IntegerLiteral:
+		return &object.Integer{Value: expr.Value}
+	case *ast.FloatLiteral:
+		return &object.Float{Value: expr.Value}
+	case *ast.StringLiteral:
+		return &object.String{Value: expr.Value}
+	case *ast.BooleanLiteral:
+		return &object.Boolean{Value: expr.Value}
+	}
+	return nil
+}
+
+// jumpTable reports whether node only compares its subject with literals, which it can then look up in a table. A
+// catch-all arm may follow the literal arms
+func (c *Compiler) jumpTable(node *ast.MatchExpr) (*object.Hash, bool) {
+	arms := node.Arms
+	if len(arms) > 0 && irrefutable(arms[len(arms)-1].Pattern) {
+		arms = arms[:len(arms)-1]
+	}
+	if len(arms) < jumpTableMin {
+		return nil, false
+	}
+
+	for _, arm := range node.Arms {
+		if arm.Guard != nil {
+			return nil, false
+		}
+	}
+	for _, arm := range arms {
+		literal, ok := arm.Pattern.(*ast.LiteralPattern)
+		if !ok || literalConstant(literal.Value) == nil {
+			return nil, false
+		}
+	}
+
+	return &object.Hash{Pairs: make(map[object.HashKey]object.HashPair)}, true
+}
+
+// compileJumpTable compiles the literal arms of node and fills in table, which maps each literal to its arm. The
+// first arm for a literal wins, like it does when the arms are tried in turn
+func (c *Compiler) compileJumpTable(node *ast.MatchExpr, table *object.Hash, load func()) error {
+	load()
+	c.emit(code.OpJumpTable, c.addConstant(table))
+	defaultJump := c.emit(code.OpJump, 9999)
+
+	endJumps := []int{}
+	for _, arm := range node.Arms {
+		literal, ok := arm.Pattern.(*ast.LiteralPattern)
+		if !ok {
+			break
+		}
+
+		value := literalConstant(literal.Value)
+		key := value.(object.Hashable).HashKey()
+		if _, ok := table.Pairs[key]; !ok {
+			table.Pairs[key] = object.HashPair{Key: value, Value: &object.Integer{Value: int64(len(c.currentInstructions()))}}
+		}
+
+		err := c.compileArmBody(arm.Body)
+		if err != nil {
+			return err
+		}
+		endJumps = append(endJumps, c.emit(code.OpJump, 9999))
+	}
+
+	c.changeOperand(defaultJump, len(c.currentInstructions()))
+	last := node.Arms[len(node.Arms)-1]
+	if irrefutable(last.Pattern) {
+		c.symbolTable = NewBlockSymbolTable(c.symbolTable)
+		_, err := c.compilePattern(last.Pattern, load)
+		if err == nil {
+			err = c.compileArmBody(last.Body)
+		}
+		c.symbolTable = c.symbolTable.Outer
+		if err != nil {
+			return err
+		}
+	} else {
+		c.emit(code.OpNull)
+	}
+
+	end := len(c.currentInstructions())
+	for _, pos := range endJumps {
+		c.changeOperand(pos, end)
+	}
+	return nil
+}
+
+// irrefutable reports whether pattern matches anything
+func irrefutable(pattern ast.Pattern) bool {
+	switch pattern.(type) {
+	case *ast.WildcardPattern, *ast.BindingPattern:
+		return true
+	}
+	return false
+}
+
+// checkExhaustive warns about a match on the variants of a known enum, or on booleans, that has no arm for some of
+// them. The type is known from the patterns, and an arm with a guard covers nothing
+func (c *Compiler) checkExhaustive(node *ast.MatchExpr) {
+	var enum *object.EnumType
+	booleans := false
+	covered := make(map[string]bool)
+
+	for _, arm := range node.Arms {
+		if irrefutable(arm.Pattern) {
+			if arm.Guard == nil {
+				return
+			}
+			continue
+		}
+
+		var name string
+		switch pattern := arm.Pattern.(type) {
+		case *ast.LiteralPattern:
+			b, ok := pattern.Value.(*ast.BooleanLiteral)
+			if !ok {
+				return
+			}
+			booleans = true
+			name = b.String()
+		case *ast.ValuePattern:
+			e, variant, ok := c.knownVariant(pattern.Value)
+			if !ok || (enum != nil && e != enum) {
+				return
+			}
+			enum, name = e, variant
+		case *ast.VariantPattern:
+			e, variant, ok := c.knownVariant(pattern.Variant)
+			if !ok || (enum != nil && e != enum) {
+				return
+			}
+			enum, name = e, variant
+
+			// a variant is only covered when any payload matches
+			for _, field := range pattern.Fields {
+				if !irrefutable(field) {
+					name = ""
+				}
+			}
+		default:
+			return
+		}
+
+		if arm.Guard == nil && name != "" {
+			covered[name] = true
+		}
+	}
+
+	var what string
+	var missing []string
+	switch {
+	case enum != nil && !booleans:
+		what = enum.Name
+		for _, variant := range enum.Variants {
+			if !covered[variant.Name] {
+				missing = append(missing, enum.Name+"."+variant.Name)
+			}
+		}
+	case booleans && enum == nil:
+		what = "a boolean"
+		for _, b := range []string{"true", "false"} {
+			if !covered[b] {
+				missing = append(missing, b)
+			}
+		}
+	}
+
+	if len(missing) > 0 {
+		c.warnings = append(c.warnings, fmt.Sprintf("match on %s is not exhaustive, missing %s on line %d",
+			what, strings.Join(missing, ", "), node.Token.Line))
+	}
+}
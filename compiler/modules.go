@@ -0,0 +1,136 @@
+package compiler
+
+import (
+	"fmt"
+	"quonk/ast"
+	"quonk/code"
+	"quonk/module"
+	"quonk/object"
+)
+
+// compiledModule is a module compiled into the program. Its code runs once, where the program first imports it, and
+// leaves the module object in a global slot for every import of it to read
+type compiledModule struct {
+	slot    int
+	name    string
+	exports map[string]bool
+}
+
+// Modules are the modules compiled into a program and the loader finding them. Like the symbol table and the
+// constants, they outlive a compiler in the REPL, where each line imports into the program of the lines before it
+type Modules struct {
+	loader   *module.Loader
+	compiled map[string]*compiledModule // by the path of their file
+}
+
+func NewModules() *Modules {
+	return &Modules{loader: module.NewLoader(""), compiled: make(map[string]*compiledModule)}
+}
+
+func (c *Compiler) atTopLevel() bool {
+	return c.scopeIndex == 0 && c.symbolTable.Outer == nil
+}
+
+func (c *Compiler) compileImport(node *ast.ImportStmt) error {
+	line := node.Token.Line
+	if !c.atTopLevel() {
+		return fmt.Errorf("import must be at the top level of a file on line %d", line)
+	}
+
+	mod, err := c.importModule(node.Path, line)
+	if err != nil {
+		return err
+	}
+
+	if node.Names == nil {
+		return c.bindImport(node.Alias.Value, line, func() {
+			c.emit(code.OpGetGlobal, mod.slot)
+		})
+	}
+
+	for _, name := range node.Names {
+		if !mod.exports[name.Value] {
+			return fmt.Errorf("module %s has no export %s on line %d", mod.name, name.Value, line)
+		}
+
+		export := &object.String{Value: name.Value}
+		err := c.bindImport(name.Value, line, func() {
+			c.emit(code.OpGetGlobal, mod.slot)
+			c.emit(code.OpConstant, c.addConstant(export))
+			c.emit(code.OpIndex)
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// bindImport declares an imported name as a constant global with the value load pushes
+func (c *Compiler) bindImport(name string, line int, load func()) error {
+	if sym, fromOuter, ok := c.symbolTable.Resolve(name); ok && !fromOuter && sym.Scope != BuiltinScope {
+		return fmt.Errorf("variable %s already declared on line %d", name, line)
+	}
+
+	symbol := c.symbolTable.DefineImmutable(name)
+	load()
+	c.emit(code.OpSetImmutableGlobal, symbol.Index)
+	return nil
+}
+
+// importModule compiles the module at path the first time the program imports it. Its code becomes a function
+// returning the module object, which the program calls right here
+func (c *Compiler) importModule(path string, line int) (*compiledModule, error) {
+	resolved, err := c.modules.loader.Resolve(path)
+	if err != nil {
+		return nil, fmt.Errorf("cannot import %s: %s on line %d", path, err, line)
+	}
+	if mod, ok := c.modules.compiled[resolved]; ok {
+		return mod, nil
+	}
+
+	program, err := c.modules.loader.Load(resolved)
+	if err != nil {
+		return nil, fmt.Errorf("%s on line %d", err, line)
+	}
+	defer c.modules.loader.Done()
+
+	sub := New()
+	sub.constants = c.constants
+	sub.symbolTable = NewModuleSymbolTable(c.symbolTable)
+	sub.modules = c.modules
+
+	err = sub.Compile(program)
+	if err != nil {
+		return nil, err
+	}
+
+	mod := &compiledModule{name: module.Name(resolved), exports: make(map[string]bool)}
+	sub.emit(code.OpConstant, sub.addConstant(&object.String{Value: mod.name}))
+	for _, name := range sub.exports {
+		symbol, _, _ := sub.symbolTable.Resolve(name)
+		sub.emit(code.OpConstant, sub.addConstant(&object.String{Value: name}))
+		sub.loadSymbol(symbol)
+		mod.exports[name] = true
+	}
+	sub.emit(code.OpModule, len(sub.exports))
+	sub.emit(code.OpReturnValue)
+
+	c.constants = sub.constants
+	c.warnings = append(c.warnings, sub.warnings...)
+	fn := &object.CompiledFunction{
+		Instructions: sub.currentInstructions(),
+		NumLocals:    sub.symbolTable.NumLocals(),
+		Handlers:     sub.scopes[0].handlers,
+		Lines:        sub.scopes[0].lines,
+	}
+
+	mod.slot = c.symbolTable.reserveGlobal()
+	c.emit(code.OpClosure, c.addConstant(fn), 0)
+	pos := c.emit(code.OpCall, 0)
+	c.addLine(pos, line)
+	c.emit(code.OpSetImmutableGlobal, mod.slot)
+
+	c.modules.compiled[resolved] = mod
+	return mod, nil
+}
@@ -0,0 +1,181 @@
+package compiler
+
+import (
+	"fmt"
+	"quonk/ast"
+	"quonk/code"
+	"quonk/object"
+)
+
+// knownType is a constant whose struct or enum type the compiler knows. The fields of its structs are read and written
+// by slot instead of by name, and the variants of its enum are checked as they are named
+type knownType struct {
+	structType *object.StructType
+	enumType   *object.EnumType
+	instance   bool // the constant is a struct of the type rather than the type itself
+}
+
+// knownTypeOf is the type known to be the value of expr, if any. Only constants are known, a mutable variable could
+// be assigned anything
+func (c *Compiler) knownTypeOf(expr ast.Expr) (knownType, bool) {
+	ident, ok := expr.(*ast.Identifier)
+	if !ok {
+		return knownType{}, false
+	}
+	return c.symbolTable.lookupType(ident.Value)
+}
+
+// recordType remembers the type of a constant declared with a struct or enum definition, or a struct literal
+func (c *Compiler) recordType(name string, value ast.Expr) {
+	switch value := value.(type) {
+	case *ast.StructDefinition:
+		c.symbolTable.defineType(name, knownType{structType: structType(value)})
+	case *ast.EnumDefinition:
+		c.symbolTable.defineType(name, knownType{enumType: enumType(value)})
+	case *ast.StructLiteral:
+		if known, ok := c.knownTypeOf(value.Type); ok && !known.instance {
+			c.symbolTable.defineType(name, knownType{structType: known.structType, instance: true})
+		}
+	}
+}
+
+func structType(def *ast.StructDefinition) *object.StructType {
+	fields := make([]string, len(def.Fields))
+	for i, field := range def.Fields {
+		fields[i] = field.Value
+	}
+	return &object.StructType{Name: def.Name, Fields: fields}
+}
+
+func enumType(def *ast.EnumDefinition) *object.EnumType {
+	variants := make([]string, len(def.Variants))
+	fields := make([][]string, len(def.Variants))
+	for i, variant := range def.Variants {
+		variants[i] = variant.Name.Value
+		for _, field := range variant.Fields {
+			fields[i] = append(fields[i], field.Value)
+		}
+	}
+	return object.NewEnumType(def.Name, variants, fields)
+}
+
+// checkVariant makes naming a variant a known enum does not have, as in Color.Purple, a compile error
+func (c *Compiler) checkVariant(expr ast.Expr, variant string, line int) error {
+	known, ok := c.knownTypeOf(expr)
+	if !ok || known.enumType == nil {
+		return nil
+	}
+	if _, ok := known.enumType.FindVariant(variant); ok {
+		return nil
+	}
+	return fmt.Errorf("enum %s has no variant %s on line %d", known.enumType.Name, variant, line)
+}
+
+// mayHaveMember reports whether the value of expr could have the member name that a method call prefers over the
+// function of that name. Literals other than hashes have no members, and neither do structs of a known type that lack
+// the field
+func (c *Compiler) mayHaveMember(expr ast.Expr, name string) bool {
+	switch expr := expr.(type) {
+	case *ast.IntegerLiteral, *ast.FloatLiteral, *ast.StringLiteral, *ast.BooleanLiteral, *ast.NullLiteral,
+		*ast.ArrayLiteral, *ast.FunctionLiteral:
+		return false
+	case *ast.StructLiteral:
+		if known, ok := c.knownTypeOf(expr.Type); ok && !known.instance {
+			_, ok := known.structType.FieldIndex(name)
+			return ok
+		}
+	case *ast.Identifier:
+		if known, ok := c.knownTypeOf(expr); ok && known.instance {
+			_, ok := known.structType.FieldIndex(name)
+			return ok
+		}
+	}
+	return true
+}
+
+// fieldSlot is the slot of field in the structs of a known type, a field the type does not have is a compile error
+func fieldSlot(t *object.StructType, field string, line int) (int, error) {
+	idx, ok := t.FieldIndex(field)
+	if !ok {
+		return 0, fmt.Errorf("struct %s has no field %s on line %d", t.Name, field, line)
+	}
+	return idx, nil
+}
+
+func (c *Compiler) compileStructLiteral(node *ast.StructLiteral) error {
+	known, ok := c.knownTypeOf(node.Type)
+	if ok && !known.instance {
+		for _, field := range node.Fields {
+			if _, err := fieldSlot(known.structType, field.Value, node.Token.Line); err != nil {
+				return err
+			}
+		}
+	}
+
+	err := c.Compile(node.Type)
+	if err != nil {
+		return err
+	}
+
+	for i, field := range node.Fields {
+		c.emit(code.OpConstant, c.addConstant(&object.String{Value: field.Value}))
+
+		err := c.Compile(node.Values[i])
+		if err != nil {
+			return err
+		}
+	}
+
+	c.emit(code.OpStruct, len(node.Fields))
+	return nil
+}
+
+// compileFieldRead compiles a.b by slot when a is a struct of a known type, and reports whether it did
+func (c *Compiler) compileFieldRead(node *ast.IndexExpr) (bool, error) {
+	known, ok := c.knownTypeOf(node.Left)
+	if !ok || !known.instance || node.Optional {
+		return false, nil
+	}
+
+	idx, err := fieldSlot(known.structType, node.Index.(*ast.StringLiteral).Value, node.Token.Line)
+	if err != nil {
+		return true, err
+	}
+
+	err = c.Compile(node.Left)
+	if err != nil {
+		return true, err
+	}
+	c.emit(code.OpGetField, idx)
+	return true, nil
+}
+
+func (c *Compiler) compileFieldAssignment(node *ast.FieldAssignmentStmt) error {
+	err := c.Compile(node.Object)
+	if err != nil {
+		return err
+	}
+
+	known, ok := c.knownTypeOf(node.Object)
+	if ok && known.instance {
+		idx, err := fieldSlot(known.structType, node.Field.Value, node.Token.Line)
+		if err != nil {
+			return err
+		}
+
+		err = c.Compile(node.Value)
+		if err != nil {
+			return err
+		}
+		c.emit(code.OpSetField, idx)
+		return nil
+	}
+
+	c.emit(code.OpConstant, c.addConstant(&object.String{Value: node.Field.Value}))
+	err = c.Compile(node.Value)
+	if err != nil {
+		return err
+	}
+	c.emit(code.OpSetFieldByName)
+	return nil
+}
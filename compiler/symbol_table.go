@@ -1,5 +1,7 @@
 package compiler
 
+import "quonk/object"
+
 type SymbolScopes string
 
 const (
@@ -15,6 +17,7 @@ type Symbol struct {
 	Scope      SymbolScopes
 	Index      int
 	IsConstant bool
+	IsCell     bool // a captured mutable local, boxed so that closures share it
 }
 
 type SymbolTable struct {
@@ -23,29 +26,76 @@ type SymbolTable struct {
 	store          map[string]Symbol
 	numDefinitions int
 	FreeSymbols    []Symbol
+	// names referenced by nested functions, mutable locals with these names are boxed in cells
+	captured map[string]bool
+	// a block table shares the frame of the function or program it is in, so its symbols are locals of that frame
+	block bool
+	// the most slots any block of this frame has needed at once. Sibling blocks reuse the same slots
+	blockLocals int
+	// the number of globals in use, shared by the global tables of a program and of the modules it imports
+	globals *int
+	// the struct and enum types of the constants known to hold one, or a struct of one, see knownType
+	types map[string]knownType
 }
 
 func NewSymbolTable() *SymbolTable {
 	s := make(map[string]Symbol)
 	free := []Symbol{}
-	return &SymbolTable{store: s, FreeSymbols: free}
+	return &SymbolTable{store: s, FreeSymbols: free, globals: new(int)}
+}
+
+// NewModuleSymbolTable creates the global table of a module imported by the program of importer. The module sees
+// none of the program's variables, but its globals are numbered after the program's so that both fit in one store
+func NewModuleSymbolTable(importer *SymbolTable) *SymbolTable {
+	for importer.Outer != nil {
+		importer = importer.Outer
+	}
+
+	s := NewSymbolTable()
+	s.globals = importer.globals
+	for i, name := range object.BuiltinNames() {
+		s.DefineBuiltin(i, name)
+	}
+	return s
+}
+
+// nextIndex is the index of the next symbol defined in the table. Global tables number their symbols from the count
+// they share, every other table from its own definitions
+func (s *SymbolTable) nextIndex() int {
+	if s.Outer != nil {
+		return s.numDefinitions
+	}
+
+	index := *s.globals
+	*s.globals++
+	return index
+}
+
+// reserveGlobal takes a global slot that no variable is named after
+func (s *SymbolTable) reserveGlobal() int {
+	for s.Outer != nil {
+		s = s.Outer
+	}
+	return s.nextIndex()
 }
 
 func (s *SymbolTable) DefineMutable(name string) Symbol {
-	symbol := Symbol{Name: name, Index: s.numDefinitions, IsConstant: false}
+	symbol := Symbol{Name: name, Index: s.nextIndex(), IsConstant: false}
 	if s.Outer == nil {
 		symbol.Scope = GlobalScope
 	} else {
 		symbol.Scope = LocalScope
+		symbol.IsCell = s.captured[name]
 	}
 
 	s.store[name] = symbol
 	s.numDefinitions++
+	s.reserveBlockSlot()
 	return symbol
 }
 
 func (s *SymbolTable) DefineImmutable(name string) Symbol {
-	symbol := Symbol{Name: name, Index: s.numDefinitions, IsConstant: true}
+	symbol := Symbol{Name: name, Index: s.nextIndex(), IsConstant: true}
 	if s.Outer == nil {
 		symbol.Scope = GlobalScope
 	} else {
@@ -54,9 +104,39 @@ func (s *SymbolTable) DefineImmutable(name string) Symbol {
 
 	s.store[name] = symbol
 	s.numDefinitions++
+	s.reserveBlockSlot()
 	return symbol
 }
 
+// reserveBlockSlot makes room in the enclosing frame for the slot just defined in a block
+func (s *SymbolTable) reserveBlockSlot() {
+	if !s.block {
+		return
+	}
+
+	frame := s.Outer
+	for frame.block {
+		frame = frame.Outer
+	}
+
+	if s.numDefinitions > frame.blockLocals {
+		frame.blockLocals = s.numDefinitions
+	}
+}
+
+// NumLocals is the number of local slots the frame of this table needs
+func (s *SymbolTable) NumLocals() int {
+	if s.Outer == nil {
+		// the definitions of the global table are globals, only its blocks use local slots
+		return s.blockLocals
+	}
+
+	if s.blockLocals > s.numDefinitions {
+		return s.blockLocals
+	}
+	return s.numDefinitions
+}
+
 func (s *SymbolTable) DefineBuiltin(index int, name string) Symbol {
 	symbol := Symbol{Name: name, Index: index, Scope: BuiltinScope, IsConstant: true}
 	s.store[name] = symbol
@@ -66,7 +146,7 @@ func (s *SymbolTable) DefineBuiltin(index int, name string) Symbol {
 func (s *SymbolTable) defineFree(original Symbol) Symbol {
 	s.FreeSymbols = append(s.FreeSymbols, original)
 
-	symbol := Symbol{Name: original.Name, Index: len(s.FreeSymbols) - 1, IsConstant: original.IsConstant}
+	symbol := Symbol{Name: original.Name, Index: len(s.FreeSymbols) - 1, IsConstant: original.IsConstant, IsCell: original.IsCell}
 	symbol.Scope = FreeScope
 	s.store[original.Name] = symbol
 
@@ -78,7 +158,8 @@ func (s *SymbolTable) Resolve(name string) (Symbol, bool, bool) {
 	symbol, ok := s.store[name]
 	if !ok && s.Outer != nil {
 		symbol, _, ok = s.Outer.Resolve(name)
-		if !ok {
+		if !ok || s.block {
+			// a block is in the same frame as its outer table, so nothing it resolves from there is free
 			return symbol, true, ok
 		}
 
@@ -100,6 +181,45 @@ func NewEnclosedSymbolTable(outer *SymbolTable) *SymbolTable {
 	return s
 }
 
+// NewBlockSymbolTable creates the table of an if, for or try block. Its slots follow the ones in use by outer
+func NewBlockSymbolTable(outer *SymbolTable) *SymbolTable {
+	s := NewEnclosedSymbolTable(outer)
+	s.block = true
+	s.captured = outer.captured
+
+	// blocks of the program are locals of the main frame rather than globals
+	if outer.block || outer.Outer != nil {
+		s.numDefinitions = outer.numDefinitions
+	}
+
+	return s
+}
+
+// defineType records that the constant name, defined in this table, holds a known type or a struct of one
+func (s *SymbolTable) defineType(name string, known knownType) {
+	if s.types == nil {
+		s.types = make(map[string]knownType)
+	}
+	s.types[name] = known
+}
+
+// lookupType is what defineType recorded for the symbol name resolves to
+func (s *SymbolTable) lookupType(name string) (knownType, bool) {
+	if known, ok := s.types[name]; ok {
+		return known, true
+	}
+
+	// a free symbol is the variable of an outer table, anything else defined here shadows the outer ones
+	if symbol, ok := s.store[name]; ok && symbol.Scope != FreeScope {
+		return knownType{}, false
+	}
+
+	if s.Outer == nil {
+		return knownType{}, false
+	}
+	return s.Outer.lookupType(name)
+}
+
 func (s *SymbolTable) DefineFunctionName(name string) Symbol {
 	symbol := Symbol{Name: name, Index: 0, Scope: FunctionScope, IsConstant: false}
 	s.store[name] = symbol
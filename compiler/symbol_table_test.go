@@ -266,10 +266,10 @@ func TestResolveUnresolvableFree(t *testing.T) {
 	secondLocal.DefineImmutable("f")
 
 	expected := []Symbol{
-		{"a", GlobalScope, 0, true},
-		{"c", FreeScope, 0, true},
-		{"e", LocalScope, 0, true},
-		{"f", LocalScope, 1, true},
+		{"a", GlobalScope, 0, true, false},
+		{"c", FreeScope, 0, true, false},
+		{"e", LocalScope, 0, true, false},
+		{"f", LocalScope, 1, true, false},
 	}
 
 	for _, sym := range expected {
@@ -330,3 +330,113 @@ func TestShadowingFunctionName(t *testing.T) {
 		t.Errorf("expected %s to resolve to %+v, got=%+v", expected.Name, expected, result)
 	}
 }
+
+func TestBlockSymbolTables(t *testing.T) {
+	global := NewSymbolTable()
+	global.DefineMutable("a")
+
+	// blocks of the program define locals of the main frame
+	programBlock := NewBlockSymbolTable(global)
+	programBlock.DefineMutable("a")
+	programBlock.DefineMutable("b")
+
+	expected := []Symbol{
+		{"a", LocalScope, 0, false, false},
+		{"b", LocalScope, 1, false, false},
+	}
+	for _, sym := range expected {
+		result, _, ok := programBlock.Resolve(sym.Name)
+		if !ok {
+			t.Errorf("name %s not resolvable", sym.Name)
+			continue
+		}
+
+		if result != sym {
+			t.Errorf("expected %s to resolve to %+v, got=%+v", sym.Name, sym, result)
+		}
+	}
+
+	local := NewEnclosedSymbolTable(global)
+	local.DefineImmutable("c")
+
+	first := NewBlockSymbolTable(local)
+	first.DefineMutable("d")
+	nested := NewBlockSymbolTable(first)
+	nested.DefineMutable("e")
+
+	// the sibling block reuses the slots of the first one
+	second := NewBlockSymbolTable(local)
+	second.DefineMutable("f")
+
+	expected = []Symbol{
+		{"a", GlobalScope, 0, false, false},
+		{"c", LocalScope, 0, true, false},
+		{"d", LocalScope, 1, false, false},
+		{"e", LocalScope, 2, false, false},
+	}
+	for _, sym := range expected {
+		result, fromOuter, ok := nested.Resolve(sym.Name)
+		if !ok {
+			t.Errorf("name %s not resolvable", sym.Name)
+			continue
+		}
+
+		if result != sym {
+			t.Errorf("expected %s to resolve to %+v, got=%+v", sym.Name, sym, result)
+		}
+
+		if fromOuter != (sym.Name != "e") {
+			t.Errorf("expected %s fromOuter to be %t, got=%t", sym.Name, sym.Name != "e", fromOuter)
+		}
+	}
+
+	result, _, _ := second.Resolve("f")
+	if want := (Symbol{"f", LocalScope, 1, false, false}); result != want {
+		t.Errorf("expected f to resolve to %+v, got=%+v", want, result)
+	}
+
+	if _, _, ok := second.Resolve("d"); ok {
+		t.Errorf("name d resolved outside of its block")
+	}
+
+	if len(local.FreeSymbols) != 0 {
+		t.Errorf("blocks must not create free symbols. got=%+v", local.FreeSymbols)
+	}
+
+	if local.NumLocals() != 3 {
+		t.Errorf("wrong number of locals. want=%d, got=%d", 3, local.NumLocals())
+	}
+	if global.NumLocals() != 2 {
+		t.Errorf("wrong number of main frame locals. want=%d, got=%d", 2, global.NumLocals())
+	}
+}
+
+func TestModuleSymbolTables(t *testing.T) {
+	global := NewSymbolTable()
+	global.DefineMutable("a")
+
+	mod := NewModuleSymbolTable(NewEnclosedSymbolTable(global))
+	mod.DefineImmutable("b")
+	slot := global.reserveGlobal()
+	global.DefineMutable("c")
+
+	if _, _, ok := mod.Resolve("a"); ok {
+		t.Errorf("module resolved a variable of the program")
+	}
+	if _, _, ok := mod.Resolve("len"); !ok {
+		t.Errorf("module did not resolve builtin len")
+	}
+
+	// the module and the program number their globals from the same count
+	b, _, _ := mod.Resolve("b")
+	if want := (Symbol{"b", GlobalScope, 1, true, false}); b != want {
+		t.Errorf("expected b to resolve to %+v, got=%+v", want, b)
+	}
+	if slot != 2 {
+		t.Errorf("wrong reserved slot. want=2, got=%d", slot)
+	}
+	c, _, _ := global.Resolve("c")
+	if want := (Symbol{"c", GlobalScope, 3, false, false}); c != want {
+		t.Errorf("expected c to resolve to %+v, got=%+v", want, c)
+	}
+}
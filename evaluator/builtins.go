@@ -5,13 +5,102 @@ import (
 )
 
 var builtIns = map[string]*object.BuiltIn{
-	"len":    object.GetBuiltInByName("len"),
-	"print":  object.GetBuiltInByName("print"),
-	"first":  object.GetBuiltInByName("first"),
-	"last":   object.GetBuiltInByName("last"),
-	"rest":   object.GetBuiltInByName("rest"),
-	"append": object.GetBuiltInByName("append"),
-	"slice":  object.GetBuiltInByName("slice"),
-	"keys":   object.GetBuiltInByName("keys"),
-	"values": object.GetBuiltInByName("values"),
+	"len":             object.GetBuiltInByName("len"),
+	"print":           object.GetBuiltInByName("print"),
+	"first":           object.GetBuiltInByName("first"),
+	"last":            object.GetBuiltInByName("last"),
+	"rest":            object.GetBuiltInByName("rest"),
+	"append":          object.GetBuiltInByName("append"),
+	"slice":           object.GetBuiltInByName("slice"),
+	"keys":            object.GetBuiltInByName("keys"),
+	"values":          object.GetBuiltInByName("values"),
+	"tuple":           object.GetBuiltInByName("tuple"),
+	"freeze":          object.GetBuiltInByName("freeze"),
+	"error":           object.GetBuiltInByName("error"),
+	"map":             object.GetBuiltInByName("map"),
+	"filter":          object.GetBuiltInByName("filter"),
+	"reduce":          object.GetBuiltInByName("reduce"),
+	"each":            object.GetBuiltInByName("each"),
+	"find":            object.GetBuiltInByName("find"),
+	"any":             object.GetBuiltInByName("any"),
+	"all":             object.GetBuiltInByName("all"),
+	"split":           object.GetBuiltInByName("split"),
+	"join":            object.GetBuiltInByName("join"),
+	"trim":            object.GetBuiltInByName("trim"),
+	"trim_left":       object.GetBuiltInByName("trim_left"),
+	"trim_right":      object.GetBuiltInByName("trim_right"),
+	"contains":        object.GetBuiltInByName("contains"),
+	"starts_with":     object.GetBuiltInByName("starts_with"),
+	"ends_with":       object.GetBuiltInByName("ends_with"),
+	"index_of":        object.GetBuiltInByName("index_of"),
+	"replace":         object.GetBuiltInByName("replace"),
+	"upper":           object.GetBuiltInByName("upper"),
+	"lower":           object.GetBuiltInByName("lower"),
+	"repeat":          object.GetBuiltInByName("repeat"),
+	"chars":           object.GetBuiltInByName("chars"),
+	"reverse":         object.GetBuiltInByName("reverse"),
+	"format":          object.GetBuiltInByName("format"),
+	"abs":             object.GetBuiltInByName("abs"),
+	"floor":           object.GetBuiltInByName("floor"),
+	"ceil":            object.GetBuiltInByName("ceil"),
+	"round":           object.GetBuiltInByName("round"),
+	"sqrt":            object.GetBuiltInByName("sqrt"),
+	"pow":             object.GetBuiltInByName("pow"),
+	"log":             object.GetBuiltInByName("log"),
+	"exp":             object.GetBuiltInByName("exp"),
+	"sin":             object.GetBuiltInByName("sin"),
+	"cos":             object.GetBuiltInByName("cos"),
+	"tan":             object.GetBuiltInByName("tan"),
+	"asin":            object.GetBuiltInByName("asin"),
+	"acos":            object.GetBuiltInByName("acos"),
+	"atan":            object.GetBuiltInByName("atan"),
+	"atan2":           object.GetBuiltInByName("atan2"),
+	"min":             object.GetBuiltInByName("min"),
+	"max":             object.GetBuiltInByName("max"),
+	"read_file":       object.GetBuiltInByName("read_file"),
+	"write_file":      object.GetBuiltInByName("write_file"),
+	"append_file":     object.GetBuiltInByName("append_file"),
+	"read_lines":      object.GetBuiltInByName("read_lines"),
+	"exists":          object.GetBuiltInByName("exists"),
+	"list_dir":        object.GetBuiltInByName("list_dir"),
+	"input":           object.GetBuiltInByName("input"),
+	"read_line":       object.GetBuiltInByName("read_line"),
+	"json_encode":     object.GetBuiltInByName("json_encode"),
+	"json_decode":     object.GetBuiltInByName("json_decode"),
+	"re_compile":      object.GetBuiltInByName("re_compile"),
+	"re_match":        object.GetBuiltInByName("re_match"),
+	"re_find":         object.GetBuiltInByName("re_find"),
+	"re_find_all":     object.GetBuiltInByName("re_find_all"),
+	"re_replace":      object.GetBuiltInByName("re_replace"),
+	"re_split":        object.GetBuiltInByName("re_split"),
+	"now":             object.GetBuiltInByName("now"),
+	"monotonic":       object.GetBuiltInByName("monotonic"),
+	"sleep":           object.GetBuiltInByName("sleep"),
+	"format_time":     object.GetBuiltInByName("format_time"),
+	"parse_time":      object.GetBuiltInByName("parse_time"),
+	"milliseconds":    object.GetBuiltInByName("milliseconds"),
+	"seconds":         object.GetBuiltInByName("seconds"),
+	"minutes":         object.GetBuiltInByName("minutes"),
+	"hours":           object.GetBuiltInByName("hours"),
+	"format_duration": object.GetBuiltInByName("format_duration"),
+	"rand_int":        object.GetBuiltInByName("rand_int"),
+	"rand_float":      object.GetBuiltInByName("rand_float"),
+	"shuffle":         object.GetBuiltInByName("shuffle"),
+	"choice":          object.GetBuiltInByName("choice"),
+	"seed":            object.GetBuiltInByName("seed"),
+	"sort":            object.GetBuiltInByName("sort"),
+	"sort_by":         object.GetBuiltInByName("sort_by"),
+	"has_key":         object.GetBuiltInByName("has_key"),
+	"get":             object.GetBuiltInByName("get"),
+	"delete":          object.GetBuiltInByName("delete"),
+	"merge":           object.GetBuiltInByName("merge"),
+	"entries":         object.GetBuiltInByName("entries"),
+	"from_entries":    object.GetBuiltInByName("from_entries"),
+}
+
+var builtInConstants = map[string]object.Object{
+	"PI":  object.GetBuiltinConstantByName("PI"),
+	"E":   object.GetBuiltinConstantByName("E"),
+	"INF": object.GetBuiltinConstantByName("INF"),
+	"NAN": object.GetBuiltinConstantByName("NAN"),
 }
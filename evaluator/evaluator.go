@@ -2,14 +2,15 @@ package evaluator
 
 import (
 	"fmt"
+	"math/rand"
 	"quonk/ast"
 	"quonk/object"
 )
 
 var (
-	NULL  = &object.Null{}
-	TRUE  = &object.Boolean{Value: true}
-	FALSE = &object.Boolean{Value: false}
+	NULL  = object.NULL
+	TRUE  = object.TRUE
+	FALSE = object.FALSE
 )
 
 func Eval(node ast.Node, s *object.Scope) object.Object {
@@ -43,10 +44,26 @@ func Eval(node ast.Node, s *object.Scope) object.Object {
 			return errorMaybe
 		}
 	case *ast.ForStmt:
-		errorMaybe := evalForStmt(node, s)
-		if isError(errorMaybe) {
-			return errorMaybe
+		result := evalForStmt(node, s)
+		if result != nil {
+			return result
+		}
+	case *ast.ThrowStmt:
+		val := Eval(node.Value, s)
+		if isError(val) {
+			return val
 		}
+		thrown := newError(node.Token.Line, "uncaught exception: %s", val.Inspect())
+		thrown.Thrown = val
+		return thrown
+	case *ast.TryStmt:
+		return evalTryStmt(node, s)
+	case *ast.ImportStmt:
+		return evalImportStmt(node, s)
+	case *ast.ExportStmt:
+		return evalExportStmt(node, s)
+	case *ast.FieldAssignmentStmt:
+		return evalFieldAssignmentStmt(node, s)
 	// Literals
 	case *ast.IntegerLiteral:
 		return &object.Integer{Value: node.Value}
@@ -70,6 +87,18 @@ func Eval(node ast.Node, s *object.Scope) object.Object {
 		return evalHashLiteral(node, s, node.Token.Line)
 	case *ast.FloatLiteral:
 		return &object.Float{Value: node.Value}
+	case *ast.StructDefinition:
+		fields := make([]string, len(node.Fields))
+		for i, field := range node.Fields {
+			fields[i] = field.Value
+		}
+		return &object.StructType{Name: node.Name, Fields: fields}
+	case *ast.MatchExpr:
+		return evalMatchExpr(node, s)
+	case *ast.EnumDefinition:
+		return evalEnumDefinition(node)
+	case *ast.StructLiteral:
+		return evalStructLiteral(node, s)
 	// Expressions
 	case *ast.Identifier:
 		return evalIdentifier(node, s)
@@ -85,6 +114,14 @@ func Eval(node ast.Node, s *object.Scope) object.Object {
 			return left
 		}
 
+		// the right side of ?? is only evaluated when the left side is null
+		if node.Operator == "??" {
+			if left != NULL {
+				return left
+			}
+			return Eval(node.Right, s)
+		}
+
 		right := Eval(node.Right, s)
 		if isError(right) {
 			return right
@@ -106,18 +143,49 @@ func Eval(node ast.Node, s *object.Scope) object.Object {
 			return args[0]
 		}
 
-		return applyFunction(function, args, node.Token.Line)
+		return applyFunction(function, args, node.Token.Line, s)
+	case *ast.MethodCallExpr:
+		return evalMethodCall(node, s)
 	case *ast.IndexExpr:
 		left := Eval(node.Left, s)
 		if isError(left) {
 			return left
 		}
+		if node.Optional && left == NULL {
+			return NULL
+		}
 		index := Eval(node.Index, s)
 		if isError(index) {
 			return index
 		}
 
-		return evalIndexExpr(left, index, node.Token.Line)
+		return evalIndexExpr(left, index, node.Strict, node.Token.Line)
+	case *ast.SliceExpr:
+		left := Eval(node.Left, s)
+		if isError(left) {
+			return left
+		}
+		if node.Optional && left == NULL {
+			return NULL
+		}
+
+		// a bound that is left out is null, like in the VM
+		bounds := []object.Object{NULL, NULL}
+		for i, bound := range []ast.Expr{node.Start, node.End} {
+			if bound == nil {
+				continue
+			}
+			bounds[i] = Eval(bound, s)
+			if isError(bounds[i]) {
+				return bounds[i]
+			}
+		}
+
+		sliced, err := object.Slice(left, bounds[0], bounds[1])
+		if err != nil {
+			return newError(node.Token.Line, "%s", err)
+		}
+		return sliced
 	}
 
 	return nil
@@ -141,6 +209,11 @@ func evalProgram(program *ast.Program, s *object.Scope) object.Object {
 	return result
 }
 
+// evalScopedBlock evaluates the block of an if, for or try in its own scope, so its declarations end with it
+func evalScopedBlock(block *ast.BlockStmt, s *object.Scope) object.Object {
+	return evalBlockStmt(block, object.NewEnclosedScope(s))
+}
+
 func evalBlockStmt(block *ast.BlockStmt, s *object.Scope) object.Object {
 	var result object.Object
 
@@ -214,9 +287,9 @@ func evalInfixExpr(operator string, left, right object.Object, line int) object.
 	case left.Type() == object.FloatObj && right.Type() == object.FloatObj:
 		return evalFloatInfixExpr(operator, left, right, line)
 	case operator == "==":
-		return nativeBoolToBooleanObject(left == right)
+		return nativeBoolToBooleanObject(object.Equal(left, right))
 	case operator == "!=":
-		return nativeBoolToBooleanObject(right != left)
+		return nativeBoolToBooleanObject(!object.Equal(left, right))
 	case operator == "&&" && left.Type() == object.BooleanObj && right.Type() == object.BooleanObj:
 		fallthrough
 	case operator == "||" && left.Type() == object.BooleanObj && right.Type() == object.BooleanObj:
@@ -261,15 +334,20 @@ func evalIntegerInfixExpr(operator string, left, right object.Object, line int)
 }
 
 func evalStringInfixExpr(operator string, left, right object.Object, line int) object.Object {
-	if operator != "+" {
-		return newError(line, "unknown operator: %s %s %s", left.Type(), operator, right.Type())
-	}
-
 	leftVal := left.(*object.String).Value
 	rightVal := right.(*object.String).Value
 
-	// Not sure about this line number here
-	return &object.String{Value: leftVal + rightVal}
+	switch operator {
+	case "+":
+		// Not sure about this line number here
+		return &object.String{Value: leftVal + rightVal}
+	case "==":
+		return nativeBoolToBooleanObject(leftVal == rightVal)
+	case "!=":
+		return nativeBoolToBooleanObject(leftVal != rightVal)
+	default:
+		return newError(line, "unknown operator: %s %s %s", left.Type(), operator, right.Type())
+	}
 }
 
 func evalBooleanComparisonExpr(operator string, left, right object.Object) object.Object {
@@ -293,9 +371,22 @@ func evalIfExpr(expr *ast.IfExpr, s *object.Scope) object.Object {
 	}
 
 	if isTruthy(condition) {
-		return Eval(expr.Consequence, s)
-	} else if expr.Alternative != nil {
-		return Eval(expr.Alternative, s)
+		return evalScopedBlock(expr.Consequence, s)
+	}
+
+	for _, branch := range expr.ElseIfs {
+		condition := Eval(branch.Condition, s)
+		if isError(condition) {
+			return condition
+		}
+
+		if isTruthy(condition) {
+			return evalScopedBlock(branch.Consequence, s)
+		}
+	}
+
+	if expr.Alternative != nil {
+		return evalScopedBlock(expr.Alternative, s)
 	} else {
 		return NULL
 	}
@@ -310,6 +401,10 @@ func evalIdentifier(node *ast.Identifier, s *object.Scope) object.Object {
 		return builtin
 	}
 
+	if constant, ok := builtInConstants[node.Value]; ok {
+		return constant
+	}
+
 	return newError(node.Token.Line, "identifier not found: %s", node.Value)
 
 }
@@ -328,34 +423,79 @@ func evalExpressions(exprs []ast.Expr, s *object.Scope) []object.Object {
 	return result
 }
 
-func evalIndexExpr(left, index object.Object, line int) object.Object {
+// in strict-null mode an index miss is an error instead of null
+func evalIndexExpr(left, index object.Object, strict bool, line int) object.Object {
 	switch {
 	case left.Type() == object.ArrayObj && index.Type() == object.IntegerObj:
-		return evalArrayIndexExpr(left, index, line)
+		return evalArrayIndexExpr(left.(*object.Array).Elements, index, strict, line)
+	case left.Type() == object.TupleObj && index.Type() == object.IntegerObj:
+		return evalArrayIndexExpr(left.(*object.Tuple).Elements, index, strict, line)
+	case left.Type() == object.StringObj && index.Type() == object.IntegerObj:
+		return evalStringIndexExpr(left.(*object.String).Value, index, strict, line)
 	case left.Type() == object.HashObj:
-		return evalHashIndexExpr(left, index, line)
+		return evalHashIndexExpr(left, index, strict, line)
+	case left.Type() == object.ExceptionObj:
+		return evalExceptionIndexExpr(left, index, line)
+	case left.Type() == object.ModuleObj:
+		export, err := left.(*object.Module).Export(index)
+		if err != nil {
+			return newError(line, "%s", err)
+		}
+		return export
+	case left.Type() == object.StructObj:
+		field, err := left.(*object.Struct).Field(index)
+		if err != nil {
+			return newError(line, "%s", err)
+		}
+		return field
+	case left.Type() == object.EnumObj:
+		variant, err := left.(*object.EnumType).Variant(index)
+		if err != nil {
+			return newError(line, "%s", err)
+		}
+		return variant
+	case left.Type() == object.VariantObj:
+		field, err := left.(*object.Variant).Field(index)
+		if err != nil {
+			return newError(line, "%s", err)
+		}
+		return field
 	default:
 		return newError(line, "index operator not supported: %s", left.Type())
 	}
 }
 
-func evalArrayIndexExpr(array, index object.Object, line int) object.Object {
-	arrayObj := array.(*object.Array)
+func evalArrayIndexExpr(elements []object.Object, index object.Object, strict bool, line int) object.Object {
 	idx := index.(*object.Integer).Value
-	arrLen := int64(len(arrayObj.Elements))
-	maxIdx := arrLen - 1
+	arrLen := int64(len(elements))
 
-	if (idx >= 0 && idx > maxIdx) || (idx < 0 && idx < -arrLen) {
-		return newError(line, "array index out of bounds")
+	// since idx < 0 here, we check against the max len. Example: idx = -2, len = 3 will return elems[1],
+	// the second to last elem
+	if idx < 0 {
+		idx += arrLen
 	}
 
-	if idx >= 0 {
-		return arrayObj.Elements[idx]
-	} else {
-		// since idx < 0 here, we check against the max len. Example: idx = -2, len = 3 will return elems[1],
-		// the second to last elem
-		return arrayObj.Elements[arrLen+idx]
+	if idx < 0 || idx >= arrLen {
+		if strict {
+			return newError(line, "array index out of bounds: %d", index.(*object.Integer).Value)
+		}
+		return NULL
+	}
+
+	return elements[idx]
+}
+
+// indexing a string gives a one character string, counting bytes like len does
+func evalStringIndexExpr(str string, index object.Object, strict bool, line int) object.Object {
+	char, ok := object.CharAt(str, index.(*object.Integer).Value)
+	if !ok {
+		if strict {
+			return newError(line, "string index out of bounds: %d", index.(*object.Integer).Value)
+		}
+		return NULL
 	}
+
+	return char
 }
 
 func evalForStmt(node *ast.ForStmt, s *object.Scope) object.Object {
@@ -367,7 +507,14 @@ func evalForStmt(node *ast.ForStmt, s *object.Scope) object.Object {
 	condition := conditionVal.(*object.Boolean).Value
 
 	for condition {
-		Eval(node.Body, s)
+		// errors and return values stop the loop and bubble up
+		result := evalScopedBlock(node.Body, s)
+		if result != nil {
+			rt := result.Type()
+			if rt == object.ReturnValueObj || rt == object.ErrorObj {
+				return result
+			}
+		}
 
 		conditionVal = Eval(node.Condition, s)
 
@@ -380,6 +527,42 @@ func evalForStmt(node *ast.ForStmt, s *object.Scope) object.Object {
 	return nil
 }
 
+func evalTryStmt(node *ast.TryStmt, s *object.Scope) object.Object {
+	result := evalScopedBlock(node.Body, s)
+
+	if errObj, ok := result.(*object.Error); ok && node.Catch != nil {
+		// runtime errors are caught as exceptions, thrown values are caught as they are
+		var thrown object.Object = &object.Exception{Message: errObj.Message}
+		if errObj.Thrown != nil {
+			thrown = errObj.Thrown
+		}
+
+		catchScope := object.NewEnclosedScope(s)
+		catchScope.DeclareVar(node.Param.Value, thrown, false, node.Token.Line)
+		result = Eval(node.Catch, catchScope)
+	}
+
+	if node.Finally != nil {
+		// an error or return in the finally block replaces the result of the try and catch blocks
+		finallyResult := evalScopedBlock(node.Finally, s)
+		if finallyResult != nil {
+			rt := finallyResult.Type()
+			if rt == object.ReturnValueObj || rt == object.ErrorObj {
+				return finallyResult
+			}
+		}
+	}
+
+	// like for loops, try statements produce null, only a return or an uncaught error gets out of them
+	if result != nil {
+		rt := result.Type()
+		if rt == object.ReturnValueObj || rt == object.ErrorObj {
+			return result
+		}
+	}
+	return NULL
+}
+
 func evalHashLiteral(node *ast.HashLiteral, s *object.Scope, line int) object.Object {
 	pairs := make(map[object.HashKey]object.HashPair)
 
@@ -389,7 +572,7 @@ func evalHashLiteral(node *ast.HashLiteral, s *object.Scope, line int) object.Ob
 			return key
 		}
 
-		hashKey, ok := key.(object.Hashable)
+		hashKey, ok := object.AsHashable(key)
 		if !ok {
 			return newError(line, "unusable as hash key: %s", key.Type())
 		}
@@ -407,38 +590,120 @@ func evalHashLiteral(node *ast.HashLiteral, s *object.Scope, line int) object.Ob
 }
 
 // Function calls
-func applyFunction(fn object.Object, args []object.Object, line int) object.Object {
+func applyFunction(fn object.Object, args []object.Object, line int, s *object.Scope) object.Object {
 	switch fn := fn.(type) {
 	case *object.Function:
-		extendedScope := extendFunctionScope(fn, args, line)
+		if len(args) != len(fn.Parameters) {
+			return newError(line, "wrong number of arguments. want=%d, got=%d", len(fn.Parameters), len(args))
+		}
+
+		extendedScope := extendFunctionScope(fn, args)
 		evaluated := Eval(fn.Body, extendedScope)
 		return unwrapReturnValue(evaluated)
 	case *object.BuiltIn:
-		if result := fn.Fn(args...); result != nil {
+		caller := &builtInCaller{line: line, scope: s}
+		result := fn.Fn(caller, args...)
+		if errObj, ok := result.(*object.Error); ok {
+			// errors of the functions the builtin called already name their line
+			if errObj == caller.err {
+				return errObj
+			}
+			return newError(line, "%s", errObj.Message)
+		}
+		if result != nil {
 			return result
 		}
 		return NULL
 	default:
-		return newError(line, "not a function: %s", fn.Type())
+		return newError(line, "calling non-function %s", fn.Inspect())
+	}
+}
+
+// evalMethodCall calls the member Method of the receiver with the arguments, or the function Method with the receiver
+// followed by the arguments when the receiver has no such member
+func evalMethodCall(node *ast.MethodCallExpr, s *object.Scope) object.Object {
+	// the function the call falls back on is looked up before the receiver and arguments are evaluated, like in the VM
+	name := node.Method.Value
+	var fallback object.Object
+	if val, _, ok := s.Get(name); ok {
+		fallback = val.Value
+	} else if builtin, ok := builtIns[name]; ok {
+		fallback = builtin
+	}
+
+	receiver := Eval(node.Receiver, s)
+	if isError(receiver) {
+		return receiver
+	}
+	if node.Optional && receiver == NULL {
+		return NULL
 	}
+	args := evalExpressions(node.Arguments, s)
+	if len(args) == 1 && isError(args[0]) {
+		return args[0]
+	}
+
+	if member, ok := object.Member(receiver, name); ok {
+		return applyFunction(member, args, node.Token.Line, s)
+	}
+	if fallback != nil {
+		return applyFunction(fallback, append([]object.Object{receiver}, args...), node.Token.Line, s)
+	}
+
+	return newError(node.Token.Line, "%s has no method %s", receiver.Type(), name)
+}
+
+// builtInCaller calls functions back for a builtin called on line from scope
+type builtInCaller struct {
+	line  int
+	scope *object.Scope
+	err   *object.Error
 }
 
-func evalHashIndexExpr(hash, index object.Object, line int) object.Object {
+func (c *builtInCaller) Random() *rand.Rand {
+	return c.scope.Random()
+}
+
+func (c *builtInCaller) IO() *object.Capabilities {
+	return c.scope.IO()
+}
+
+func (c *builtInCaller) Call(fn object.Object, args ...object.Object) object.Object {
+	result := applyFunction(fn, args, c.line, c.scope)
+	if errObj, ok := result.(*object.Error); ok {
+		c.err = errObj
+	}
+
+	return result
+}
+
+func evalHashIndexExpr(hash, index object.Object, strict bool, line int) object.Object {
 	hashObj := hash.(*object.Hash)
 
-	key, ok := index.(object.Hashable)
+	key, ok := object.AsHashable(index)
 	if !ok {
 		return newError(line, "unusable as hash key: %s", index.Type())
 	}
 
 	pair, ok := hashObj.Pairs[key.HashKey()]
 	if !ok {
+		if strict {
+			return newError(line, "key not found: %s", index.Inspect())
+		}
 		return NULL
 	}
 
 	return pair.Value
 }
 
+func evalExceptionIndexExpr(exception, index object.Object, line int) object.Object {
+	if key, ok := index.(*object.String); !ok || key.Value != "message" {
+		return newError(line, "unknown exception field: %s", index.Inspect())
+	}
+
+	return &object.String{Value: exception.(*object.Exception).Message}
+}
+
 // Utilty functions
 func nativeBoolToBooleanObject(input bool) *object.Boolean {
 	if input {
@@ -471,11 +736,12 @@ func isError(obj object.Object) bool {
 	return false
 }
 
-func extendFunctionScope(fn *object.Function, args []object.Object, line int) *object.Scope {
+func extendFunctionScope(fn *object.Function, args []object.Object) *object.Scope {
 	scope := object.NewEnclosedScope(fn.Scope)
 
 	for paramIdx, param := range fn.Parameters {
-		scope.DeclareVar(param.Value, args[paramIdx], true, line) // arguments from a function should be constant
+		// arguments from a function should be constant. They are set directly because a const declaration rejects null
+		scope.Set(param.Value, args[paramIdx], true)
 	}
 
 	return scope
@@ -2,10 +2,14 @@ package evaluator
 
 import (
 	"fmt"
+	"os"
+	"path/filepath"
 	"quonk/lexer"
 	"quonk/object"
 	"quonk/parser"
+	"strings"
 	"testing"
+	"time"
 )
 
 func TestEvalIntegerExpr(t *testing.T) {
@@ -103,6 +107,10 @@ func TestIfElseExpressions(t *testing.T) {
 		{"if (1 > 2) { 10 }", nil},
 		{"if (1 > 2) { 10 } else { 20 }", 20},
 		{"if (1 < 2) { 10 } else { 20 }", 10},
+		{"if (false) { 10 } else if (true) { 20 } else { 30 }", 20},
+		{"if (false) { 10 } elseif (false) { 20 } else { 30 }", 30},
+		{"if (1 > 2) { 10 } else if (1 > 3) { 20 } elseif (1 < 4) { 40 }", 40},
+		{"if (false) { 10 } else if (false) { 20 }", nil},
 	}
 	for _, tt := range tests {
 		evaluated := testEval(tt.input)
@@ -185,6 +193,16 @@ func TestErrorHandling(t *testing.T) {
 			"unusable as hash key: Function on line 1",
 			1,
 		},
+		{
+			`"use strict-null"; {"name": "QuonkScript"}["age"];`,
+			"key not found: age on line 1",
+			1,
+		},
+		{
+			`throw error("boom");`,
+			"uncaught exception: Error: boom on line 1",
+			1,
+		},
 	}
 
 	for _, tt := range tests {
@@ -226,6 +244,83 @@ func TestVarDeclarationStmts(t *testing.T) {
 	}
 }
 
+func TestZeroValues(t *testing.T) {
+	tests := []struct {
+		source   string
+		expected interface{}
+	}{
+		{"mut count int; count", 0},
+		{"mut done bool; done", false},
+		{`"use strict-null"; mut count int; count = count + 1; count`, 1},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.source)
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case bool:
+			testBooleanObject(t, evaluated, expected)
+		}
+	}
+}
+
+func TestExceptions(t *testing.T) {
+	tests := []struct {
+		source   string
+		expected interface{}
+	}{
+		{`mut r = 0; try { throw 1; r = 2; } catch (e) { r = e; } r`, 1},
+		{`mut r = 0; try { r = 2; } catch (e) { r = 3; } r`, 2},
+		{`mut r = ""; try { len(1); } catch (e) { r = e["message"]; } r`, "argument to `len` of wrong type. got=Integer on line 1"},
+		{`mut r = ""; try { throw error("boom"); } catch (e) { r = e["message"]; } r`, "boom"},
+		{`mut r = 0; try { r = 1; } finally { r = r + 10; } r`, 11},
+		{`mut r = 0; try { throw 1; } catch (e) { r = 1; } finally { r = r + 10; } r`, 11},
+		{`
+		mut r = 0;
+		try {
+			try { throw 1; } catch (e) { throw e + 1; }
+		} catch (e) {
+			r = e;
+		}
+		r`, 2},
+		{`
+		const thrower = func(x) { if (x > 0) { throw x; } 0 };
+		const deep = func(x) { thrower(x) + 1 };
+		mut r = 0;
+		try { deep(7); } catch (e) { r = e; }
+		r`, 7},
+		{`
+		mut cleanups = 0;
+		const f = func() {
+			try { return 1; } finally { cleanups = cleanups + 1; }
+		};
+		f() + f() + cleanups`, 4},
+		{`
+		mut r = 0;
+		const f = func() { mut i = 0; for (i < 3) { i = i + 1; if (i == 2) { throw i; } } };
+		try { f(); } catch (e) { r = e; }
+		r`, 2},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.source)
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case string:
+			str, ok := evaluated.(*object.String)
+			if !ok {
+				t.Errorf("object is not String. got=%T (%+v)", evaluated, evaluated)
+				continue
+			}
+			if str.Value != expected {
+				t.Errorf("String has wrong value. want=%q, got=%q", expected, str.Value)
+			}
+		}
+	}
+}
+
 func TestFunctionObject(t *testing.T) {
 	input := "func(x) { x + 2; };"
 	evaluated := testEval(input)
@@ -274,6 +369,53 @@ func TestClosures(t *testing.T) {
 	testIntegerObject(t, testEval(input), 4)
 }
 
+func TestBlockScoping(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"mut x = 1; if (true) { mut x = 2; } x", 1},
+		{"if (true) { mut y = 1; } if (true) { mut y = 2; y }", 2},
+		{"mut x = 1; if (true) { x = 2; } x", 2},
+		{"mut i = 0; mut total = 0; for (i < 3) { mut sq = i * i; total = total + sq; i = i + 1; } total", 5},
+		{"mut e = 1; try { throw 2; } catch (e) { e; } e", 1},
+		{"if (true) { mut y = 1; } y", "identifier not found: y on line 1"},
+		{"mut i = 0; for (i < 1) { mut z = 1; i = i + 1; } z", "identifier not found: z on line 1"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case string:
+			errObj, ok := evaluated.(*object.Error)
+			if !ok {
+				t.Errorf("no error object returned. got=%T(%+v)", evaluated, evaluated)
+				continue
+			}
+			if errObj.Message != expected {
+				t.Errorf("wrong error message. expected=%q, got=%q", expected, errObj.Message)
+			}
+		}
+	}
+}
+
+func TestClosureCounters(t *testing.T) {
+	input := `
+	const newCounter = func() {
+		mut count = 0;
+		func() { count = count + 1; count };
+	}
+	const a = newCounter();
+	const b = newCounter();
+	a();
+	a();
+	b();
+	a();`
+	testIntegerObject(t, testEval(input), 3)
+}
+
 func TestVariableAssignment(t *testing.T) {
 	tests := []struct {
 		source   string
@@ -343,8 +485,510 @@ func TestBuiltInFunction(t *testing.T) {
 		{`len("")`, 0},
 		{`len("four")`, 4},
 		{`len("hello world")`, 11},
-		{`len(1)`, "argument to `len` of wrong type. got=Integer"},
-		{`len("one", "two")`, "`len` expects one argument"},
+		{`len(1)`, "argument to `len` of wrong type. got=Integer on line 1"},
+		{`len("one", "two")`, "`len` expects one argument on line 1"},
+		{`const f = func(x) {
+			len(x)
+		};
+		f(1) + 1`, "argument to `len` of wrong type. got=Integer on line 2"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.source)
+
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case string:
+			errObj, ok := evaluated.(*object.Error)
+			if !ok {
+				t.Errorf("object is not Error. got=%T (%+v)", evaluated, evaluated)
+				continue
+			}
+			if errObj.Message != expected {
+				t.Errorf("wrong error message. expected=%q, got=%q", expected, errObj.Message)
+			}
+		}
+	}
+}
+
+func TestHigherOrderBuiltins(t *testing.T) {
+	tests := []struct {
+		source   string
+		expected interface{}
+	}{
+		{"map([1, 2, 3], func(x) { x * 2 }) == [2, 4, 6]", true},
+		{`map(["a", "bb"], len) == [1, 2]`, true},
+		{"filter([1, 2, 3, 4], func(x) { x > 2 }) == [3, 4]", true},
+		{"filter([1, null, 2], func(x) { x }) == [1, 2]", true},
+		{"reduce([1, 2, 3, 4], func(acc, x) { acc + x }, 10)", 20},
+		{"reduce([1, 2, 3, 4], func(acc, x) { acc * x })", 24},
+		{"mut total = 0; each([1, 2, 3], func(x) { total = total + x; }); total", 6},
+		{"find([1, 2, 3, 4], func(x) { x > 2 })", 3},
+		{"any([1, 2, 3], func(x) { x > 2 })", true},
+		{"all([1, 2, 3], func(x) { x > 1 })", false},
+		{"map([[1, 2], [3]], func(xs) { reduce(xs, func(a, b) { a + b }, 0) }) == [3, 3]", true},
+		{`mut r = 0; try { map([1], func(x) { throw 7; }); } catch (e) { r = e; } r`, 7},
+		{"map(1, func(x) { x })", "first argument to `map` must be array type on line 1"},
+		{"filter([1], 1)", "second argument to `filter` must be a function. got=Integer on line 1"},
+		{"reduce([], func(a, b) { a })", "`reduce` of empty array with no initial value on line 1"},
+		{"map([1], func(a, b) { a })", "wrong number of arguments. want=2, got=1 on line 1"},
+		{`map([1, 2], func(x) {
+			len(x)
+		})`, "argument to `len` of wrong type. got=Integer on line 2"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.source)
+
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case bool:
+			testBooleanObject(t, evaluated, expected)
+		case string:
+			errObj, ok := evaluated.(*object.Error)
+			if !ok {
+				t.Errorf("object is not Error. got=%T (%+v)", evaluated, evaluated)
+				continue
+			}
+			if errObj.Message != expected {
+				t.Errorf("wrong error message. expected=%q, got=%q", expected, errObj.Message)
+			}
+		}
+	}
+}
+
+func TestStringBuiltins(t *testing.T) {
+	tests := []struct {
+		source   string
+		expected interface{}
+	}{
+		{`split("a,b,c", ",") == ["a", "b", "c"]`, true},
+		{`join(split("a b  c"), "-") == "a-b-c"`, true},
+		{`trim("  hi  ") == "hi"`, true},
+		{`contains("quonk", "on")`, true},
+		{`starts_with("quonk", "on")`, false},
+		{`index_of("quonk", "on")`, 2},
+		{`upper(replace("a-b", "-", "+")) == "A+B"`, true},
+		{`reverse(chars("abc")) == ["c", "b", "a"]`, true},
+		{`format("%-4s|%6.2f|%03d", "ab", 3.14159, 7) == "ab  |  3.14|007"`, true},
+		{`repeat("a", -1)`, "negative count for `repeat`: -1 on line 1"},
+		{`repeat("ab", 9223372036854775807)`, "result of `repeat` is too long: 9223372036854775807 copies of 2 bytes on line 1"},
+		{`format("%d", "a")`, "`format` verb %d does not accept String on line 1"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.source)
+
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case bool:
+			testBooleanObject(t, evaluated, expected)
+		case string:
+			errObj, ok := evaluated.(*object.Error)
+			if !ok {
+				t.Errorf("object is not Error. got=%T (%+v)", evaluated, evaluated)
+				continue
+			}
+			if errObj.Message != expected {
+				t.Errorf("wrong error message. expected=%q, got=%q", expected, errObj.Message)
+			}
+		}
+	}
+}
+
+func TestMathBuiltins(t *testing.T) {
+	tests := []struct {
+		source   string
+		expected interface{}
+	}{
+		{`abs(-3)`, 3},
+		{`floor(2.7)`, 2},
+		{`round(-2.5)`, -3},
+		{`pow(2, 10)`, 1024},
+		{`max(3, 9, 4)`, 9},
+		{`min([5, 2, 8])`, 2},
+		{`sqrt(16.0) == 4.0`, true},
+		{`PI > 3.14 && PI < 3.15`, true},
+		{`atan2(0, 1) == 0.0`, true},
+		{`sqrt(-1)`, "math domain error in `sqrt` on line 1"},
+		{`log(0)`, "math domain error in `log` on line 1"},
+		{`pow(10, 19)`, "`pow` of 10 and 19 does not fit in an int on line 1"},
+		{`max()`, "`max` expects at least one number on line 1"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.source)
+
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case bool:
+			testBooleanObject(t, evaluated, expected)
+		case string:
+			errObj, ok := evaluated.(*object.Error)
+			if !ok {
+				t.Errorf("object is not Error. got=%T (%+v)", evaluated, evaluated)
+				continue
+			}
+			if errObj.Message != expected {
+				t.Errorf("wrong error message. expected=%q, got=%q", expected, errObj.Message)
+			}
+		}
+	}
+}
+
+type fakeClock struct {
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	return c.now
+}
+
+func (c *fakeClock) Monotonic() time.Duration {
+	return time.Duration(c.now.UnixNano())
+}
+
+func (c *fakeClock) Sleep(d time.Duration) {
+	c.now = c.now.Add(d)
+}
+
+func TestHashBuiltins(t *testing.T) {
+	tests := []struct {
+		source   string
+		expected interface{}
+	}{
+		{`keys({2.5: 1}) == [2.5]`, true},
+		{`len(merge({"a": 1}, {"b": 2}))`, 2},
+		{`has_key(delete({"a": 1}, "a"), "a")`, false},
+		{`get({}, "a", 7)`, 7},
+		{`from_entries(entries({"a": [1]})) == {"a": [1]}`, true},
+		{`get({}, {})`, "unusable as hash key: Hash on line 1"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.source)
+
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case bool:
+			testBooleanObject(t, evaluated, expected)
+		case string:
+			errObj, ok := evaluated.(*object.Error)
+			if !ok {
+				t.Errorf("object is not Error. got=%T (%+v)", evaluated, evaluated)
+				continue
+			}
+			if errObj.Message != expected {
+				t.Errorf("wrong error message. expected=%q, got=%q", expected, errObj.Message)
+			}
+		}
+	}
+}
+
+func TestSortBuiltins(t *testing.T) {
+	tests := []struct {
+		source   string
+		expected interface{}
+	}{
+		{`sort([3, "a", 1.5, 2]) == [1.5, 2, 3, "a"]`, true},
+		{`sort([1, 3, 2], func(a, b) { b - a }) == [3, 2, 1]`, true},
+		{`sort_by([[2, "x"], [1, "y"], [2, "a"]], first) == [[1, "y"], [2, "x"], [2, "a"]]`, true},
+		{`sort([1, null])`, "`sort` cannot order Null on line 1"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.source)
+
+		switch expected := tt.expected.(type) {
+		case bool:
+			testBooleanObject(t, evaluated, expected)
+		case string:
+			errObj, ok := evaluated.(*object.Error)
+			if !ok {
+				t.Errorf("object is not Error. got=%T (%+v)", evaluated, evaluated)
+				continue
+			}
+			if errObj.Message != expected {
+				t.Errorf("wrong error message. expected=%q, got=%q", expected, errObj.Message)
+			}
+		}
+	}
+}
+
+func TestRandomBuiltins(t *testing.T) {
+	tests := []struct {
+		source   string
+		expected interface{}
+	}{
+		{`seed(3); const a = [rand_int(1, 100), rand_float(), shuffle([1, 2, 3])]; seed(3); a == [rand_int(1, 100), rand_float(), shuffle([1, 2, 3])]`, true},
+		{`const f = func() { rand_int(1, 1000000) }; seed(9); const a = f(); seed(9); a == map([1], func(x) { f() })[0]`, true},
+		{`choice(["a"]) == "a"`, true},
+		{`rand_int(1, 0)`, "empty range for `rand_int`: 1 > 0 on line 1"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.source)
+
+		switch expected := tt.expected.(type) {
+		case bool:
+			testBooleanObject(t, evaluated, expected)
+		case string:
+			errObj, ok := evaluated.(*object.Error)
+			if !ok {
+				t.Errorf("object is not Error. got=%T (%+v)", evaluated, evaluated)
+				continue
+			}
+			if errObj.Message != expected {
+				t.Errorf("wrong error message. expected=%q, got=%q", expected, errObj.Message)
+			}
+		}
+	}
+}
+
+func TestTimeBuiltins(t *testing.T) {
+	saved := object.TimeSource
+	defer func() { object.TimeSource = saved }()
+	object.TimeSource = &fakeClock{now: time.Date(2024, 3, 15, 12, 30, 45, 0, time.UTC)}
+
+	tests := []struct {
+		source   string
+		expected interface{}
+	}{
+		{`const t = monotonic(); sleep(250); monotonic() - t == milliseconds(250)`, true},
+		{`format_time(now(), "15:04:05.000") == "12:30:45.250"`, true},
+		{`parse_time("2024-03-15", "2006-01-02") < now()`, true},
+		{`format_duration(minutes(90)) == "1h30m0s"`, true},
+		{`sleep(1.5)`, "argument to `sleep` must be int type. got=Float on line 1"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.source)
+
+		switch expected := tt.expected.(type) {
+		case bool:
+			testBooleanObject(t, evaluated, expected)
+		case string:
+			errObj, ok := evaluated.(*object.Error)
+			if !ok {
+				t.Errorf("object is not Error. got=%T (%+v)", evaluated, evaluated)
+				continue
+			}
+			if errObj.Message != expected {
+				t.Errorf("wrong error message. expected=%q, got=%q", expected, errObj.Message)
+			}
+		}
+	}
+}
+
+func TestRegexBuiltins(t *testing.T) {
+	tests := []struct {
+		source   string
+		expected interface{}
+	}{
+		{`const re = re_compile("\d+"); re_match(re, "a1") && !re_match(re, "ab")`, true},
+		{`re_find_all("(\d)", "1 2") == [["1", "1"], ["2", "2"]]`, true},
+		{`re_replace("(a)(b)", "abab", "$2$1") == "baba"`, true},
+		{`len(re_split("\s+", "a  b c"))`, 3},
+		{`re_find("(", "a")`, "invalid regular expression for `re_find`: error parsing regexp: missing closing ): `(` on line 1"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.source)
+
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case bool:
+			testBooleanObject(t, evaluated, expected)
+		case string:
+			errObj, ok := evaluated.(*object.Error)
+			if !ok {
+				t.Errorf("object is not Error. got=%T (%+v)", evaluated, evaluated)
+				continue
+			}
+			if errObj.Message != expected {
+				t.Errorf("wrong error message. expected=%q, got=%q", expected, errObj.Message)
+			}
+		}
+	}
+}
+
+func TestJSONBuiltins(t *testing.T) {
+	tests := []struct {
+		source   string
+		expected interface{}
+	}{
+		{`json_encode({"a": [1, 2.0, null]}) == "{" + json_encode("a") + ":[1,2.0,null]}"`, true},
+		{`json_decode(json_encode({"n": [7]}))["n"][0]`, 7},
+		{`json_decode("[1.5]")[0] == 1.5`, true},
+		{`json_encode(func(x) { x })`, "`json_encode` cannot encode Function on line 1"},
+		{`json_decode("{")`, "`json_decode` invalid JSON: unexpected EOF on line 1"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.source)
+
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case bool:
+			testBooleanObject(t, evaluated, expected)
+		case string:
+			errObj, ok := evaluated.(*object.Error)
+			if !ok {
+				t.Errorf("object is not Error. got=%T (%+v)", evaluated, evaluated)
+				continue
+			}
+			if errObj.Message != expected {
+				t.Errorf("wrong error message. expected=%q, got=%q", expected, errObj.Message)
+			}
+		}
+	}
+}
+
+func TestIOBuiltins(t *testing.T) {
+	dir := t.TempDir()
+	outside := t.TempDir()
+
+	capabilities := object.NewCapabilities(strings.NewReader("line\n"))
+	capabilities.AllowRead(dir)
+	capabilities.AllowWrite(dir)
+
+	tests := []struct {
+		source   string
+		expected interface{}
+	}{
+		{fmt.Sprintf("write_file(\"%[1]s/a.txt\", \"x\ny\"); read_lines(\"%[1]s/a.txt\") == [\"x\", \"y\"]", dir), true},
+		{fmt.Sprintf(`exists("%s/a.txt")`, dir), true},
+		{`read_line() == "line"`, true},
+		{
+			fmt.Sprintf(`list_dir("%s")`, outside),
+			fmt.Sprintf("`list_dir` is not allowed to read %s, run with --allow-read on line 1", outside),
+		},
+	}
+
+	for _, tt := range tests {
+		scope := object.NewScope()
+		scope.SetIO(capabilities)
+		evaluated := Eval(parser.New(lexer.New(tt.source)).ParseProgram(), scope)
+
+		switch expected := tt.expected.(type) {
+		case bool:
+			testBooleanObject(t, evaluated, expected)
+		case string:
+			errObj, ok := evaluated.(*object.Error)
+			if !ok {
+				t.Errorf("object is not Error. got=%T (%+v)", evaluated, evaluated)
+				continue
+			}
+			if errObj.Message != expected {
+				t.Errorf("wrong error message. expected=%q, got=%q", expected, errObj.Message)
+			}
+		}
+	}
+
+	// the grants belong to the program they were given to
+	evaluated := testEval(fmt.Sprintf(`exists("%s/a.txt")`, dir))
+	expected := fmt.Sprintf("`exists` is not allowed to read %s/a.txt, run with --allow-read on line 1", dir)
+	if errObj, ok := evaluated.(*object.Error); !ok || errObj.Message != expected {
+		t.Errorf("wrong result for a program without grants. want=%q, got=%s", expected, evaluated.Inspect())
+	}
+}
+
+func TestModules(t *testing.T) {
+	dir := t.TempDir()
+	os.Mkdir(filepath.Join(dir, "lib"), 0o755)
+	os.WriteFile(filepath.Join(dir, "lib", "shapes.qk"), []byte(`
+import { square } from "square.qk";
+export const pi = 3;
+export const made = rand_int(0, 1000000000);
+export const area = func(r) { pi * square(r) };
+const hidden = 1;
+`), 0o644)
+	os.WriteFile(filepath.Join(dir, "lib", "square.qk"), []byte("export const square = func(x) { x * x };"), 0o644)
+	os.WriteFile(filepath.Join(dir, "a.qk"), []byte(`import "b.qk"; export const a = 1;`), 0o644)
+	os.WriteFile(filepath.Join(dir, "b.qk"), []byte(`import "a.qk"; export const b = 2;`), 0o644)
+	os.WriteFile(filepath.Join(dir, "broken.qk"), []byte("const a = 1;\nexport func() {};"), 0o644)
+	// a directory of the program named std is not the standard library
+	os.Mkdir(filepath.Join(dir, "lib", "std"), 0o755)
+	os.WriteFile(filepath.Join(dir, "lib", "std", "list.qk"), []byte("export const sum = func(a) { 100 };"), 0o644)
+	os.WriteFile(filepath.Join(dir, "lib", "sums.qk"), []byte(`
+import "std/list.qk" as mine;
+import "std:list";
+export const total = mine.sum([1]) + list.sum([1, 2]);
+`), 0o644)
+
+	tests := []struct {
+		source   string
+		expected interface{}
+	}{
+		{fmt.Sprintf(`import "%s/lib/shapes.qk"; shapes.area(2)`, dir), 12},
+		{fmt.Sprintf(`import { area, pi } from "%s/lib/shapes.qk"; area(pi)`, dir), 27},
+		{fmt.Sprintf(`const pi = 1; import "%s/lib/shapes.qk" as s; pi + s.pi`, dir), 4},
+		{fmt.Sprintf(`import "%[1]s/lib/shapes.qk" as s; import "%[1]s/lib/shapes.qk" as t; s.made == t.made`, dir), true},
+		{fmt.Sprintf(`import "%s/lib/sums.qk"; sums.total`, dir), 103},
+		{fmt.Sprintf(`import "%s/lib/shapes.qk" as s; s.hidden`, dir), "module shapes has no export hidden on line 1"},
+		{fmt.Sprintf(`import { hidden } from "%s/lib/shapes.qk";`, dir), "module shapes has no export hidden on line 1"},
+		{fmt.Sprintf(`import "%s/a.qk";`, dir), "import cycle: a.qk -> b.qk -> a.qk on line 1"},
+		{fmt.Sprintf(`import "%s/missing.qk";`, dir), "cannot import missing.qk: no such file or directory on line 1"},
+		{`import "std:missing";`, "cannot import missing.qk: file does not exist on line 1"},
+		{fmt.Sprintf(`import "%s/broken.qk";`, dir), "broken.qk line 2: export must be followed by a const, struct or enum declaration on line 1"},
+		{fmt.Sprintf(`if (true) { import "%s/a.qk"; }`, dir), "import must be at the top level of a file on line 1"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.source)
+
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case bool:
+			testBooleanObject(t, evaluated, expected)
+		case string:
+			errObj, ok := evaluated.(*object.Error)
+			if !ok {
+				t.Errorf("object is not Error. got=%T (%+v)", evaluated, evaluated)
+				continue
+			}
+			if errObj.Message != expected {
+				t.Errorf("wrong error message. expected=%q, got=%q", expected, errObj.Message)
+			}
+		}
+	}
+
+	// a program from a file imports relative to the file rather than to the working directory
+	scope := object.NewScope()
+	scope.SetFile(filepath.Join(dir, "main.qk"))
+	program := parser.New(lexer.New(`import "lib/shapes.qk"; shapes.pi`)).ParseProgram()
+	testIntegerObject(t, Eval(program, scope), 3)
+}
+
+func TestStructs(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "geo.qk"), []byte("export struct Point { x, y }"), 0o644)
+
+	tests := []struct {
+		source   string
+		expected interface{}
+	}{
+		{`struct P { x, y } const p = P{x: 1, y: 2}; p.x + p.y`, 3},
+		{`struct P { x, y } mut p = P{y: 2, x: 1}; p.x + p.y`, 3},
+		{`struct P { x, y } P{x: 1}.y == null`, true},
+		{`struct P { x } mut p = P{x: 1}; p.x = 5; p.x`, 5},
+		{`struct P { x } const p = P{x: 1}; p.x = p.x + 1; p.x`, 2},
+		{`struct C { n } const c = C{n: 0}; const inc = func() { c.n = c.n + 1; }; inc(); inc(); c.n`, 2},
+		{`struct P { x } P{x: [1]} == P{x: [1]}`, true},
+		{`struct P { x } struct Q { x } P{x: 1} == Q{x: 1}`, false},
+		{fmt.Sprintf(`import "%s/geo.qk"; const p = geo.Point{x: 1, y: 2}; p.y`, dir), 2},
+		{`struct P { x } mut p = P{x: 1}; p.z`, "struct P has no field z on line 1"},
+		{`mut p = 1; p.x = 2;`, "field assignment not supported: Integer on line 1"},
+		{`mut T = 1; T{x: 1}`, "not a struct type: Integer on line 1"},
+		{`struct P { x } mut T = P; T{z: 1}`, "struct P has no field z on line 1"},
 	}
 
 	for _, tt := range tests {
@@ -353,6 +997,157 @@ func TestBuiltInFunction(t *testing.T) {
 		switch expected := tt.expected.(type) {
 		case int:
 			testIntegerObject(t, evaluated, int64(expected))
+		case bool:
+			testBooleanObject(t, evaluated, expected)
+		case string:
+			errObj, ok := evaluated.(*object.Error)
+			if !ok {
+				t.Errorf("object is not Error. got=%T (%+v)", evaluated, evaluated)
+				continue
+			}
+			if errObj.Message != expected {
+				t.Errorf("wrong error message. expected=%q, got=%q", expected, errObj.Message)
+			}
+		}
+	}
+}
+
+func TestEnums(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "shapes.qk"), []byte("export enum Shape { Dot, Circle(r) }"), 0o644)
+
+	tests := []struct {
+		source   string
+		expected interface{}
+	}{
+		{`enum Color { Red, Green } Color.Red == Color.Red`, true},
+		{`enum Color { Red, Green } Color.Red == Color.Green`, false},
+		{`enum Color { Red } enum Other { Red } Color.Red == Other.Red`, false},
+		{`enum Color { Red, Green } format("%v", Color.Green) == "Color.Green"`, true},
+		{`enum Result { Ok(value), Err(msg) } format("%v", Result.Err("no")) == "Result.Err(no)"`, true},
+		{`enum Result { Ok(value), Err(msg) } Result.Ok(3) == Result.Ok(3)`, true},
+		{`enum Result { Ok(value), Err(msg) } Result.Ok(3) == Result.Err(3)`, false},
+		{`enum Result { Ok(value) } const r = Result.Ok(3); r.value + r["value"]`, 6},
+		{`enum Pair { Of(a, b) } const mk = Pair.Of; mk(1, 2).b`, 2},
+		{`enum Result { Ok(value) } const h = {Result.Ok(1): 10}; h[Result.Ok(1)]`, 10},
+		{fmt.Sprintf(`import "%s/shapes.qk"; shapes.Shape.Circle(2).r`, dir), 2},
+		{`enum Color { Red } Color.Purple`, "enum Color has no variant Purple on line 1"},
+		{`enum Result { Ok(value) } Result.Ok(1).nope`, "variant Result.Ok has no field nope on line 1"},
+		{`enum Result { Ok(value) } Result.Ok(1, 2)`, "wrong number of arguments to Result.Ok. want=1, got=2 on line 1"},
+		{`enum Result { Ok(value) } format("%v", Result.Ok) == "Result.Ok"`, true},
+		{`enum Color { Red } Color.Red(1)`, "calling non-function Color.Red on line 1"},
+		{`enum Result { Ok(value) } Result.Ok({"a": 1}).value["a"]`, 1},
+		{`enum Result { Ok(value) } Result.Ok(null).value == null`, true},
+		{`enum Result { Ok(value) } len(Result.Ok([1, 2]).value)`, 2},
+		{`enum Result { Ok(value) } {Result.Ok({}): 1}`, "unusable as hash key: Variant on line 1"},
+		{`enum Result { Ok(value) } {}[Result.Ok(Result.Ok(null))]`, "unusable as hash key: Variant on line 1"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.source)
+
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case bool:
+			testBooleanObject(t, evaluated, expected)
+		case string:
+			errObj, ok := evaluated.(*object.Error)
+			if !ok {
+				t.Errorf("object is not Error. got=%T (%+v)", evaluated, evaluated)
+				continue
+			}
+			if errObj.Message != expected {
+				t.Errorf("wrong error message. expected=%q, got=%q", expected, errObj.Message)
+			}
+		}
+	}
+}
+
+func TestMatch(t *testing.T) {
+	tests := []struct {
+		source   string
+		expected interface{}
+	}{
+		{`match (2) { 1 => "one", 2 => "two", _ => "many" } == "two"`, true},
+		{`match (5) { 1 => "one", 2 => "two", _ => "many" } == "many"`, true},
+		{`match (5) { 1 => "one" } == null`, true},
+		{`match (-1.5) { 1.5 => 1, -1.5 => 2 }`, 2},
+		{`match (1) { 1.0 => 1, 1 => 2 }`, 2},
+		{`match ([1, 2]) { [a] => a, [a, b] => a + b }`, 3},
+		{`match ([1, [2, 3]]) { [a, [b, c]] => a + b + c }`, 6},
+		{`match (tuple(1, 2)) { [_, b] => b }`, 2},
+		{`match ({"k": 5, "z": 1}) { {"k": v} if v > 10 => 0, {"k": v} => v }`, 5},
+		{`match ({"a": 1}) { {"b": v} => v, {} => 7 }`, 7},
+		{`match (7) { x if x > 3 => { mut y = x * 2; y } }`, 14},
+		{`mut x = 1; match (5) { x => x }; x`, 1},
+		{`mut r = [match (1) { 1 => { mut q = 1; } }]; r == [null]`, true},
+		{`enum R { Ok(v), Err(m) } match (R.Err(4)) { R.Ok(v) => v, R.Err(m) => m * 2 }`, 8},
+		{`enum C { Red, Green } match (C.Green) { C.Red => 1, C.Green => 2 }`, 2},
+		{`enum O { Some(v), None } match (O.Some([1, 2])) { O.Some([a, b]) => a + b, O.None => 0 }`, 3},
+		{`enum O { Some(v) } match (3) { O.Some(v) => v, _ => 0 }`, 0},
+		{`const fs = [1, 2].map(func(x) { match (x) { n => func() { n } } }); fs[0]() + fs[1]()`, 3},
+		{`match (1) { 1 => 10, 2 => 20, 3 => 30, 4 => 40, _ => 0 }`, 10},
+		{`enum R { Ok(v) } match (R.Ok(1)) { R.Ok(a, b) => 1 }`, "wrong number of fields in pattern for R.Ok. want=1, got=2 on line 1"},
+		{`match (1) { x if y => 1 }`, "identifier not found: y on line 1"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.source)
+
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case bool:
+			testBooleanObject(t, evaluated, expected)
+		case string:
+			errObj, ok := evaluated.(*object.Error)
+			if !ok {
+				t.Errorf("object is not Error. got=%T (%+v)", evaluated, evaluated)
+				continue
+			}
+			if errObj.Message != expected {
+				t.Errorf("wrong error message. expected=%q, got=%q", expected, errObj.Message)
+			}
+		}
+	}
+}
+
+func TestMethodCalls(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "geo.qk"), []byte("export const area = func(r) { r * r };"), 0o644)
+
+	tests := []struct {
+		source   string
+		expected interface{}
+	}{
+		{`[1, 2, 3].len()`, 3},
+		{`"honk".upper() == "HONK"`, true},
+		{`const double = func(x) { x * 2 }; [1, 2].map(double).reduce(func(a, b) { a + b })`, 6},
+		{`const add = func(a, b) { a + b }; 5.add(2)`, 7},
+		{`-3.abs()`, -3},
+		{`(-3).abs()`, 3},
+		{`[3, 1, 2].sort().first()`, 1},
+		{`struct C { n, next } const c = C{n: 1, next: func(x) { x + 1 }}; c.next(c.n)`, 2},
+		{`const h = {"len": func() { 42 }}; [h.len(), [1].len()] == [42, 1]`, true},
+		{`const f = func() { const twice = func(x) { x * 2 }; func(y) { y.twice() } }; f()(4)`, 8},
+		{fmt.Sprintf(`import "%s/geo.qk"; geo.area(3)`, dir), 9},
+		{`mut f = func(a) { 1 }; const r = func() { f = func(a) { 2 }; [0] }; r().f()`, 1},
+		{`mut m = null; m?.len() == null`, true},
+		{`mut m = [1, 2]; m?.len()`, 2},
+		{`const len = func(a) { 7 }; const f = func() { [1].len() }; f()`, 7},
+		{`5.nope()`, "Integer has no method nope on line 1"},
+		{`[1].join(5)`, "second argument to `join` must be string type. got=Integer on line 1"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.source)
+
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case bool:
+			testBooleanObject(t, evaluated, expected)
 		case string:
 			errObj, ok := evaluated.(*object.Error)
 			if !ok {
@@ -419,9 +1214,9 @@ func TestArrayIndexExpressions(t *testing.T) {
 			nil,
 		},
 		{
-			"[1, 2, 3][3]",
+			`"use strict-null"; [1, 2, 3][3]`,
 			nil,
-			"array index out of bounds on line 1",
+			"array index out of bounds: 3 on line 1",
 		},
 		{
 			"[1, 2, 3][-1]",
@@ -439,9 +1234,9 @@ func TestArrayIndexExpressions(t *testing.T) {
 			nil,
 		},
 		{
-			"[1, 2, 3][-4]",
+			`"use strict-null"; [1, 2, 3][-4]`,
 			nil,
-			"array index out of bounds on line 1",
+			"array index out of bounds: -4 on line 1",
 		},
 	}
 
@@ -459,6 +1254,54 @@ func TestArrayIndexExpressions(t *testing.T) {
 	}
 }
 
+func TestSliceExpressions(t *testing.T) {
+	tests := []struct {
+		source   string
+		expected interface{}
+	}{
+		{"[1, 2, 3, 4][1:3] == [2, 3]", true},
+		{"[1, 2, 3, 4][:2] == [1, 2]", true},
+		{"[1, 2, 3, 4][-2:] == [3, 4]", true},
+		{"[1, 2, 3, 4][:-1] == [1, 2, 3]", true},
+		{"[1, 2, 3, 4][-10:10] == [1, 2, 3, 4]", true},
+		{"[1, 2, 3, 4][3:1] == []", true},
+		{"tuple(1, 2, 3)[1:] == tuple(2, 3)", true},
+		{`"quonk"[1:3] == "uo"`, true},
+		{`"quonk"[-2:] == "nk"`, true},
+		{`"quonk"[0] == "q"`, true},
+		{`"quonk"[-1] == "k"`, true},
+		{`"quonk"[5] == null`, true},
+		{`"é"[0] == "é"`, true},
+		{`"héllo"[1:3] == "él"`, true},
+		{`"日本語"[-1] == "語"`, true},
+		{`const s = "日本語"; s[len(s) - 1] == "語"`, true},
+		{"[1, 2][2] == null", true},
+		{"slice([1, 2, 3, 4], -3, -1) == [2, 3]", true},
+		{"null?[1:] == null", true},
+		{`"use strict-null"; "ab"[-3]`, "string index out of bounds: -3 on line 1"},
+		{`[1]["a":]`, "slice bound must be int type. got=String on line 1"},
+		{`1[1:]`, "slice operator not supported: Integer on line 1"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.source)
+
+		switch expected := tt.expected.(type) {
+		case bool:
+			testBooleanObject(t, evaluated, expected)
+		case string:
+			errObj, ok := evaluated.(*object.Error)
+			if !ok {
+				t.Errorf("object is not Error. got=%T (%+v)", evaluated, evaluated)
+				continue
+			}
+			if errObj.Message != expected {
+				t.Errorf("wrong error message. expected=%q, got=%q", expected, errObj.Message)
+			}
+		}
+	}
+}
+
 func TestHashLiterals(t *testing.T) {
 	source := `mut two = "two";
 	{
@@ -544,6 +1387,64 @@ func TestHashIndexExpressions(t *testing.T) {
 	}
 }
 
+func TestStructuralEquality(t *testing.T) {
+	tests := []struct {
+		source   string
+		expected interface{}
+	}{
+		{"[1, 2] == [1, 2]", true},
+		{"[1, 2] != [1, 2]", false},
+		{"[1, [2, 3]] == [1, [2, 3]]", true},
+		{"[1, 2] == [2, 1]", false},
+		{`{"a": [1]} == {"a": [1]}`, true},
+		{`{"a": 1} == {"a": 2}`, false},
+		{"null == null", true},
+		{`"a" == "a"`, true},
+		{`"a" != "a"`, false},
+		{"tuple(1, 2) == freeze([1, 2])", true},
+		{"{1.5: 1}[1.5]", 1},
+		{"{tuple(1, 2): 3}[tuple(1, 2)]", 3},
+		{"tuple(1, 2)[-1]", 2},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.source)
+		switch expected := tt.expected.(type) {
+		case bool:
+			testBooleanObject(t, evaluated, expected)
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		}
+	}
+}
+
+func TestNullSafetyOperators(t *testing.T) {
+	tests := []struct {
+		source   string
+		expected interface{}
+	}{
+		{"null ?? 1", 1},
+		{"2 ?? 1", 2},
+		{`{"a": 1}["b"] ?? 3`, 3},
+		{"null?[0]", nil},
+		{"[1, 2]?[1]", 2},
+		{`const h = {"a": {"b": 5}}; h?["a"]?["b"]`, 5},
+		{`const h = {"a": {"b": 5}}; h?["x"]?["b"]`, nil},
+		{`const h = {"a": {"b": 5}}; h?.x?.b ?? 6`, 6},
+		{"mut calls = 0; const f = func() { calls = calls + 1; 1 }; 1 ?? f(); calls", 0},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.source)
+		integer, ok := tt.expected.(int)
+		if ok {
+			testIntegerObject(t, evaluated, int64(integer))
+		} else {
+			testNullObject(t, evaluated)
+		}
+	}
+}
+
 func TestFloatExpressions(t *testing.T) {
 	tests := []struct {
 		source   string
@@ -0,0 +1,129 @@
+package evaluator
+
+import (
+	"quonk/ast"
+	"quonk/object"
+)
+
+// evalMatchExpr tries the arms in order. The bindings of an arm's pattern are scoped to its guard and body
+func evalMatchExpr(node *ast.MatchExpr, s *object.Scope) object.Object {
+	subject := Eval(node.Subject, s)
+	if isError(subject) {
+		return subject
+	}
+
+	for _, arm := range node.Arms {
+		armScope := object.NewEnclosedScope(s)
+
+		matched, errObj := matchPattern(arm.Pattern, subject, armScope)
+		if errObj != nil {
+			return errObj
+		}
+		if !matched {
+			continue
+		}
+
+		if arm.Guard != nil {
+			guard := Eval(arm.Guard, armScope)
+			if isError(guard) {
+				return guard
+			}
+			if !isTruthy(guard) {
+				continue
+			}
+		}
+
+		// an arm whose block ends in a statement has no value
+		result := evalScopedBlock(arm.Body, armScope)
+		if result == nil {
+			return NULL
+		}
+		return result
+	}
+
+	return NULL
+}
+
+// matchPattern reports whether value matches pattern, declaring the names the pattern binds in s as it goes
+func matchPattern(pattern ast.Pattern, value object.Object, s *object.Scope) (bool, object.Object) {
+	switch pattern := pattern.(type) {
+	case *ast.WildcardPattern:
+		return true, nil
+	case *ast.BindingPattern:
+		s.DeclareVar(pattern.Name.Value, value, false, pattern.Token.Line)
+		return true, nil
+	case *ast.LiteralPattern:
+		return matchEqual(pattern.Value, value, s)
+	case *ast.ValuePattern:
+		return matchEqual(pattern.Value, value, s)
+	case *ast.ArrayPattern:
+		var elements []object.Object
+		switch value := value.(type) {
+		case *object.Array:
+			elements = value.Elements
+		case *object.Tuple:
+			elements = value.Elements
+		default:
+			return false, nil
+		}
+		return matchPatterns(pattern.Elements, elements, s)
+	case *ast.HashPattern:
+		hash, ok := value.(*object.Hash)
+		if !ok {
+			return false, nil
+		}
+
+		values := make([]object.Object, len(pattern.Keys))
+		for i, key := range pattern.Keys {
+			pair, ok := hash.Pairs[Eval(key, s).(object.Hashable).HashKey()]
+			if !ok {
+				return false, nil
+			}
+			values[i] = pair.Value
+		}
+		return matchPatterns(pattern.Values, values, s)
+	case *ast.VariantPattern:
+		variant := Eval(pattern.Variant, s)
+		if isError(variant) {
+			return false, variant
+		}
+
+		v, ok := value.(*object.Variant)
+		if !ok {
+			return false, nil
+		}
+		matched, err := v.Matches(variant, len(pattern.Fields))
+		if err != nil {
+			return false, newError(pattern.Token.Line, "%s", err)
+		}
+		if !matched {
+			return false, nil
+		}
+		return matchPatterns(pattern.Fields, v.Values, s)
+	}
+
+	return false, nil
+}
+
+// matchPatterns matches each of values with the pattern in its place, there must be as many of both
+func matchPatterns(patterns []ast.Pattern, values []object.Object, s *object.Scope) (bool, object.Object) {
+	if len(patterns) != len(values) {
+		return false, nil
+	}
+
+	for i, pattern := range patterns {
+		matched, errObj := matchPattern(pattern, values[i], s)
+		if errObj != nil || !matched {
+			return false, errObj
+		}
+	}
+	return true, nil
+}
+
+func matchEqual(expected ast.Expr, value object.Object, s *object.Scope) (bool, object.Object) {
+	want := Eval(expected, s)
+	if isError(want) {
+		return false, want
+	}
+	return object.Equal(want, value), nil
+}
@@ -0,0 +1,85 @@
+package evaluator
+
+import (
+	"quonk/ast"
+	"quonk/module"
+	"quonk/object"
+)
+
+// evalImportStmt runs the imported file in a scope of its own the first time the program imports it, later imports
+// reuse the module it made
+func evalImportStmt(node *ast.ImportStmt, s *object.Scope) object.Object {
+	line := node.Token.Line
+	if !s.IsTopLevel() {
+		return newError(line, "import must be at the top level of a file")
+	}
+
+	mod, err := loadModule(node.Path, s, line)
+	if err != nil {
+		return err
+	}
+
+	if node.Names == nil {
+		if err := declareImport(node.Alias.Value, mod, s, line); err != nil {
+			return err
+		}
+		return nil
+	}
+	for _, name := range node.Names {
+		value, ok := mod.Exports[name.Value]
+		if !ok {
+			return newError(line, "module %s has no export %s", mod.Name, name.Value)
+		}
+		if err := declareImport(name.Value, value, s, line); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func loadModule(path string, s *object.Scope, line int) (*object.Module, *object.Error) {
+	loader := s.Loader()
+	resolved, err := loader.Resolve(path)
+	if err != nil {
+		return nil, newError(line, "cannot import %s: %s", path, err)
+	}
+	if mod, ok := s.Module(resolved); ok {
+		return mod, nil
+	}
+
+	program, err := loader.Load(resolved)
+	if err != nil {
+		return nil, newError(line, "%s", err)
+	}
+	defer loader.Done()
+
+	scope := object.NewModuleScope(s)
+	if result := Eval(program, scope); isError(result) {
+		return nil, result.(*object.Error)
+	}
+
+	mod := &object.Module{Name: module.Name(resolved), Exports: scope.Exports()}
+	s.AddModule(resolved, mod)
+	return mod, nil
+}
+
+// imported names are constants, like the variables of a module cannot be assigned to from outside it
+func declareImport(name string, value object.Object, s *object.Scope, line int) *object.Error {
+	if _, fromOuter, ok := s.Get(name); ok && !fromOuter {
+		return newError(line, "cannot redeclare block scoped variable %s", name)
+	}
+	s.Set(name, value, true)
+	return nil
+}
+
+func evalExportStmt(node *ast.ExportStmt, s *object.Scope) object.Object {
+	if !s.IsTopLevel() {
+		return newError(node.Token.Line, "export must be at the top level of a file")
+	}
+
+	if result := Eval(node.Declaration, s); isError(result) {
+		return result
+	}
+	s.Export(node.Declaration.Name.Value)
+	return nil
+}
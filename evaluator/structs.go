@@ -0,0 +1,71 @@
+package evaluator
+
+import (
+	"quonk/ast"
+	"quonk/object"
+)
+
+func evalEnumDefinition(node *ast.EnumDefinition) object.Object {
+	variants := make([]string, len(node.Variants))
+	fields := make([][]string, len(node.Variants))
+	for i, variant := range node.Variants {
+		variants[i] = variant.Name.Value
+		for _, field := range variant.Fields {
+			fields[i] = append(fields[i], field.Value)
+		}
+	}
+	return object.NewEnumType(node.Name, variants, fields)
+}
+
+// evalStructLiteral evaluates the values of a struct literal in the order they are written
+func evalStructLiteral(node *ast.StructLiteral, s *object.Scope) object.Object {
+	line := node.Token.Line
+
+	structType := Eval(node.Type, s)
+	if isError(structType) {
+		return structType
+	}
+	t, ok := structType.(*object.StructType)
+	if !ok {
+		return newError(line, "not a struct type: %s", structType.Type())
+	}
+
+	names := make([]string, len(node.Fields))
+	values := make([]object.Object, len(node.Values))
+	for i, field := range node.Fields {
+		names[i] = field.Value
+		values[i] = Eval(node.Values[i], s)
+		if isError(values[i]) {
+			return values[i]
+		}
+	}
+
+	built, err := t.New(names, values)
+	if err != nil {
+		return newError(line, "%s", err)
+	}
+	return built
+}
+
+func evalFieldAssignmentStmt(node *ast.FieldAssignmentStmt, s *object.Scope) object.Object {
+	line := node.Token.Line
+
+	obj := Eval(node.Object, s)
+	if isError(obj) {
+		return obj
+	}
+	target, ok := obj.(*object.Struct)
+	if !ok {
+		return newError(line, "field assignment not supported: %s", obj.Type())
+	}
+
+	val := Eval(node.Value, s)
+	if isError(val) {
+		return val
+	}
+
+	if err := target.SetField(node.Field.Value, val); err != nil {
+		return newError(line, "%s", err)
+	}
+	return nil
+}
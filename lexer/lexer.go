@@ -32,6 +32,7 @@ const (
 	bang        = '!'
 	ampersand   = '&'
 	pipe        = '|'
+	question    = '?'
 )
 
 type Lexer struct {
@@ -67,7 +68,8 @@ func (l *Lexer) readChar() {
 func (l *Lexer) readIdentifer() string {
 	position := l.position
 
-	for utils.IsAlpha(string(l.char)) {
+	// identifiers start with a letter, after that digits are allowed too, as in atan2
+	for utils.IsAlpha(string(l.char)) || utils.IsNumeric(string(l.char)) {
 		l.readChar() // Advances the position pointer
 	}
 	return l.source[position:l.position]
@@ -76,7 +78,8 @@ func (l *Lexer) readIdentifer() string {
 func (l *Lexer) readNumber() (string, bool) {
 	position := l.position
 	encounteredDecimal := false
-	for utils.IsNumeric(string(l.char)) || (!encounteredDecimal && l.char == dot) {
+	// a dot that no digit follows is not a decimal point, 5.abs() calls abs on 5
+	for utils.IsNumeric(string(l.char)) || (!encounteredDecimal && l.char == dot && utils.IsNumeric(string(l.peekChar()))) {
 		if l.char == dot {
 			encounteredDecimal = true
 		}
@@ -149,6 +152,9 @@ func (l *Lexer) NextToken() token.Token {
 			l.readChar() // advance past first equals
 			literal := string(char) + string(l.char)
 			tok = token.Token{Type: token.EqualTo, Literal: literal, Line: l.line}
+		} else if l.peekChar() == greaterThan {
+			l.readChar() // advance past equals
+			tok = token.Token{Type: token.FatArrow, Literal: "=>", Line: l.line}
 		} else {
 			tok = token.MakeToken(token.Assign, l.char, l.line)
 		}
@@ -209,6 +215,26 @@ func (l *Lexer) NextToken() token.Token {
 			// Single & is an illegal char
 			tok = token.MakeToken(token.Illegal, l.char, l.line)
 		}
+	case question:
+		var tokType token.TokenType
+		switch l.peekChar() {
+		case question:
+			tokType = token.NullCoalesce
+		case leftSquareBracket:
+			tokType = token.OptionalIndex
+		case dot:
+			tokType = token.OptionalDot
+		}
+
+		if tokType != "" {
+			char := l.char
+			l.readChar()
+			literal := string(char) + string(l.char)
+			tok = token.Token{Type: tokType, Literal: literal, Line: l.line}
+		} else {
+			// Single ? is an illegal char
+			tok = token.MakeToken(token.Illegal, l.char, l.line)
+		}
 	case 0:
 		tok.Literal = ""
 		tok.Type = "EOF"
@@ -240,18 +266,27 @@ func (l *Lexer) NextToken() token.Token {
 }
 
 var keywords = map[string]token.TokenType{
-	"mut":    token.Mut,
-	"const":  token.Const,
-	"null":   token.Null,
-	"true":   token.True,
-	"false":  token.False,
-	"if":     token.If,
-	"else":   token.Else,
-	"elseif": token.Elseif,
-	"func":   token.Func,
-	"return": token.Return,
-	"for":    token.For,
-	"macro":  token.Macro,
+	"mut":     token.Mut,
+	"const":   token.Const,
+	"null":    token.Null,
+	"true":    token.True,
+	"false":   token.False,
+	"if":      token.If,
+	"else":    token.Else,
+	"elseif":  token.Elseif,
+	"func":    token.Func,
+	"return":  token.Return,
+	"for":     token.For,
+	"macro":   token.Macro,
+	"try":     token.Try,
+	"catch":   token.Catch,
+	"finally": token.Finally,
+	"throw":   token.Throw,
+	"import":  token.Import,
+	"export":  token.Export,
+	"struct":  token.Struct,
+	"enum":    token.Enum,
+	"match":   token.Match,
 }
 
 func LookupIdent(ident string) token.TokenType {
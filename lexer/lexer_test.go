@@ -32,6 +32,10 @@ func TestNextToken(t *testing.T) {
 	{"foo": "bar" }
 	5.2;
 	macro(x, y) { x + y; };
+	a ?? b?["c"]?.d;
+	atan2(x1, 2);
+	5.abs();
+	match (x) { 1 => y }
 	`
 
 	tests := []struct {
@@ -193,6 +197,41 @@ func TestNextToken(t *testing.T) {
 		{token.RightCurlyBracket, "}", 26},
 		{token.Semicolon, ";", 26},
 
+		{token.Identifier, "a", 27},
+		{token.NullCoalesce, "??", 27},
+		{token.Identifier, "b", 27},
+		{token.OptionalIndex, "?[", 27},
+		{token.String, "c", 27},
+		{token.RightSquareBracket, "]", 27},
+		{token.OptionalDot, "?.", 27},
+		{token.Identifier, "d", 27},
+		{token.Semicolon, ";", 27},
+
+		{token.Identifier, "atan2", 28},
+		{token.LeftParen, "(", 28},
+		{token.Identifier, "x1", 28},
+		{token.Comma, ",", 28},
+		{token.Integer, "2", 28},
+		{token.RightParen, ")", 28},
+		{token.Semicolon, ";", 28},
+
+		{token.Integer, "5", 29},
+		{token.Dot, ".", 29},
+		{token.Identifier, "abs", 29},
+		{token.LeftParen, "(", 29},
+		{token.RightParen, ")", 29},
+		{token.Semicolon, ";", 29},
+
+		{token.Match, "match", 30},
+		{token.LeftParen, "(", 30},
+		{token.Identifier, "x", 30},
+		{token.RightParen, ")", 30},
+		{token.LeftCurlyBracket, "{", 30},
+		{token.Integer, "1", 30},
+		{token.FatArrow, "=>", 30},
+		{token.Identifier, "y", 30},
+		{token.RightCurlyBracket, "}", 30},
+
 		{token.EOF, "", 0},
 	}
 
@@ -2,6 +2,7 @@ package main
 
 import (
 	"bytes"
+	"flag"
 	"fmt"
 	"io"
 	"os"
@@ -11,6 +12,7 @@ import (
 	"quonk/parser"
 	"quonk/repl"
 	"quonk/vm"
+	"strings"
 )
 
 // TODO : unfuck this
@@ -23,7 +25,21 @@ func main() {
 		repl.StartVM(os.Stdin, os.Stdout)
 	} else {
 		if args[1] == "run" {
-			Run(args[2])
+			runCmd := flag.NewFlagSet("run", flag.ExitOnError)
+			strictNull := runCmd.Bool("strict-null", false, "reject null and give uninitialized variables zero values")
+			var allowRead, allowWrite pathList
+			runCmd.Var(&allowRead, "allow-read", "comma separated directories the script may read")
+			runCmd.Var(&allowWrite, "allow-write", "comma separated directories the script may write")
+			seed := runCmd.Int64("seed", 0, "seed the random builtins so runs can be reproduced")
+			runCmd.Parse(args[2:])
+
+			opts := RunOptions{StrictNull: *strictNull, AllowRead: allowRead, AllowWrite: allowWrite}
+			runCmd.Visit(func(f *flag.Flag) {
+				if f.Name == "seed" {
+					opts.Seed = seed
+				}
+			})
+			Run(runCmd.Arg(0), opts)
 		} else if args[1] == "compile" {
 			// TODO: implement writing intermediate bytecode file
 			Compile(args[2])
@@ -32,19 +48,61 @@ func main() {
 			// TODO: implement reading intermediate bytecode file
 			fmt.Println("Honk! Exec not implemented yet")
 		} else if args[1] == "help" {
-			fmt.Println("Usage: quonk [run|compile|exec|help] [filename]")
+			fmt.Println("Usage: quonk [run|compile|exec|help] [options] [filename]")
+			fmt.Println("Options for run:")
+			fmt.Println("  --strict-null\treject null and give uninitialized variables zero values")
+			fmt.Println("  --allow-read=dir[,dir]\tlet the script read files below the directories")
+			fmt.Println("  --allow-write=dir[,dir]\tlet the script write files below the directories")
+			fmt.Println("  --seed=n\tseed the random builtins so runs can be reproduced")
 		}
 	}
 
 }
 
-func Run(filename string) {
+type RunOptions struct {
+	StrictNull bool
+	AllowRead  []string
+	AllowWrite []string
+	Seed       *int64 // nil seeds the random builtins from the time
+}
+
+// pathList is a flag that can be repeated and takes comma separated paths
+type pathList []string
+
+func (p *pathList) String() string {
+	return strings.Join(*p, ",")
+}
+
+func (p *pathList) Set(value string) error {
+	for _, path := range strings.Split(value, ",") {
+		if path != "" {
+			*p = append(*p, path)
+		}
+	}
+	return nil
+}
+
+func Run(filename string, opts RunOptions) {
 
 	constants := []object.Object{}
 	globals := make([]object.Object, vm.GlobalsSize)
 	symbolTable := compiler.NewSymbolTable()
-	for i, v := range object.Builtins {
-		symbolTable.DefineBuiltin(i, v.Name)
+	for i, name := range object.BuiltinNames() {
+		symbolTable.DefineBuiltin(i, name)
+	}
+
+	capabilities := object.NewCapabilities(os.Stdin)
+	for _, dir := range opts.AllowRead {
+		if err := capabilities.AllowRead(dir); err != nil {
+			fmt.Printf("Honk! Cannot allow reading %s: %s\n", dir, err)
+			return
+		}
+	}
+	for _, dir := range opts.AllowWrite {
+		if err := capabilities.AllowWrite(dir); err != nil {
+			fmt.Printf("Honk! Cannot allow writing %s: %s\n", dir, err)
+			return
+		}
 	}
 
 	file, err := os.ReadFile(filename)
@@ -57,20 +115,31 @@ func Run(filename string) {
 
 	l := lexer.New(src)
 	p := parser.New(l)
+	if opts.StrictNull {
+		p.EnableStrictNull()
+	}
 	program := p.ParseProgram()
 	if len(p.Errors()) != 0 {
 		printParserErrors(os.Stdout, p.Errors())
 		return
 	}
 
-	comp := compiler.NewWithState(symbolTable, constants)
+	comp := compiler.NewWithState(symbolTable, constants, compiler.NewModules())
+	comp.SetFile(filename)
 	err = comp.Compile(program)
 	if err != nil {
 		fmt.Printf("Compiler error: %s\n", err)
 		return
 	}
+	for _, warning := range comp.Warnings() {
+		fmt.Printf("Warning: %s\n", warning)
+	}
 
 	machine := vm.NewWithGlobalStore(comp.Bytecode(), globals)
+	machine.SetIO(capabilities)
+	if opts.Seed != nil {
+		machine.Seed(*opts.Seed)
+	}
 	err = machine.Run()
 	if err != nil {
 		fmt.Printf("Runtime error: %s\n", err)
@@ -99,11 +168,15 @@ func Compile(filename string) {
 	}
 
 	comp := compiler.New()
+	comp.SetFile(filename)
 	err = comp.Compile(program)
 	if err != nil {
 		fmt.Printf("Honk! Compiler error: %s\n", err)
 		return
 	}
+	for _, warning := range comp.Warnings() {
+		fmt.Printf("Honk! Warning: %s\n", warning)
+	}
 
 	bytecode := comp.Bytecode()
 	var out bytes.Buffer
@@ -0,0 +1,118 @@
+package module
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"quonk/ast"
+	"quonk/lexer"
+	"quonk/parser"
+	"quonk/std"
+	"strings"
+)
+
+// Loader finds and parses the files a program imports and catches import cycles. The engines cache the modules
+// they have loaded themselves, by the path Resolve returns
+type Loader struct {
+	// the files being loaded, each one imported by the one before it. The first is the program, if it is a file
+	loading []string
+}
+
+// NewLoader makes the loader of a program, file is its path or "" when it does not come from a file, like in the REPL
+func NewLoader(file string) *Loader {
+	l := &Loader{}
+	if file != "" {
+		if abs, err := filepath.Abs(file); err == nil {
+			l.loading = append(l.loading, abs)
+		}
+	}
+	return l
+}
+
+// Resolve makes an imported path absolute. A relative path is relative to the directory of the importing file, and
+// a path starting with std: is a module of the standard library, which keeps the path std.Path gives it
+func (l *Loader) Resolve(path string) (string, error) {
+	if stdPath, ok := std.Path(path); ok {
+		return stdPath, nil
+	}
+
+	if !filepath.IsAbs(path) && len(l.loading) > 0 {
+		path = filepath.Join(filepath.Dir(l.loading[len(l.loading)-1]), path)
+	}
+
+	return filepath.Abs(path)
+}
+
+// Load parses the file at a resolved path, which counts as being loaded until Done is called. Importing a file
+// that is still being loaded is an import cycle
+func (l *Loader) Load(path string) (*ast.Program, error) {
+	for i, loading := range l.loading {
+		if loading == path {
+			return nil, cycleError(append(l.loading[i:], path))
+		}
+	}
+
+	program, err := parse(path)
+	if err != nil {
+		var pathErr *fs.PathError
+		if errors.As(err, &pathErr) {
+			err = pathErr.Err
+		}
+		if pathErr == nil && !errors.Is(err, fs.ErrNotExist) {
+			return nil, syntaxError(path, err.Error())
+		}
+		return nil, fmt.Errorf("cannot import %s: %s", filepath.Base(path), err)
+	}
+
+	l.loading = append(l.loading, path)
+	return program, nil
+}
+
+// parse parses the file at path. The std modules were parsed at startup
+func parse(path string) (*ast.Program, error) {
+	if std.IsModule(path) {
+		return std.Program(path)
+	}
+
+	source, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	p := parser.New(lexer.New(string(source)))
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		return nil, errors.New(p.Errors()[0])
+	}
+	return program, nil
+}
+
+// syntaxError is the first error of the parser in a file, a.qk line 2: ... The engines add the line of the import
+func syntaxError(path string, msg string) error {
+	msg = strings.TrimPrefix(msg, "Honk! ")
+	if i := strings.LastIndex(msg, " on line "); i >= 0 {
+		return fmt.Errorf("%s line %s: %s", filepath.Base(path), msg[i+len(" on line "):], msg[:i])
+	}
+	return fmt.Errorf("%s: %s", filepath.Base(path), msg)
+}
+
+// Done marks the file Load returned last as loaded
+func (l *Loader) Done() {
+	l.loading = l.loading[:len(l.loading)-1]
+}
+
+// Name is what a module is called in messages, its file name without the extension
+func Name(path string) string {
+	return strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+}
+
+func cycleError(files []string) error {
+	names := make([]string, len(files))
+	for i, file := range files {
+		names[i] = filepath.Base(file)
+	}
+
+	return fmt.Errorf("import cycle: %s", strings.Join(names, " -> "))
+}
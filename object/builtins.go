@@ -3,7 +3,11 @@ package object
 import (
 	"bytes"
 	"fmt"
+	"math"
+	"os"
 	"strings"
+	"time"
+	"unicode/utf8"
 )
 
 var Builtins = []struct {
@@ -13,16 +17,21 @@ var Builtins = []struct {
 	{
 		"len",
 		&BuiltIn{
-			Fn: func(args ...Object) Object {
+			Fn: func(_ Caller, args ...Object) Object {
 				if len(args) != 1 {
 					return newError("`len` expects one argument")
 				}
 
 				switch arg := args[0].(type) {
 				case *String:
-					return &Integer{Value: int64(len(arg.Value))}
+					// characters rather than bytes, like string indexes and slices count
+					return &Integer{Value: int64(utf8.RuneCountInString(arg.Value))}
 				case *Array:
 					return &Integer{Value: int64(len(arg.Elements))}
+				case *Tuple:
+					return &Integer{Value: int64(len(arg.Elements))}
+				case *Hash:
+					return &Integer{Value: int64(len(arg.Pairs))}
 				default:
 					return newError("argument to `len` of wrong type. got=%s", args[0].Type())
 				}
@@ -32,7 +41,7 @@ var Builtins = []struct {
 	{
 		"print",
 		&BuiltIn{
-			Fn: func(args ...Object) Object {
+			Fn: func(_ Caller, args ...Object) Object {
 				var out bytes.Buffer
 
 				elems := make([]string, 0)
@@ -49,7 +58,7 @@ var Builtins = []struct {
 	{
 		"first",
 		&BuiltIn{
-			Fn: func(args ...Object) Object {
+			Fn: func(_ Caller, args ...Object) Object {
 				if len(args) != 1 {
 					return newError("`first` expects a single argument")
 				}
@@ -68,7 +77,7 @@ var Builtins = []struct {
 	{
 		"last",
 		&BuiltIn{
-			Fn: func(args ...Object) Object {
+			Fn: func(_ Caller, args ...Object) Object {
 				if len(args) != 1 {
 					return newError("`last` expects a single argument.")
 				}
@@ -88,7 +97,7 @@ var Builtins = []struct {
 	{
 		"rest",
 		&BuiltIn{
-			Fn: func(args ...Object) Object {
+			Fn: func(_ Caller, args ...Object) Object {
 				if len(args) != 1 {
 					return newError("`rest` expects one argument")
 				}
@@ -109,7 +118,7 @@ var Builtins = []struct {
 	{
 		"append",
 		&BuiltIn{
-			Fn: func(args ...Object) Object {
+			Fn: func(_ Caller, args ...Object) Object {
 				if len(args) != 2 {
 					return newError("`append` expects two arguments")
 				}
@@ -132,13 +141,13 @@ var Builtins = []struct {
 	{
 		"slice",
 		&BuiltIn{
-			Fn: func(args ...Object) Object {
+			Fn: func(_ Caller, args ...Object) Object {
 				if len(args) != 3 {
 					return newError("`slice` expects three arguments")
 				}
 
-				if args[0].Type() != ArrayObj {
-					return newError("first argument to `slice` must be array type")
+				if args[0].Type() != ArrayObj && args[0].Type() != TupleObj && args[0].Type() != StringObj {
+					return newError("first argument to `slice` must be array, tuple or string type")
 				}
 				if args[1].Type() != IntegerObj {
 					return newError("`start` argument to `slice` must be int")
@@ -148,30 +157,20 @@ var Builtins = []struct {
 					return newError("`end` argument to `slice` must be int")
 				}
 
-				arr := args[0].(*Array)
-				start := args[1].(*Integer).Value
-				end := args[2].(*Integer).Value
-				arrLength := int64(len(arr.Elements) - 1)
-
-				if start < 0 {
-					start = 0
-				}
-				if end > arrLength {
-					end = arrLength - 1
+				// slice(a, start, end) is a[start:end]
+				sliced, err := Slice(args[0], args[1], args[2])
+				if err != nil {
+					return newError("%s", err)
 				}
-				slicedLength := int64(end - start)
 
-				newElems := make([]Object, slicedLength)
-				copy(newElems, arr.Elements[start:end])
-
-				return &Array{Elements: newElems}
+				return sliced
 			},
 		},
 	},
 	{
 		"keys",
 		&BuiltIn{
-			Fn: func(args ...Object) Object {
+			Fn: func(_ Caller, args ...Object) Object {
 				if len(args) != 1 {
 					return newError("`keys` expects one argument")
 				}
@@ -181,16 +180,10 @@ var Builtins = []struct {
 					return newError("unknown argument type for `keys`: %T", args[0])
 				}
 
-				keys := make([]Object, 0)
-				for key := range hash.Pairs {
-					switch val := key.ObjectValue.(type) {
-					case bool:
-						keys = append(keys, &Boolean{Value: val})
-					case string:
-						keys = append(keys, &String{Value: val})
-					case int64:
-						keys = append(keys, &Integer{Value: val})
-					}
+				// the pairs hold the key objects themselves, so floats and tuples come back as they went in
+				keys := make([]Object, 0, len(hash.Pairs))
+				for _, pair := range hash.Pairs {
+					keys = append(keys, pair.Key)
 				}
 
 				return &Array{Elements: keys}
@@ -200,7 +193,7 @@ var Builtins = []struct {
 	{
 		"values",
 		&BuiltIn{
-			Fn: func(args ...Object) Object {
+			Fn: func(_ Caller, args ...Object) Object {
 				if len(args) != 1 {
 					return newError("`values` expects one argument")
 				}
@@ -219,6 +212,311 @@ var Builtins = []struct {
 			},
 		},
 	},
+	{
+		"tuple",
+		&BuiltIn{
+			Fn: func(_ Caller, args ...Object) Object {
+				tuple, err := NewTuple(args)
+				if err != nil {
+					return newError("%s", err)
+				}
+
+				return tuple
+			},
+		},
+	},
+	{
+		"freeze",
+		&BuiltIn{
+			Fn: func(_ Caller, args ...Object) Object {
+				if len(args) != 1 {
+					return newError("`freeze` expects one argument")
+				}
+
+				arr, ok := args[0].(*Array)
+				if !ok {
+					return newError("argument to `freeze` must be array type")
+				}
+
+				tuple, err := NewTuple(arr.Elements)
+				if err != nil {
+					return newError("%s", err)
+				}
+
+				return tuple
+			},
+		},
+	},
+	{
+		"error",
+		&BuiltIn{
+			Fn: func(_ Caller, args ...Object) Object {
+				if len(args) != 1 {
+					return newError("`error` expects one argument")
+				}
+
+				msg, ok := args[0].(*String)
+				if !ok {
+					return newError("argument to `error` must be string type")
+				}
+
+				return &Exception{Message: msg.Value}
+			},
+		},
+	},
+	{
+		"map",
+		&BuiltIn{
+			Fn: func(caller Caller, args ...Object) Object {
+				arr, fn, err := arrayAndCallback("map", args)
+				if err != nil {
+					return err
+				}
+
+				mapped := make([]Object, len(arr.Elements))
+				for i, el := range arr.Elements {
+					result := caller.Call(fn, el)
+					if isError(result) {
+						return result
+					}
+					mapped[i] = result
+				}
+
+				return &Array{Elements: mapped}
+			},
+		},
+	},
+	{
+		"filter",
+		&BuiltIn{
+			Fn: func(caller Caller, args ...Object) Object {
+				arr, fn, err := arrayAndCallback("filter", args)
+				if err != nil {
+					return err
+				}
+
+				filtered := make([]Object, 0)
+				for _, el := range arr.Elements {
+					result := caller.Call(fn, el)
+					if isError(result) {
+						return result
+					}
+					if isTruthy(result) {
+						filtered = append(filtered, el)
+					}
+				}
+
+				return &Array{Elements: filtered}
+			},
+		},
+	},
+	{
+		"reduce",
+		&BuiltIn{
+			Fn: func(caller Caller, args ...Object) Object {
+				if len(args) != 2 && len(args) != 3 {
+					return newError("`reduce` expects two or three arguments")
+				}
+
+				arr, fn, err := arrayAndCallback("reduce", args[:2])
+				if err != nil {
+					return err
+				}
+
+				elements := arr.Elements
+				var acc Object
+				if len(args) == 3 {
+					acc = args[2]
+				} else if len(elements) > 0 {
+					// without an initial value the first element is the initial value
+					acc, elements = elements[0], elements[1:]
+				} else {
+					return newError("`reduce` of empty array with no initial value")
+				}
+
+				for _, el := range elements {
+					acc = caller.Call(fn, acc, el)
+					if isError(acc) {
+						return acc
+					}
+				}
+
+				return acc
+			},
+		},
+	},
+	{
+		"each",
+		&BuiltIn{
+			Fn: func(caller Caller, args ...Object) Object {
+				arr, fn, err := arrayAndCallback("each", args)
+				if err != nil {
+					return err
+				}
+
+				for _, el := range arr.Elements {
+					result := caller.Call(fn, el)
+					if isError(result) {
+						return result
+					}
+				}
+
+				return NULL
+			},
+		},
+	},
+	{
+		"find",
+		&BuiltIn{
+			Fn: func(caller Caller, args ...Object) Object {
+				arr, fn, err := arrayAndCallback("find", args)
+				if err != nil {
+					return err
+				}
+
+				for _, el := range arr.Elements {
+					result := caller.Call(fn, el)
+					if isError(result) {
+						return result
+					}
+					if isTruthy(result) {
+						return el
+					}
+				}
+
+				return NULL
+			},
+		},
+	},
+	{
+		"any",
+		&BuiltIn{
+			Fn: func(caller Caller, args ...Object) Object {
+				arr, fn, err := arrayAndCallback("any", args)
+				if err != nil {
+					return err
+				}
+
+				for _, el := range arr.Elements {
+					result := caller.Call(fn, el)
+					if isError(result) {
+						return result
+					}
+					if isTruthy(result) {
+						return TRUE
+					}
+				}
+
+				return FALSE
+			},
+		},
+	},
+	{
+		"all",
+		&BuiltIn{
+			Fn: func(caller Caller, args ...Object) Object {
+				arr, fn, err := arrayAndCallback("all", args)
+				if err != nil {
+					return err
+				}
+
+				for _, el := range arr.Elements {
+					result := caller.Call(fn, el)
+					if isError(result) {
+						return result
+					}
+					if !isTruthy(result) {
+						return FALSE
+					}
+				}
+
+				return TRUE
+			},
+		},
+	},
+	{"split", &BuiltIn{Fn: stringSplit}},
+	{"join", &BuiltIn{Fn: stringJoin}},
+	{"trim", &BuiltIn{Fn: trimBuiltin("trim", strings.TrimSpace, strings.Trim)}},
+	{"trim_left", &BuiltIn{Fn: trimBuiltin("trim_left", trimLeftSpace, strings.TrimLeft)}},
+	{"trim_right", &BuiltIn{Fn: trimBuiltin("trim_right", trimRightSpace, strings.TrimRight)}},
+	{"contains", &BuiltIn{Fn: stringPredicate("contains", strings.Contains)}},
+	{"starts_with", &BuiltIn{Fn: stringPredicate("starts_with", strings.HasPrefix)}},
+	{"ends_with", &BuiltIn{Fn: stringPredicate("ends_with", strings.HasSuffix)}},
+	{"index_of", &BuiltIn{Fn: stringIndexOf}},
+	{"replace", &BuiltIn{Fn: stringReplace}},
+	{"upper", &BuiltIn{Fn: stringMapping("upper", strings.ToUpper)}},
+	{"lower", &BuiltIn{Fn: stringMapping("lower", strings.ToLower)}},
+	{"repeat", &BuiltIn{Fn: stringRepeat}},
+	{"chars", &BuiltIn{Fn: stringChars}},
+	{"reverse", &BuiltIn{Fn: reverse}},
+	{"format", &BuiltIn{Fn: stringFormat}},
+	{"abs", &BuiltIn{Fn: mathAbs}},
+	{"floor", &BuiltIn{Fn: roundingFunction("floor", math.Floor)}},
+	{"ceil", &BuiltIn{Fn: roundingFunction("ceil", math.Ceil)}},
+	{"round", &BuiltIn{Fn: roundingFunction("round", math.Round)}},
+	{"sqrt", &BuiltIn{Fn: floatFunction("sqrt", math.Sqrt)}},
+	{"pow", &BuiltIn{Fn: mathPow}},
+	{"log", &BuiltIn{Fn: mathLog}},
+	{"exp", &BuiltIn{Fn: floatFunction("exp", math.Exp)}},
+	{"sin", &BuiltIn{Fn: floatFunction("sin", math.Sin)}},
+	{"cos", &BuiltIn{Fn: floatFunction("cos", math.Cos)}},
+	{"tan", &BuiltIn{Fn: floatFunction("tan", math.Tan)}},
+	{"asin", &BuiltIn{Fn: floatFunction("asin", math.Asin)}},
+	{"acos", &BuiltIn{Fn: floatFunction("acos", math.Acos)}},
+	{"atan", &BuiltIn{Fn: floatFunction("atan", math.Atan)}},
+	{"atan2", &BuiltIn{Fn: mathAtan2}},
+	{"min", &BuiltIn{Fn: extremum("min", func(a, b float64) bool { return a < b })}},
+	{"max", &BuiltIn{Fn: extremum("max", func(a, b float64) bool { return a > b })}},
+	{"read_file", &BuiltIn{Fn: readFile}},
+	{"write_file", &BuiltIn{Fn: writeBuiltin("write_file", os.O_TRUNC)}},
+	{"append_file", &BuiltIn{Fn: writeBuiltin("append_file", os.O_APPEND)}},
+	{"read_lines", &BuiltIn{Fn: readLines}},
+	{"exists", &BuiltIn{Fn: exists}},
+	{"list_dir", &BuiltIn{Fn: listDir}},
+	{"input", &BuiltIn{Fn: input}},
+	{"read_line", &BuiltIn{Fn: readLine}},
+	{"json_encode", &BuiltIn{Fn: jsonEncode}},
+	{"json_decode", &BuiltIn{Fn: jsonDecode}},
+	{"re_compile", &BuiltIn{Fn: regexCompile}},
+	{"re_match", &BuiltIn{Fn: regexMatch}},
+	{"re_find", &BuiltIn{Fn: regexFind}},
+	{"re_find_all", &BuiltIn{Fn: regexFindAll}},
+	{"re_replace", &BuiltIn{Fn: regexReplace}},
+	{"re_split", &BuiltIn{Fn: regexSplit}},
+	{"now", &BuiltIn{Fn: now}},
+	{"monotonic", &BuiltIn{Fn: monotonic}},
+	{"sleep", &BuiltIn{Fn: sleep}},
+	{"format_time", &BuiltIn{Fn: formatTime}},
+	{"parse_time", &BuiltIn{Fn: parseTime}},
+	{"milliseconds", &BuiltIn{Fn: durationUnit("milliseconds", time.Millisecond)}},
+	{"seconds", &BuiltIn{Fn: durationUnit("seconds", time.Second)}},
+	{"minutes", &BuiltIn{Fn: durationUnit("minutes", time.Minute)}},
+	{"hours", &BuiltIn{Fn: durationUnit("hours", time.Hour)}},
+	{"format_duration", &BuiltIn{Fn: formatDuration}},
+	{"rand_int", &BuiltIn{Fn: randInt}},
+	{"rand_float", &BuiltIn{Fn: randFloat}},
+	{"shuffle", &BuiltIn{Fn: shuffle}},
+	{"choice", &BuiltIn{Fn: choice}},
+	{"seed", &BuiltIn{Fn: seed}},
+	{"sort", &BuiltIn{Fn: sortBuiltin}},
+	{"sort_by", &BuiltIn{Fn: sortBy}},
+	{"has_key", &BuiltIn{Fn: hasKey}},
+	{"get", &BuiltIn{Fn: get}},
+	{"delete", &BuiltIn{Fn: deleteKey}},
+	{"merge", &BuiltIn{Fn: merge}},
+	{"entries", &BuiltIn{Fn: entries}},
+	{"from_entries", &BuiltIn{Fn: fromEntries}},
+}
+
+// BuiltinConstants are predeclared values. OpGetBuiltIn indexes them after Builtins
+var BuiltinConstants = []struct {
+	Name  string
+	Value Object
+}{
+	{"PI", &Float{Value: math.Pi}},
+	{"E", &Float{Value: math.E}},
+	{"INF", &Float{Value: math.Inf(1)}},
+	{"NAN", &Float{Value: math.NaN()}},
 }
 
 func GetBuiltInByName(name string) *BuiltIn {
@@ -230,3 +528,79 @@ func GetBuiltInByName(name string) *BuiltIn {
 
 	return nil
 }
+
+func GetBuiltinConstantByName(name string) Object {
+	for _, c := range BuiltinConstants {
+		if c.Name == name {
+			return c.Value
+		}
+	}
+
+	return nil
+}
+
+// BuiltinNames are the names of Builtins followed by the names of BuiltinConstants, in OpGetBuiltIn order
+func BuiltinNames() []string {
+	names := make([]string, 0, len(Builtins)+len(BuiltinConstants))
+	for _, bi := range Builtins {
+		names = append(names, bi.Name)
+	}
+	for _, c := range BuiltinConstants {
+		names = append(names, c.Name)
+	}
+
+	return names
+}
+
+// BuiltinValue is the builtin or constant OpGetBuiltIn pushes for index
+func BuiltinValue(index int) Object {
+	if index < len(Builtins) {
+		return Builtins[index].BuiltIn
+	}
+
+	return BuiltinConstants[index-len(Builtins)].Value
+}
+
+// arrayAndCallback checks the arguments of the builtins that call a function for every element of an array
+func arrayAndCallback(name string, args []Object) (*Array, Object, *Error) {
+	if len(args) != 2 {
+		return nil, nil, newError("`%s` expects two arguments", name)
+	}
+
+	arr, ok := args[0].(*Array)
+	if !ok {
+		return nil, nil, newError("first argument to `%s` must be array type", name)
+	}
+
+	if !isCallable(args[1]) {
+		return nil, nil, newError("second argument to `%s` must be a function. got=%s", name, args[1].Type())
+	}
+
+	return arr, args[1], nil
+}
+
+func isCallable(obj Object) bool {
+	switch obj.(type) {
+	case *Function, *Closure, *BuiltIn:
+		return true
+	default:
+		return false
+	}
+}
+
+// isTruthy decides the elements callbacks select, only false and null are not truthy
+func isTruthy(obj Object) bool {
+	switch obj := obj.(type) {
+	case *Boolean:
+		return obj.Value
+	case *Null:
+		return false
+	default:
+		return true
+	}
+}
+
+func isError(obj Object) bool {
+	_, ok := obj.(*Error)
+	return ok
+}
@@ -0,0 +1,117 @@
+package object
+
+import "fmt"
+
+// NewEnumType builds the enum name with a variant for each of variants, fields holds the payload fields of each
+func NewEnumType(name string, variants []string, fields [][]string) *EnumType {
+	e := &EnumType{Name: name, Variants: make([]*VariantType, len(variants))}
+
+	for i, variantName := range variants {
+		v := &VariantType{Enum: e, Name: variantName, Fields: fields[i]}
+		if len(v.Fields) == 0 {
+			v.unit = &Variant{VariantType: v}
+		} else {
+			v.constructor = &BuiltIn{Fn: v.construct, Name: name + "." + variantName}
+		}
+		e.Variants[i] = v
+	}
+
+	return e
+}
+
+// FindVariant is the variant of e called name
+func (e *EnumType) FindVariant(name string) (*VariantType, bool) {
+	for _, v := range e.Variants {
+		if v.Name == name {
+			return v, true
+		}
+	}
+	return nil, false
+}
+
+// Variant is e.name, both engines index enums with it. A unit variant is the variant itself, one with fields is the
+// builtin that constructs it
+func (e *EnumType) Variant(index Object) (Object, error) {
+	name, ok := index.(*String)
+	if !ok {
+		return nil, fmt.Errorf("enum %s has no variant %s", e.Name, index.Inspect())
+	}
+
+	v, ok := e.FindVariant(name.Value)
+	if !ok {
+		return nil, fmt.Errorf("enum %s has no variant %s", e.Name, name.Value)
+	}
+	return v.value(), nil
+}
+
+func (v *VariantType) value() Object {
+	if v.unit != nil {
+		return v.unit
+	}
+	return v.constructor
+}
+
+// FieldIndex is the slot of the field name in the payload of v
+func (v *VariantType) FieldIndex(name string) (int, bool) {
+	for i, field := range v.Fields {
+		if field == name {
+			return i, true
+		}
+	}
+	return 0, false
+}
+
+// construct builds a variant of v from a value for each of its fields. The payload can be anything, a variant
+// holding an unhashable value is only an error when it is used as a hash key
+func (v *VariantType) construct(_ Caller, args ...Object) Object {
+	if len(args) != len(v.Fields) {
+		return newError("wrong number of arguments to %s.%s. want=%d, got=%d", v.Enum.Name, v.Name, len(v.Fields), len(args))
+	}
+
+	values := make([]Object, len(args))
+	copy(values, args)
+	return &Variant{VariantType: v, Values: values}
+}
+
+// Field is v.name, both engines index variants with it. The compiled patterns of match expressions index the payload
+// by position instead
+func (v *Variant) Field(index Object) (Object, error) {
+	variant := v.VariantType.Enum.Name + "." + v.VariantType.Name
+	if position, ok := index.(*Integer); ok && position.Value >= 0 && position.Value < int64(len(v.Values)) {
+		return v.Values[position.Value], nil
+	}
+
+	name, ok := index.(*String)
+	if !ok {
+		return nil, fmt.Errorf("variant %s has no field %s", variant, index.Inspect())
+	}
+
+	idx, ok := v.VariantType.FieldIndex(name.Value)
+	if !ok {
+		return nil, fmt.Errorf("variant %s has no field %s", variant, name.Value)
+	}
+	return v.Values[idx], nil
+}
+
+// Matches reports whether v was built by variant, the value that the name in a pattern such as Result.Ok(x) stands for.
+// A pattern with another number of fields than the variant is an error rather than a mismatch
+func (v *Variant) Matches(variant Object, numFields int) (bool, error) {
+	t := v.VariantType
+	switch variant := variant.(type) {
+	case *Variant:
+		if variant != t.unit {
+			return false, nil
+		}
+	case *BuiltIn:
+		if variant != t.constructor {
+			return false, nil
+		}
+	default:
+		return false, nil
+	}
+
+	if numFields != len(t.Fields) {
+		return false, fmt.Errorf("wrong number of fields in pattern for %s.%s. want=%d, got=%d", t.Enum.Name, t.Name, len(t.Fields), numFields)
+	}
+	return true, nil
+}
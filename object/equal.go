@@ -0,0 +1,69 @@
+package object
+
+// Equal reports whether a and b hold the same value. Arrays, tuples, hashes, structs of the same type and variants of
+// the same variant are compared element by element, functions and closures are only equal to themselves
+func Equal(a, b Object) bool {
+	if a == b {
+		return true
+	}
+
+	if a == nil || b == nil || a.Type() != b.Type() {
+		return false
+	}
+
+	switch a := a.(type) {
+	case *Integer:
+		return a.Value == b.(*Integer).Value
+	case *Float:
+		return a.Value == b.(*Float).Value
+	case *Boolean:
+		return a.Value == b.(*Boolean).Value
+	case *String:
+		return a.Value == b.(*String).Value
+	case *Null:
+		return true
+	case *Exception:
+		return a.Message == b.(*Exception).Message
+	case *Regex:
+		return a.Value.String() == b.(*Regex).Value.String()
+	case *Array:
+		return elementsEqual(a.Elements, b.(*Array).Elements)
+	case *Tuple:
+		return elementsEqual(a.Elements, b.(*Tuple).Elements)
+	case *Struct:
+		other := b.(*Struct)
+		return a.StructType == other.StructType && elementsEqual(a.Fields, other.Fields)
+	case *Variant:
+		other := b.(*Variant)
+		return a.VariantType == other.VariantType && elementsEqual(a.Values, other.Values)
+	case *Hash:
+		other := b.(*Hash)
+		if len(a.Pairs) != len(other.Pairs) {
+			return false
+		}
+
+		for key, pair := range a.Pairs {
+			otherPair, ok := other.Pairs[key]
+			if !ok || !Equal(pair.Value, otherPair.Value) {
+				return false
+			}
+		}
+		return true
+	default:
+		return false
+	}
+}
+
+func elementsEqual(a, b []Object) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	for i := range a {
+		if !Equal(a[i], b[i]) {
+			return false
+		}
+	}
+
+	return true
+}
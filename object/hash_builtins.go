@@ -0,0 +1,148 @@
+package object
+
+// hashAndKey checks the arguments of the builtins that look up one key, a hash followed by a hashable key
+func hashAndKey(name string, args []Object) (*Hash, HashKey, *Error) {
+	hash, ok := args[0].(*Hash)
+	if !ok {
+		return nil, HashKey{}, newError("first argument to `%s` must be hash type. got=%s", name, args[0].Type())
+	}
+
+	key, ok := AsHashable(args[1])
+	if !ok {
+		return nil, HashKey{}, newError("unusable as hash key: %s", args[1].Type())
+	}
+
+	return hash, key.HashKey(), nil
+}
+
+func copyPairs(hash *Hash) map[HashKey]HashPair {
+	pairs := make(map[HashKey]HashPair, len(hash.Pairs))
+	for k, pair := range hash.Pairs {
+		pairs[k] = pair
+	}
+	return pairs
+}
+
+func hasKey(_ Caller, args ...Object) Object {
+	if len(args) != 2 {
+		return newError("`has_key` expects two arguments")
+	}
+
+	hash, key, err := hashAndKey("has_key", args)
+	if err != nil {
+		return err
+	}
+
+	_, ok := hash.Pairs[key]
+	return nativeBool(ok)
+}
+
+// get(h, k, default) is h[k], or default when h has no key k. default is null when it is left out
+func get(_ Caller, args ...Object) Object {
+	if len(args) != 2 && len(args) != 3 {
+		return newError("`get` expects two or three arguments")
+	}
+
+	hash, key, err := hashAndKey("get", args)
+	if err != nil {
+		return err
+	}
+
+	if pair, ok := hash.Pairs[key]; ok {
+		return pair.Value
+	}
+	if len(args) == 3 {
+		return args[2]
+	}
+	return NULL
+}
+
+// delete returns a copy of the hash without the key, the hash itself is left as it is
+func deleteKey(_ Caller, args ...Object) Object {
+	if len(args) != 2 {
+		return newError("`delete` expects two arguments")
+	}
+
+	hash, key, err := hashAndKey("delete", args)
+	if err != nil {
+		return err
+	}
+
+	pairs := copyPairs(hash)
+	delete(pairs, key)
+	return &Hash{Pairs: pairs}
+}
+
+// merge returns a hash with the pairs of all its arguments, where keys repeat the last hash wins
+func merge(_ Caller, args ...Object) Object {
+	if len(args) == 0 {
+		return newError("`merge` expects at least one argument")
+	}
+
+	pairs := make(map[HashKey]HashPair)
+	for _, arg := range args {
+		hash, ok := arg.(*Hash)
+		if !ok {
+			return newError("argument to `merge` must be hash type. got=%s", arg.Type())
+		}
+
+		for k, pair := range hash.Pairs {
+			pairs[k] = pair
+		}
+	}
+
+	return &Hash{Pairs: pairs}
+}
+
+// entries returns the [key, value] pairs of a hash
+func entries(_ Caller, args ...Object) Object {
+	if len(args) != 1 {
+		return newError("`entries` expects one argument")
+	}
+
+	hash, ok := args[0].(*Hash)
+	if !ok {
+		return newError("argument to `entries` must be hash type. got=%s", args[0].Type())
+	}
+
+	pairs := make([]Object, 0, len(hash.Pairs))
+	for _, pair := range hash.Pairs {
+		pairs = append(pairs, &Array{Elements: []Object{pair.Key, pair.Value}})
+	}
+
+	return &Array{Elements: pairs}
+}
+
+// from_entries builds a hash from [key, value] pairs, the inverse of entries
+func fromEntries(_ Caller, args ...Object) Object {
+	if len(args) != 1 {
+		return newError("`from_entries` expects one argument")
+	}
+
+	arr, ok := args[0].(*Array)
+	if !ok {
+		return newError("argument to `from_entries` must be array type. got=%s", args[0].Type())
+	}
+
+	pairs := make(map[HashKey]HashPair, len(arr.Elements))
+	for _, el := range arr.Elements {
+		var entry []Object
+		switch el := el.(type) {
+		case *Array:
+			entry = el.Elements
+		case *Tuple:
+			entry = el.Elements
+		}
+		if len(entry) != 2 {
+			return newError("`from_entries` expects [key, value] pairs. got=%s", el.Inspect())
+		}
+
+		key, ok := AsHashable(entry[0])
+		if !ok {
+			return newError("unusable as hash key: %s", entry[0].Type())
+		}
+		pairs[key.HashKey()] = HashPair{Key: entry[0], Value: entry[1]}
+	}
+
+	return &Hash{Pairs: pairs}
+}
@@ -0,0 +1,331 @@
+package object
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Capabilities is what the I/O builtins of an interpreter are allowed to touch, the CLI grants them from
+// --allow-read and --allow-write. Nothing on disk is readable or writable until a directory is granted, stdin is
+// always available to input and read_line
+type Capabilities struct {
+	readRoots  []string
+	writeRoots []string
+	stdin      *bufio.Reader
+}
+
+func NewCapabilities(stdin io.Reader) *Capabilities {
+	return &Capabilities{stdin: bufio.NewReader(stdin)}
+}
+
+// AllowRead lets the I/O builtins read every file below dir
+func (c *Capabilities) AllowRead(dir string) error {
+	root, err := resolvePath(dir)
+	if err != nil {
+		return err
+	}
+
+	c.readRoots = append(c.readRoots, root)
+	return nil
+}
+
+// AllowWrite lets the I/O builtins create and write every file below dir
+func (c *Capabilities) AllowWrite(dir string) error {
+	root, err := resolvePath(dir)
+	if err != nil {
+		return err
+	}
+
+	c.writeRoots = append(c.writeRoots, root)
+	return nil
+}
+
+// checkRead returns the resolved path if name may read it
+func (c *Capabilities) checkRead(name, path string) (string, *Error) {
+	return checkAccess(name, path, "read", c.readRoots)
+}
+
+// checkWrite returns the resolved path if name may write it
+func (c *Capabilities) checkWrite(name, path string) (string, *Error) {
+	return checkAccess(name, path, "write", c.writeRoots)
+}
+
+func checkAccess(name, path, access string, roots []string) (string, *Error) {
+	resolved, err := resolvePath(path)
+	if err != nil {
+		return "", newError("`%s` cannot resolve %s: %s", name, path, err)
+	}
+
+	if !withinAny(roots, resolved) {
+		return "", newError("`%s` is not allowed to %s %s, run with --allow-%s", name, access, path, access)
+	}
+	return resolved, nil
+}
+
+// openForWrite opens the file at a path checkWrite resolved. A symlink there now is dangling or was made since the
+// check, writing through it could leave the granted directories. What was opened is checked again before it is
+// truncated or written
+func (c *Capabilities) openForWrite(name, path, resolved string, flag int) (*os.File, *Error) {
+	if info, err := os.Lstat(resolved); err == nil && info.Mode()&fs.ModeSymlink != 0 {
+		return nil, newError("`%s` will not write through the symlink %s", name, path)
+	}
+
+	file, err := os.OpenFile(resolved, os.O_WRONLY|os.O_CREATE|flag&^os.O_TRUNC, 0o644)
+	if err != nil {
+		return nil, ioError(name, err)
+	}
+
+	if !c.isWritable(resolved, file) {
+		file.Close()
+		return nil, newError("`%s` is not allowed to write %s, run with --allow-write", name, path)
+	}
+
+	if flag&os.O_TRUNC != 0 {
+		if err := file.Truncate(0); err != nil {
+			file.Close()
+			return nil, ioError(name, err)
+		}
+	}
+	return file, nil
+}
+
+// isWritable reports whether the file opened at path is below a granted directory once path is resolved again
+func (c *Capabilities) isWritable(path string, file *os.File) bool {
+	resolved, err := filepath.EvalSymlinks(path)
+	if err != nil || !withinAny(c.writeRoots, resolved) {
+		return false
+	}
+
+	opened, err := file.Stat()
+	if err != nil {
+		return false
+	}
+	found, err := os.Lstat(resolved)
+	return err == nil && os.SameFile(opened, found)
+}
+
+// resolvePath makes path absolute and follows the symlinks in the part of it that exists, so a link cannot lead out
+// of a granted directory
+func resolvePath(path string) (string, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return "", err
+	}
+
+	existing, rest := abs, ""
+	for {
+		resolved, err := filepath.EvalSymlinks(existing)
+		if err == nil {
+			return filepath.Join(resolved, rest), nil
+		}
+
+		parent := filepath.Dir(existing)
+		if parent == existing {
+			return abs, nil
+		}
+		rest = filepath.Join(filepath.Base(existing), rest)
+		existing = parent
+	}
+}
+
+func withinAny(roots []string, path string) bool {
+	for _, root := range roots {
+		if isWithin(root, path) {
+			return true
+		}
+	}
+	return false
+}
+
+func isWithin(root, path string) bool {
+	rel, err := filepath.Rel(root, path)
+	if err != nil {
+		return false
+	}
+
+	return rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// ioError drops the path Go puts in front of file errors, the script already knows which file it asked for
+func ioError(name string, err error) *Error {
+	var pathErr *fs.PathError
+	if errors.As(err, &pathErr) {
+		err = pathErr.Err
+	}
+
+	return newError("`%s` failed: %s", name, err)
+}
+
+// fileContent reads a file for the builtin name once the capabilities allow it
+func fileContent(caller Caller, name string, args []Object) (string, *Error) {
+	values, err := stringArgs(name, args, 1)
+	if err != nil {
+		return "", err
+	}
+
+	path, err := caller.IO().checkRead(name, values[0])
+	if err != nil {
+		return "", err
+	}
+
+	content, readErr := os.ReadFile(path)
+	if readErr != nil {
+		return "", ioError(name, readErr)
+	}
+
+	return string(content), nil
+}
+
+func readFile(caller Caller, args ...Object) Object {
+	content, err := fileContent(caller, "read_file", args)
+	if err != nil {
+		return err
+	}
+
+	return &String{Value: content}
+}
+
+// read_lines splits a file into lines without their line endings
+func readLines(caller Caller, args ...Object) Object {
+	content, err := fileContent(caller, "read_lines", args)
+	if err != nil {
+		return err
+	}
+
+	if content == "" {
+		return &Array{Elements: []Object{}}
+	}
+
+	lines := strings.Split(strings.TrimSuffix(content, "\n"), "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimSuffix(line, "\r")
+	}
+
+	return stringArray(lines)
+}
+
+// writeBuiltin makes write_file and append_file, flag decides whether the file is truncated or appended to
+func writeBuiltin(name string, flag int) BuiltInFunction {
+	return func(caller Caller, args ...Object) Object {
+		values, err := stringArgs(name, args, 2)
+		if err != nil {
+			return err
+		}
+
+		path, err := caller.IO().checkWrite(name, values[0])
+		if err != nil {
+			return err
+		}
+
+		file, err := caller.IO().openForWrite(name, values[0], path, flag)
+		if err != nil {
+			return err
+		}
+
+		_, writeErr := file.WriteString(values[1])
+		closeErr := file.Close()
+		if writeErr != nil {
+			return ioError(name, writeErr)
+		}
+		if closeErr != nil {
+			return ioError(name, closeErr)
+		}
+
+		return NULL
+	}
+}
+
+// exists needs read access too, otherwise it would tell what is on disk outside the granted directories
+func exists(caller Caller, args ...Object) Object {
+	values, err := stringArgs("exists", args, 1)
+	if err != nil {
+		return err
+	}
+
+	path, err := caller.IO().checkRead("exists", values[0])
+	if err != nil {
+		return err
+	}
+
+	_, statErr := os.Stat(path)
+	if errors.Is(statErr, fs.ErrNotExist) {
+		return FALSE
+	}
+	if statErr != nil {
+		return ioError("exists", statErr)
+	}
+
+	return TRUE
+}
+
+// list_dir returns the sorted names of the entries of a directory
+func listDir(caller Caller, args ...Object) Object {
+	values, err := stringArgs("list_dir", args, 1)
+	if err != nil {
+		return err
+	}
+
+	path, err := caller.IO().checkRead("list_dir", values[0])
+	if err != nil {
+		return err
+	}
+
+	entries, readErr := os.ReadDir(path)
+	if readErr != nil {
+		return ioError("list_dir", readErr)
+	}
+
+	names := make([]string, len(entries))
+	for i, entry := range entries {
+		names[i] = entry.Name()
+	}
+	sort.Strings(names)
+
+	return stringArray(names)
+}
+
+// input prints an optional prompt and reads a line from stdin
+func input(caller Caller, args ...Object) Object {
+	if len(args) > 1 {
+		return newError("`input` expects at most one argument")
+	}
+
+	if len(args) == 1 {
+		prompt, ok := args[0].(*String)
+		if !ok {
+			return newError("argument to `input` must be string type. got=%s", args[0].Type())
+		}
+		fmt.Print(prompt.Value)
+	}
+
+	return caller.IO().readLine("input")
+}
+
+func readLine(caller Caller, args ...Object) Object {
+	if len(args) != 0 {
+		return newError("`read_line` expects no arguments")
+	}
+
+	return caller.IO().readLine("read_line")
+}
+
+// readLine returns the next line of stdin without its line ending, or null once stdin is exhausted
+func (c *Capabilities) readLine(name string) Object {
+	line, err := c.stdin.ReadString('\n')
+	if err == io.EOF && line == "" {
+		return NULL
+	}
+	if err != nil && err != io.EOF {
+		return ioError(name, err)
+	}
+
+	line = strings.TrimSuffix(line, "\n")
+	return &String{Value: strings.TrimSuffix(line, "\r")}
+}
@@ -0,0 +1,197 @@
+package object
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// maxJSONIndent caps the indent json_encode repeats on every line, like the 10 spaces of JSON.stringify
+const maxJSONIndent = 10
+
+// json_encode(value, indent?) serializes value, indent is a number of spaces or the string to indent with
+func jsonEncode(_ Caller, args ...Object) Object {
+	if len(args) != 1 && len(args) != 2 {
+		return newError("`json_encode` expects one or two arguments")
+	}
+
+	indent := ""
+	if len(args) == 2 {
+		switch arg := args[1].(type) {
+		case *Integer:
+			if arg.Value < 0 {
+				return newError("negative indent for `json_encode`: %d", arg.Value)
+			}
+			if arg.Value > maxJSONIndent {
+				return newError("indent for `json_encode` is too large: %d", arg.Value)
+			}
+			indent = strings.Repeat(" ", int(arg.Value))
+		case *String:
+			if len(arg.Value) > maxJSONIndent {
+				return newError("indent for `json_encode` is too large: %d bytes", len(arg.Value))
+			}
+			indent = arg.Value
+		default:
+			return newError("second argument to `json_encode` must be int or string type. got=%s", arg.Type())
+		}
+	}
+
+	value, err := toJSON(args[0], make(map[Object]bool))
+	if err != nil {
+		return err
+	}
+
+	var out bytes.Buffer
+	encoder := json.NewEncoder(&out)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", indent)
+	if encodeErr := encoder.Encode(value); encodeErr != nil {
+		return newError("`json_encode` failed: %s", encodeErr)
+	}
+
+	return &String{Value: strings.TrimSuffix(out.String(), "\n")}
+}
+
+// toJSON converts obj into the value encoding/json writes. Floats become json.Number so they keep their decimal
+// point and decode as floats again. seen holds the arrays and hashes being converted, to catch one that contains itself
+func toJSON(obj Object, seen map[Object]bool) (interface{}, *Error) {
+	switch obj := obj.(type) {
+	case nil, *Null:
+		return nil, nil
+	case *Boolean:
+		return obj.Value, nil
+	case *Integer:
+		return obj.Value, nil
+	case *Float:
+		if math.IsNaN(obj.Value) || math.IsInf(obj.Value, 0) {
+			return nil, newError("`json_encode` cannot encode %s", obj.Inspect())
+		}
+		return json.Number(formatJSONFloat(obj.Value)), nil
+	case *String:
+		return obj.Value, nil
+	case *Array:
+		return elementsToJSON(obj, obj.Elements, seen)
+	case *Tuple:
+		return elementsToJSON(obj, obj.Elements, seen)
+	case *Hash:
+		if seen[obj] {
+			return nil, newError("`json_encode` cannot encode a value that contains itself")
+		}
+		seen[obj] = true
+		defer delete(seen, obj)
+
+		values := make(map[string]interface{}, len(obj.Pairs))
+		for _, pair := range obj.Pairs {
+			key, ok := pair.Key.(*String)
+			if !ok {
+				return nil, newError("`json_encode` needs string keys. got=%s", pair.Key.Type())
+			}
+
+			value, err := toJSON(pair.Value, seen)
+			if err != nil {
+				return nil, err
+			}
+			values[key.Value] = value
+		}
+		return values, nil
+	default:
+		return nil, newError("`json_encode` cannot encode %s", obj.Type())
+	}
+}
+
+func elementsToJSON(obj Object, elements []Object, seen map[Object]bool) (interface{}, *Error) {
+	if seen[obj] {
+		return nil, newError("`json_encode` cannot encode a value that contains itself")
+	}
+	seen[obj] = true
+	defer delete(seen, obj)
+
+	values := make([]interface{}, len(elements))
+	for i, el := range elements {
+		value, err := toJSON(el, seen)
+		if err != nil {
+			return nil, err
+		}
+		values[i] = value
+	}
+
+	return values, nil
+}
+
+func formatJSONFloat(value float64) string {
+	str := strconv.FormatFloat(value, 'g', -1, 64)
+	if !strings.ContainsAny(str, ".eE") {
+		str += ".0"
+	}
+	return str
+}
+
+// json_decode parses a JSON document. Numbers without a fraction or exponent that fit in an int become Integers,
+// all others Floats
+func jsonDecode(_ Caller, args ...Object) Object {
+	values, err := stringArgs("json_decode", args, 1)
+	if err != nil {
+		return err
+	}
+
+	decoder := json.NewDecoder(strings.NewReader(values[0]))
+	decoder.UseNumber()
+
+	var value interface{}
+	if decodeErr := decoder.Decode(&value); decodeErr != nil {
+		return newError("`json_decode` invalid JSON: %s", decodeErr)
+	}
+	if _, extraErr := decoder.Token(); !errors.Is(extraErr, io.EOF) {
+		return newError("`json_decode` invalid JSON: unexpected data after the value")
+	}
+
+	return fromJSON(value)
+}
+
+func fromJSON(value interface{}) Object {
+	switch value := value.(type) {
+	case nil:
+		return NULL
+	case bool:
+		return nativeBool(value)
+	case json.Number:
+		if !strings.ContainsAny(string(value), ".eE") {
+			if i, err := value.Int64(); err == nil {
+				return &Integer{Value: i}
+			}
+		}
+		f, err := value.Float64()
+		if err != nil {
+			return newError("`json_decode` number out of range: %s", value)
+		}
+		return &Float{Value: f}
+	case string:
+		return &String{Value: value}
+	case []interface{}:
+		elements := make([]Object, len(value))
+		for i, el := range value {
+			elements[i] = fromJSON(el)
+			if isError(elements[i]) {
+				return elements[i]
+			}
+		}
+		return &Array{Elements: elements}
+	case map[string]interface{}:
+		pairs := make(map[HashKey]HashPair, len(value))
+		for k, v := range value {
+			key := &String{Value: k}
+			val := fromJSON(v)
+			if isError(val) {
+				return val
+			}
+			pairs[key.HashKey()] = HashPair{Key: key, Value: val}
+		}
+		return &Hash{Pairs: pairs}
+	default:
+		return newError("`json_decode` cannot decode %T", value)
+	}
+}
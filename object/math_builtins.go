@@ -0,0 +1,209 @@
+package object
+
+import (
+	"math"
+)
+
+// toFloat returns the value of an Integer or Float argument as a float64
+func toFloat(name string, arg Object) (float64, *Error) {
+	switch num := arg.(type) {
+	case *Integer:
+		return float64(num.Value), nil
+	case *Float:
+		return num.Value, nil
+	default:
+		return 0, newError("argument to `%s` must be a number. got=%s", name, arg.Type())
+	}
+}
+
+func numberArgs(name string, args []Object, count int) ([]float64, *Error) {
+	if len(args) != count {
+		return nil, newError("`%s` expects %s", name, pluralArguments(count))
+	}
+
+	values := make([]float64, len(args))
+	for i, arg := range args {
+		value, err := toFloat(name, arg)
+		if err != nil {
+			return nil, err
+		}
+		values[i] = value
+	}
+
+	return values, nil
+}
+
+// floatResult reports a NaN result as a domain error instead of returning it
+func floatResult(name string, result float64, args ...float64) Object {
+	if math.IsNaN(result) {
+		for _, arg := range args {
+			// NaN in, NaN out
+			if math.IsNaN(arg) {
+				return &Float{Value: result}
+			}
+		}
+		return newError("math domain error in `%s`", name)
+	}
+
+	return &Float{Value: result}
+}
+
+// floatFunction makes the builtins that map one number to a float, such as sqrt and the trig functions
+func floatFunction(name string, fn func(float64) float64) BuiltInFunction {
+	return func(_ Caller, args ...Object) Object {
+		values, err := numberArgs(name, args, 1)
+		if err != nil {
+			return err
+		}
+
+		return floatResult(name, fn(values[0]), values[0])
+	}
+}
+
+// log is only defined for positive numbers, log(0) is an error rather than -INF
+func mathLog(_ Caller, args ...Object) Object {
+	values, err := numberArgs("log", args, 1)
+	if err != nil {
+		return err
+	}
+
+	if values[0] <= 0 {
+		return newError("math domain error in `log`")
+	}
+
+	return &Float{Value: math.Log(values[0])}
+}
+
+func mathAbs(_ Caller, args ...Object) Object {
+	if len(args) != 1 {
+		return newError("`abs` expects one argument")
+	}
+
+	switch num := args[0].(type) {
+	case *Integer:
+		if num.Value == math.MinInt64 {
+			return newError("`abs` of %d does not fit in an int", num.Value)
+		}
+		if num.Value < 0 {
+			return &Integer{Value: -num.Value}
+		}
+		return num
+	case *Float:
+		return &Float{Value: math.Abs(num.Value)}
+	default:
+		return newError("argument to `abs` must be a number. got=%s", num.Type())
+	}
+}
+
+// roundingFunction makes floor, ceil and round. Integers are returned as they are, floats are rounded to an Integer
+func roundingFunction(name string, fn func(float64) float64) BuiltInFunction {
+	return func(_ Caller, args ...Object) Object {
+		if len(args) != 1 {
+			return newError("`%s` expects one argument", name)
+		}
+
+		switch num := args[0].(type) {
+		case *Integer:
+			return num
+		case *Float:
+			rounded := fn(num.Value)
+			if math.IsNaN(rounded) || rounded < math.MinInt64 || rounded >= math.MaxInt64 {
+				return newError("`%s` of %s does not fit in an int", name, num.Inspect())
+			}
+			return &Integer{Value: int64(rounded)}
+		default:
+			return newError("argument to `%s` must be a number. got=%s", name, num.Type())
+		}
+	}
+}
+
+// pow stays in integers for an Integer base and a non negative Integer exponent
+func mathPow(_ Caller, args ...Object) Object {
+	if len(args) != 2 {
+		return newError("`pow` expects two arguments")
+	}
+
+	base, baseIsInt := args[0].(*Integer)
+	exp, expIsInt := args[1].(*Integer)
+	if baseIsInt && expIsInt && exp.Value >= 0 {
+		result := int64(1)
+		b, e := base.Value, exp.Value
+		overflow := false
+		for e > 0 && !overflow {
+			if e&1 == 1 {
+				result, overflow = multiplyInts(result, b)
+			}
+			e >>= 1
+			if e > 0 && !overflow {
+				b, overflow = multiplyInts(b, b)
+			}
+		}
+		if overflow {
+			return newError("`pow` of %d and %d does not fit in an int", base.Value, exp.Value)
+		}
+		return &Integer{Value: result}
+	}
+
+	values, err := numberArgs("pow", args, 2)
+	if err != nil {
+		return err
+	}
+
+	return floatResult("pow", math.Pow(values[0], values[1]), values...)
+}
+
+// multiplyInts multiplies a and b, reporting whether the product overflows an int64
+func multiplyInts(a, b int64) (int64, bool) {
+	if a == 0 || b == 0 {
+		return 0, false
+	}
+	product := a * b
+	if product/b != a || (a == -1 && b == math.MinInt64) || (b == -1 && a == math.MinInt64) {
+		return 0, true
+	}
+	return product, false
+}
+
+func mathAtan2(_ Caller, args ...Object) Object {
+	values, err := numberArgs("atan2", args, 2)
+	if err != nil {
+		return err
+	}
+
+	return &Float{Value: math.Atan2(values[0], values[1])}
+}
+
+// extremum makes min and max. They take numbers or a single array of numbers and return the winning argument
+// unchanged, so the minimum of Integers is an Integer
+func extremum(name string, better func(a, b float64) bool) BuiltInFunction {
+	return func(_ Caller, args ...Object) Object {
+		if len(args) == 1 {
+			if arr, ok := args[0].(*Array); ok {
+				args = arr.Elements
+			}
+		}
+
+		if len(args) == 0 {
+			return newError("`%s` expects at least one number", name)
+		}
+
+		best := args[0]
+		bestValue, err := toFloat(name, best)
+		if err != nil {
+			return err
+		}
+
+		for _, arg := range args[1:] {
+			value, err := toFloat(name, arg)
+			if err != nil {
+				return err
+			}
+
+			if better(value, bestValue) {
+				best, bestValue = arg, value
+			}
+		}
+
+		return best
+	}
+}
@@ -0,0 +1,28 @@
+package object
+
+// Member is the field, export, variant or string key name of obj. A method call obj.name() calls it when there is one, and
+// name(obj) when there is not
+func Member(obj Object, name string) (Object, bool) {
+	switch obj := obj.(type) {
+	case *Struct:
+		if idx, ok := obj.StructType.FieldIndex(name); ok {
+			return obj.Fields[idx], true
+		}
+	case *Variant:
+		if idx, ok := obj.VariantType.FieldIndex(name); ok {
+			return obj.Values[idx], true
+		}
+	case *EnumType:
+		if variant, ok := obj.FindVariant(name); ok {
+			return variant.value(), true
+		}
+	case *Module:
+		export, ok := obj.Exports[name]
+		return export, ok
+	case *Hash:
+		if pair, ok := obj.Pairs[(&String{Value: name}).HashKey()]; ok {
+			return pair.Value, true
+		}
+	}
+	return nil, false
+}
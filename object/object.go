@@ -4,15 +4,37 @@ import (
 	"bytes"
 	"fmt"
 	"hash/fnv"
+	"math"
+	"math/rand"
 	"quonk/ast"
 	"quonk/code"
+	"regexp"
 	"strconv"
 
 	"strings"
 )
 
 type ObjectType string
-type BuiltInFunction func(args ...Object) Object
+
+// BuiltInFunction reports a runtime error by returning an *Error, both engines stop with its message and the line
+// of the call. The caller calls functions passed as arguments back in the engine running the builtin
+type BuiltInFunction func(caller Caller, args ...Object) Object
+
+// Caller is implemented by both engines so builtins can call Quonk functions. An *Error result must be returned by
+// the builtin unchanged, so the error or thrown value reaches the engine as it was. Random is the random number
+// generator of the interpreter running the builtin, seeding it does not affect other interpreters
+type Caller interface {
+	Call(fn Object, args ...Object) Object
+	Random() *rand.Rand
+	IO() *Capabilities
+}
+
+// TRUE, FALSE and NULL are shared by both engines, which compare them by identity, so builtins can return them
+var (
+	TRUE  = &Boolean{Value: true}
+	FALSE = &Boolean{Value: false}
+	NULL  = &Null{}
+)
 
 const (
 	IntegerObj          ObjectType = "Integer"
@@ -31,6 +53,15 @@ const (
 	MacroObj            ObjectType = "Macro"
 	CompiledFunctionObj ObjectType = "CompiledFunction"
 	ClosureObj          ObjectType = "Closure"
+	TupleObj            ObjectType = "Tuple"
+	ExceptionObj        ObjectType = "Exception"
+	CellObj             ObjectType = "Cell"
+	RegexObj            ObjectType = "Regex"
+	ModuleObj           ObjectType = "Module"
+	StructTypeObj       ObjectType = "StructType"
+	StructObj           ObjectType = "Struct"
+	EnumObj             ObjectType = "Enum"
+	VariantObj          ObjectType = "Variant"
 )
 
 type (
@@ -61,6 +92,7 @@ type (
 
 	Error struct {
 		Message string
+		Thrown  Object // the value passed to throw, nil for runtime errors
 	}
 
 	Variable struct {
@@ -80,6 +112,8 @@ type (
 
 	BuiltIn struct {
 		Fn BuiltInFunction
+		// Name is what a builtin made by the program inspects as, like Result.Ok for the constructor of a variant
+		Name string
 	}
 
 	Array struct {
@@ -119,12 +153,82 @@ type (
 		Instructions  code.Instructions
 		NumLocals     int
 		NumParameters int
+		Handlers      []ExceptionHandler
+		Lines         code.LineTable
+	}
+
+	// ExceptionHandler catches values thrown by the instructions in [Start, End) and resumes execution at Target.
+	// Handlers are ordered innermost first
+	ExceptionHandler struct {
+		Start  int
+		End    int
+		Target int
 	}
 
 	Closure struct {
 		Fn   *CompiledFunction
 		Free []Object
 	}
+
+	// Cell boxes a mutable local captured by a closure, so the closure and the declaring frame share one variable
+	Cell struct {
+		Value Object
+	}
+
+	// Exception is the first-class error value created by `error` and bound by catch
+	Exception struct {
+		Message string
+	}
+
+	// Tuple is an immutable array. Every element is Hashable, so a Tuple can be used as a composite hash key
+	Tuple struct {
+		Elements []Object
+	}
+
+	// Regex is a compiled regular expression, kept in a variable it is compiled once instead of on every call
+	Regex struct {
+		Value *regexp.Regexp
+	}
+
+	// Module is an imported file. Exports holds the values its exported variables had when it finished running
+	Module struct {
+		Name    string
+		Exports map[string]Object
+	}
+
+	// StructType is declared by a struct statement, its structs have a slot for each of Fields
+	StructType struct {
+		Name   string
+		Fields []string
+	}
+
+	// Struct is a value of a StructType, Fields holds its values in the order of the type's fields
+	Struct struct {
+		StructType *StructType
+		Fields     []Object
+	}
+
+	// EnumType is declared by an enum statement, see NewEnumType
+	EnumType struct {
+		Name     string
+		Variants []*VariantType
+	}
+
+	// VariantType is one variant of an EnumType. A variant without fields is a single value, one with fields is
+	// built by calling it with a value for each
+	VariantType struct {
+		Enum        *EnumType
+		Name        string
+		Fields      []string
+		unit        *Variant
+		constructor *BuiltIn
+	}
+
+	// Variant is a value of an EnumType, Values holds its payload in the order of the variant's fields
+	Variant struct {
+		VariantType *VariantType
+		Values      []Object
+	}
 )
 
 func (i *Integer) Type() ObjectType {
@@ -191,6 +295,42 @@ func (c *Closure) Type() ObjectType {
 	return ClosureObj
 }
 
+func (t *Tuple) Type() ObjectType {
+	return TupleObj
+}
+
+func (e *Exception) Type() ObjectType {
+	return ExceptionObj
+}
+
+func (c *Cell) Type() ObjectType {
+	return CellObj
+}
+
+func (r *Regex) Type() ObjectType {
+	return RegexObj
+}
+
+func (m *Module) Type() ObjectType {
+	return ModuleObj
+}
+
+func (t *StructType) Type() ObjectType {
+	return StructTypeObj
+}
+
+func (s *Struct) Type() ObjectType {
+	return StructObj
+}
+
+func (e *EnumType) Type() ObjectType {
+	return EnumObj
+}
+
+func (v *Variant) Type() ObjectType {
+	return VariantObj
+}
+
 func (i *Integer) Inspect() string {
 	return fmt.Sprintf("%d", i.Value)
 }
@@ -237,6 +377,9 @@ func (s *String) Inspect() string {
 }
 
 func (b *BuiltIn) Inspect() string {
+	if b.Name != "" {
+		return b.Name
+	}
 	return "builtin function"
 }
 
@@ -302,6 +445,92 @@ func (c *Closure) Inspect() string {
 	return fmt.Sprintf("Closure[%p]", c)
 }
 
+func (t *Tuple) Inspect() string {
+	var out bytes.Buffer
+
+	elements := []string{}
+	for _, e := range t.Elements {
+		elements = append(elements, e.Inspect())
+	}
+
+	out.WriteString("(")
+	out.WriteString(strings.Join(elements, ", "))
+	if len(t.Elements) == 1 {
+		out.WriteString(",")
+	}
+	out.WriteString(")")
+
+	return out.String()
+}
+
+func (e *Exception) Inspect() string {
+	return fmt.Sprintf("Error: %s", e.Message)
+}
+
+func (c *Cell) Inspect() string {
+	return c.Value.Inspect()
+}
+
+func (r *Regex) Inspect() string {
+	return "/" + r.Value.String() + "/"
+}
+
+func (m *Module) Inspect() string {
+	return fmt.Sprintf("module(%s)", m.Name)
+}
+
+func (t *StructType) Inspect() string {
+	return fmt.Sprintf("struct %s {%s}", t.Name, strings.Join(t.Fields, ", "))
+}
+
+func (s *Struct) Inspect() string {
+	fields := make([]string, len(s.Fields))
+	for i, value := range s.Fields {
+		fields[i] = s.StructType.Fields[i] + ": " + value.Inspect()
+	}
+
+	return fmt.Sprintf("%s{%s}", s.StructType.Name, strings.Join(fields, ", "))
+}
+
+func (e *EnumType) Inspect() string {
+	variants := make([]string, len(e.Variants))
+	for i, variant := range e.Variants {
+		variants[i] = variant.Name
+		if len(variant.Fields) > 0 {
+			variants[i] += "(" + strings.Join(variant.Fields, ", ") + ")"
+		}
+	}
+
+	return fmt.Sprintf("enum %s {%s}", e.Name, strings.Join(variants, ", "))
+}
+
+func (v *Variant) Inspect() string {
+	name := v.VariantType.Enum.Name + "." + v.VariantType.Name
+	if len(v.VariantType.Fields) == 0 {
+		return name
+	}
+
+	values := make([]string, len(v.Values))
+	for i, value := range v.Values {
+		values[i] = value.Inspect()
+	}
+	return fmt.Sprintf("%s(%s)", name, strings.Join(values, ", "))
+}
+
+// Export is m.name, both engines index modules with it
+func (m *Module) Export(index Object) (Object, error) {
+	name, ok := index.(*String)
+	if !ok {
+		return nil, fmt.Errorf("module %s has no export %s", m.Name, index.Inspect())
+	}
+
+	value, ok := m.Exports[name.Value]
+	if !ok {
+		return nil, fmt.Errorf("module %s has no export %s", m.Name, name.Value)
+	}
+	return value, nil
+}
+
 // HashKey functions
 func (b *Boolean) HashKey() HashKey {
 	var val uint64
@@ -325,3 +554,85 @@ func (s *String) HashKey() HashKey {
 
 	return HashKey{Type: s.Type(), HashValue: h.Sum64(), ObjectValue: s.Value}
 }
+
+func (f *Float) HashKey() HashKey {
+	val := f.Value
+	// -0.0 and 0.0 compare equal, so they must hash the same
+	if val == 0 {
+		val = 0
+	}
+
+	return HashKey{Type: f.Type(), HashValue: math.Float64bits(val), ObjectValue: val}
+}
+
+func (t *Tuple) HashKey() HashKey {
+	h := fnv.New64a()
+	var value bytes.Buffer
+
+	for _, e := range t.Elements {
+		key := e.(Hashable).HashKey()
+		fmt.Fprintf(h, "%s:%d;", key.Type, key.HashValue)
+		fmt.Fprintf(&value, "%s:%v;", key.Type, key.ObjectValue)
+	}
+
+	return HashKey{Type: t.Type(), HashValue: h.Sum64(), ObjectValue: value.String()}
+}
+
+func (v *Variant) HashKey() HashKey {
+	h := fnv.New64a()
+	var value bytes.Buffer
+
+	// variants of different enums may share a name, so the variant type itself tells them apart
+	fmt.Fprintf(h, "%p;", v.VariantType)
+	fmt.Fprintf(&value, "%p;", v.VariantType)
+	for _, e := range v.Values {
+		key := e.(Hashable).HashKey()
+		fmt.Fprintf(h, "%s:%d;", key.Type, key.HashValue)
+		fmt.Fprintf(&value, "%s:%v;", key.Type, key.ObjectValue)
+	}
+
+	return HashKey{Type: v.Type(), HashValue: h.Sum64(), ObjectValue: value.String()}
+}
+
+// AsHashable is obj as a hash key. Tuples and variants are Hashable, but a variant can hold any payload, so they
+// are only usable as keys when everything in them is
+func AsHashable(obj Object) (Hashable, bool) {
+	var elements []Object
+	switch obj := obj.(type) {
+	case *Tuple:
+		elements = obj.Elements
+	case *Variant:
+		elements = obj.Values
+	}
+	for _, e := range elements {
+		if _, ok := AsHashable(e); !ok {
+			return nil, false
+		}
+	}
+
+	key, ok := obj.(Hashable)
+	return key, ok
+}
+
+// NewTuple freezes elements into a Tuple. Nested arrays are frozen as well, any other unhashable element is an error
+func NewTuple(elements []Object) (*Tuple, error) {
+	frozen := make([]Object, len(elements))
+
+	for i, e := range elements {
+		if arr, ok := e.(*Array); ok {
+			nested, err := NewTuple(arr.Elements)
+			if err != nil {
+				return nil, err
+			}
+			frozen[i] = nested
+			continue
+		}
+
+		if _, ok := AsHashable(e); !ok {
+			return nil, fmt.Errorf("unusable as tuple element: %s", e.Type())
+		}
+		frozen[i] = e
+	}
+
+	return &Tuple{Elements: frozen}, nil
+}
@@ -1,6 +1,9 @@
 package object
 
-import "testing"
+import (
+	"math"
+	"testing"
+)
 
 func TestStringHashKey(t *testing.T) {
 	hello1 := &String{Value: "Hello, World!"}
@@ -21,3 +24,92 @@ func TestStringHashKey(t *testing.T) {
 		t.Errorf("strings with different content have same hash key")
 	}
 }
+
+func TestFloatHashKey(t *testing.T) {
+	one1 := &Float{Value: 1.5}
+	one2 := &Float{Value: 1.5}
+	diff := &Float{Value: 2.5}
+
+	if one1.HashKey() != one2.HashKey() {
+		t.Errorf("floats with same content have different hash keys")
+	}
+
+	if one1.HashKey() == diff.HashKey() {
+		t.Errorf("floats with different content have same hash key")
+	}
+
+	if (&Float{Value: 0}).HashKey() != (&Float{Value: math.Copysign(0, -1)}).HashKey() {
+		t.Errorf("0.0 and -0.0 have different hash keys")
+	}
+
+	if (&Float{Value: 1}).HashKey() == (&Integer{Value: 1}).HashKey() {
+		t.Errorf("float and integer with same value have same hash key")
+	}
+}
+
+func TestTupleHashKey(t *testing.T) {
+	pair1, err := NewTuple([]Object{&Integer{Value: 1}, &String{Value: "a"}})
+	if err != nil {
+		t.Fatalf("NewTuple returned error: %s", err)
+	}
+	pair2, _ := NewTuple([]Object{&Integer{Value: 1}, &String{Value: "a"}})
+	diff, _ := NewTuple([]Object{&String{Value: "a"}, &Integer{Value: 1}})
+	nested, _ := NewTuple([]Object{&Array{Elements: []Object{&Integer{Value: 1}}}})
+
+	if pair1.HashKey() != pair2.HashKey() {
+		t.Errorf("tuples with same content have different hash keys")
+	}
+
+	if pair1.HashKey() == diff.HashKey() {
+		t.Errorf("tuples with different content have same hash key")
+	}
+
+	if _, ok := nested.Elements[0].(*Tuple); !ok {
+		t.Errorf("nested array was not frozen. got=%T", nested.Elements[0])
+	}
+
+	_, err = NewTuple([]Object{&Hash{Pairs: map[HashKey]HashPair{}}})
+	if err == nil {
+		t.Errorf("expected error freezing unhashable element")
+	}
+}
+
+func TestEqual(t *testing.T) {
+	closure := &Closure{}
+	tests := []struct {
+		left     Object
+		right    Object
+		expected bool
+	}{
+		{&Integer{Value: 1}, &Integer{Value: 1}, true},
+		{&Integer{Value: 1}, &Float{Value: 1}, false},
+		{&Null{}, &Null{}, true},
+		{
+			&Array{Elements: []Object{&Integer{Value: 1}, &String{Value: "a"}}},
+			&Array{Elements: []Object{&Integer{Value: 1}, &String{Value: "a"}}},
+			true,
+		},
+		{
+			&Array{Elements: []Object{&Integer{Value: 1}}},
+			&Array{Elements: []Object{&Integer{Value: 1}, &Integer{Value: 2}}},
+			false,
+		},
+		{
+			&Hash{Pairs: map[HashKey]HashPair{
+				(&String{Value: "a"}).HashKey(): {Key: &String{Value: "a"}, Value: &Array{Elements: []Object{}}},
+			}},
+			&Hash{Pairs: map[HashKey]HashPair{
+				(&String{Value: "a"}).HashKey(): {Key: &String{Value: "a"}, Value: &Array{Elements: []Object{}}},
+			}},
+			true,
+		},
+		{closure, closure, true},
+		{closure, &Closure{}, false},
+	}
+
+	for _, tt := range tests {
+		if Equal(tt.left, tt.right) != tt.expected {
+			t.Errorf("Equal(%s, %s) wrong. want=%t", tt.left.Inspect(), tt.right.Inspect(), tt.expected)
+		}
+	}
+}
@@ -0,0 +1,96 @@
+package object
+
+import "math"
+
+// rand_int(lo, hi) is a random integer from lo to hi, both included
+func randInt(caller Caller, args ...Object) Object {
+	if len(args) != 2 {
+		return newError("`rand_int` expects two arguments")
+	}
+
+	lo, ok := args[0].(*Integer)
+	if !ok {
+		return newError("first argument to `rand_int` must be int type. got=%s", args[0].Type())
+	}
+	hi, ok := args[1].(*Integer)
+	if !ok {
+		return newError("second argument to `rand_int` must be int type. got=%s", args[1].Type())
+	}
+	if lo.Value > hi.Value {
+		return newError("empty range for `rand_int`: %d > %d", lo.Value, hi.Value)
+	}
+
+	random := caller.Random()
+	span := uint64(hi.Value-lo.Value) + 1
+	if span == 0 || span > math.MaxInt64 {
+		// the range is wider than Int63n can draw from, draw from all ints until one falls in it
+		for {
+			n := int64(random.Uint64())
+			if n >= lo.Value && n <= hi.Value {
+				return &Integer{Value: n}
+			}
+		}
+	}
+
+	return &Integer{Value: lo.Value + random.Int63n(int64(span))}
+}
+
+// rand_float is a random float in [0, 1)
+func randFloat(caller Caller, args ...Object) Object {
+	if len(args) != 0 {
+		return newError("`rand_float` expects no arguments")
+	}
+
+	return &Float{Value: caller.Random().Float64()}
+}
+
+// shuffle returns the elements of an array in random order, the array itself is left as it is
+func shuffle(caller Caller, args ...Object) Object {
+	if len(args) != 1 {
+		return newError("`shuffle` expects one argument")
+	}
+
+	arr, ok := args[0].(*Array)
+	if !ok {
+		return newError("argument to `shuffle` must be array type. got=%s", args[0].Type())
+	}
+
+	shuffled := make([]Object, len(arr.Elements))
+	copy(shuffled, arr.Elements)
+	caller.Random().Shuffle(len(shuffled), func(i, j int) {
+		shuffled[i], shuffled[j] = shuffled[j], shuffled[i]
+	})
+
+	return &Array{Elements: shuffled}
+}
+
+func choice(caller Caller, args ...Object) Object {
+	if len(args) != 1 {
+		return newError("`choice` expects one argument")
+	}
+
+	arr, ok := args[0].(*Array)
+	if !ok {
+		return newError("argument to `choice` must be array type. got=%s", args[0].Type())
+	}
+	if len(arr.Elements) == 0 {
+		return newError("`choice` of an empty array")
+	}
+
+	return arr.Elements[caller.Random().Intn(len(arr.Elements))]
+}
+
+// seed restarts the random numbers of the running interpreter from n
+func seed(caller Caller, args ...Object) Object {
+	if len(args) != 1 {
+		return newError("`seed` expects one argument")
+	}
+
+	n, ok := args[0].(*Integer)
+	if !ok {
+		return newError("argument to `seed` must be int type. got=%s", args[0].Type())
+	}
+
+	caller.Random().Seed(n.Value)
+	return NULL
+}
@@ -0,0 +1,131 @@
+package object
+
+import "regexp"
+
+func compileRegex(name, pattern string) (*Regex, *Error) {
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, newError("invalid regular expression for `%s`: %s", name, err)
+	}
+
+	return &Regex{Value: re}, nil
+}
+
+// regexArgs checks that a regex builtin got count arguments, a Regex or a pattern string followed by strings. A
+// pattern string is compiled on every call, re_compile compiles it once
+func regexArgs(name string, args []Object, count int) (*regexp.Regexp, []string, *Error) {
+	if len(args) != count {
+		return nil, nil, newError("`%s` expects %s", name, pluralArguments(count))
+	}
+
+	var re *Regex
+	switch arg := args[0].(type) {
+	case *Regex:
+		re = arg
+	case *String:
+		var err *Error
+		re, err = compileRegex(name, arg.Value)
+		if err != nil {
+			return nil, nil, err
+		}
+	default:
+		return nil, nil, newError("first argument to `%s` must be regex or string type. got=%s", name, arg.Type())
+	}
+
+	values, err := stringArgs(name, args[1:], count-1)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return re.Value, values, nil
+}
+
+func regexCompile(_ Caller, args ...Object) Object {
+	values, err := stringArgs("re_compile", args, 1)
+	if err != nil {
+		return err
+	}
+
+	re, err := compileRegex("re_compile", values[0])
+	if err != nil {
+		return err
+	}
+
+	return re
+}
+
+// re_match reports whether the pattern matches anywhere in s, anchor it with ^ and $ to match all of s
+func regexMatch(_ Caller, args ...Object) Object {
+	re, values, err := regexArgs("re_match", args, 2)
+	if err != nil {
+		return err
+	}
+
+	return nativeBool(re.MatchString(values[0]))
+}
+
+// re_find returns the first match as an array of the whole match followed by its groups, or null if there is none.
+// Groups that did not take part in the match are null
+func regexFind(_ Caller, args ...Object) Object {
+	re, values, err := regexArgs("re_find", args, 2)
+	if err != nil {
+		return err
+	}
+
+	indexes := re.FindStringSubmatchIndex(values[0])
+	if indexes == nil {
+		return NULL
+	}
+
+	return matchArray(values[0], indexes)
+}
+
+// re_find_all returns every match in the form re_find returns one
+func regexFindAll(_ Caller, args ...Object) Object {
+	re, values, err := regexArgs("re_find_all", args, 2)
+	if err != nil {
+		return err
+	}
+
+	all := re.FindAllStringSubmatchIndex(values[0], -1)
+	matches := make([]Object, len(all))
+	for i, indexes := range all {
+		matches[i] = matchArray(values[0], indexes)
+	}
+
+	return &Array{Elements: matches}
+}
+
+func matchArray(s string, indexes []int) *Array {
+	groups := make([]Object, len(indexes)/2)
+	for i := range groups {
+		start, end := indexes[2*i], indexes[2*i+1]
+		if start < 0 {
+			groups[i] = NULL
+			continue
+		}
+		groups[i] = &String{Value: s[start:end]}
+	}
+
+	return &Array{Elements: groups}
+}
+
+// re_replace replaces every match, $1 or ${name} in the replacement stand for the groups of the match
+func regexReplace(_ Caller, args ...Object) Object {
+	re, values, err := regexArgs("re_replace", args, 3)
+	if err != nil {
+		return err
+	}
+
+	return &String{Value: re.ReplaceAllString(values[0], values[1])}
+}
+
+// re_split splits s around every match
+func regexSplit(_ Caller, args ...Object) Object {
+	re, values, err := regexArgs("re_split", args, 2)
+	if err != nil {
+		return err
+	}
+
+	return stringArray(re.Split(values[0], -1))
+}
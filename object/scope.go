@@ -2,6 +2,10 @@ package object
 
 import (
 	"fmt"
+	"math/rand"
+	"os"
+	"quonk/module"
+	"time"
 )
 
 func NewScope() *Scope {
@@ -17,9 +21,103 @@ func NewEnclosedScope(outer *Scope) *Scope {
 	return s
 }
 
+// NewModuleScope is the outermost scope of a module imported from the program of importer. It shares nothing with
+// importer but the state of the program
+func NewModuleScope(importer *Scope) *Scope {
+	s := NewScope()
+	s.program = importer.root().state()
+
+	return s
+}
+
 type Scope struct {
-	store map[string]Variable
-	outer *Scope
+	store   map[string]Variable
+	outer   *Scope
+	program *programState // only set on the outermost scope, see state
+	exports []string      // the names a module exports, on its outermost scope
+}
+
+// programState is shared by the outermost scope of a program and the outermost scopes of the modules it imports
+type programState struct {
+	random  *rand.Rand
+	io      *Capabilities
+	loader  *module.Loader
+	modules map[string]*Module // by the path of their file
+}
+
+func (s *Scope) root() *Scope {
+	for s.outer != nil {
+		s = s.outer
+	}
+	return s
+}
+
+func (s *Scope) state() *programState {
+	root := s.root()
+	if root.program == nil {
+		root.program = &programState{
+			random:  rand.New(rand.NewSource(time.Now().UnixNano())),
+			io:      NewCapabilities(os.Stdin),
+			loader:  module.NewLoader(""),
+			modules: make(map[string]*Module),
+		}
+	}
+	return root.program
+}
+
+// Random is the random number generator of the program the scope belongs to
+func (s *Scope) Random() *rand.Rand {
+	return s.state().random
+}
+
+// IO is what the I/O builtins of the program the scope belongs to may touch
+func (s *Scope) IO() *Capabilities {
+	return s.state().io
+}
+
+// SetIO replaces the capabilities of the program, which grant nothing at first
+func (s *Scope) SetIO(io *Capabilities) {
+	s.state().io = io
+}
+
+// SetFile tells the program of the scope which file it comes from, the files it imports are relative to it
+func (s *Scope) SetFile(path string) {
+	s.state().loader = module.NewLoader(path)
+}
+
+// Loader finds the files the program imports
+func (s *Scope) Loader() *module.Loader {
+	return s.state().loader
+}
+
+// Module is the module the program loaded from the file at path, if it has been loaded
+func (s *Scope) Module(path string) (*Module, bool) {
+	m, ok := s.state().modules[path]
+	return m, ok
+}
+
+func (s *Scope) AddModule(path string, m *Module) {
+	s.state().modules[path] = m
+}
+
+// IsTopLevel reports whether the scope is the outermost one of a program or module, where import and export go
+func (s *Scope) IsTopLevel() bool {
+	return s.outer == nil
+}
+
+// Export marks a variable of the outermost scope as exported by its module
+func (s *Scope) Export(name string) {
+	s.exports = append(s.exports, name)
+}
+
+// Exports are the exported variables of a module with their current values
+func (s *Scope) Exports() map[string]Object {
+	exports := make(map[string]Object, len(s.exports))
+	for _, name := range s.exports {
+		variable, _, _ := s.Get(name)
+		exports[name] = variable.Value
+	}
+	return exports
 }
 
 // var, fromOuter, ok
@@ -0,0 +1,90 @@
+package object
+
+import (
+	"fmt"
+	"unicode/utf8"
+)
+
+// Slice is the a[start:end] of both engines and the slice builtin. Arrays slice into arrays, tuples into tuples and
+// strings into strings, counting characters like len does. A null bound is the start or end, a negative one counts from
+// the end, and bounds past either end are clamped, so a slice never fails on its bounds
+func Slice(left, start, end Object) (Object, error) {
+	var length int
+	switch left := left.(type) {
+	case *Array:
+		length = len(left.Elements)
+	case *Tuple:
+		length = len(left.Elements)
+	case *String:
+		length = utf8.RuneCountInString(left.Value)
+	default:
+		return nil, fmt.Errorf("slice operator not supported: %s", left.Type())
+	}
+
+	from, err := sliceBound(start, 0, length)
+	if err != nil {
+		return nil, err
+	}
+	to, err := sliceBound(end, length, length)
+	if err != nil {
+		return nil, err
+	}
+	if to < from {
+		to = from
+	}
+
+	switch left := left.(type) {
+	case *Array:
+		elements := make([]Object, to-from)
+		copy(elements, left.Elements[from:to])
+		return &Array{Elements: elements}, nil
+	case *Tuple:
+		return &Tuple{Elements: left.Elements[from:to]}, nil
+	default:
+		str := left.(*String).Value
+		if length == len(str) {
+			return &String{Value: str[from:to]}, nil
+		}
+		return &String{Value: string([]rune(str)[from:to])}, nil
+	}
+}
+
+// CharAt is the character of str at idx, which counts characters rather than bytes and from the end when negative.
+// It is false when idx is out of bounds
+func CharAt(str string, idx int64) (*String, bool) {
+	length := utf8.RuneCountInString(str)
+	if idx < 0 {
+		idx += int64(length)
+	}
+	if idx < 0 || idx >= int64(length) {
+		return nil, false
+	}
+
+	if length == len(str) {
+		return &String{Value: str[idx : idx+1]}, true
+	}
+	return &String{Value: string([]rune(str)[idx])}, true
+}
+
+func sliceBound(bound Object, missing, length int) (int, error) {
+	if bound == nil || bound == NULL {
+		return missing, nil
+	}
+
+	n, ok := bound.(*Integer)
+	if !ok {
+		return 0, fmt.Errorf("slice bound must be int type. got=%s", bound.Type())
+	}
+
+	idx := n.Value
+	if idx < 0 {
+		idx += int64(length)
+	}
+	if idx < 0 {
+		return 0, nil
+	}
+	if idx > int64(length) {
+		return length, nil
+	}
+	return int(idx), nil
+}
@@ -0,0 +1,194 @@
+package object
+
+import (
+	"math"
+	"sort"
+	"strings"
+)
+
+// compareValues is the order sort uses without a comparator. Numbers come before strings, Integers and Floats are
+// compared by value with NaN after every other number, and strings are compared byte by byte
+func compareValues(name string, a, b Object) (int, *Error) {
+	aRank, err := sortRank(name, a)
+	if err != nil {
+		return 0, err
+	}
+	bRank, err := sortRank(name, b)
+	if err != nil {
+		return 0, err
+	}
+	if aRank != bRank {
+		return aRank - bRank, nil
+	}
+
+	if aStr, ok := a.(*String); ok {
+		return strings.Compare(aStr.Value, b.(*String).Value), nil
+	}
+	return compareNumbers(a, b), nil
+}
+
+func sortRank(name string, obj Object) (int, *Error) {
+	switch obj.(type) {
+	case *Integer, *Float:
+		return 0, nil
+	case *String:
+		return 1, nil
+	default:
+		return 0, newError("`%s` cannot order %s", name, obj.Type())
+	}
+}
+
+func compareNumbers(a, b Object) int {
+	aInt, aIsInt := a.(*Integer)
+	bInt, bIsInt := b.(*Integer)
+	if aIsInt && bIsInt {
+		return compareInts(aInt.Value, bInt.Value)
+	}
+
+	aFloat, _ := toFloat("", a)
+	bFloat, _ := toFloat("", b)
+	switch {
+	case math.IsNaN(aFloat) && math.IsNaN(bFloat):
+		return 0
+	case math.IsNaN(aFloat):
+		return 1
+	case math.IsNaN(bFloat):
+		return -1
+	case aFloat < bFloat:
+		return -1
+	case aFloat > bFloat:
+		return 1
+	}
+
+	// equal as floats, an int and a whole float may still differ beyond float precision
+	if aIsInt && bFloat >= math.MinInt64 && bFloat < math.MaxInt64 {
+		return compareInts(aInt.Value, int64(bFloat))
+	}
+	if bIsInt && aFloat >= math.MinInt64 && aFloat < math.MaxInt64 {
+		return compareInts(int64(aFloat), bInt.Value)
+	}
+	return 0
+}
+
+func compareInts(a, b int64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// sortStable sorts a copy of elements with compare, which may fail. The first error stops the sort
+func sortStable(elements []Object, compare func(a, b Object) (int, Object)) ([]Object, Object) {
+	sorted := make([]Object, len(elements))
+	copy(sorted, elements)
+
+	var failure Object
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if failure != nil {
+			return false
+		}
+
+		result, err := compare(sorted[i], sorted[j])
+		if err != nil {
+			failure = err
+			return false
+		}
+		return result < 0
+	})
+
+	return sorted, failure
+}
+
+// sort(array) sorts in the order of compareValues, sort(array, cmp) in the order of cmp(a, b), which returns a
+// negative int when a comes first, a positive one when b does and 0 to keep them as they are. Equal elements keep
+// their order either way
+func sortBuiltin(caller Caller, args ...Object) Object {
+	if len(args) != 1 && len(args) != 2 {
+		return newError("`sort` expects one or two arguments")
+	}
+
+	arr, ok := args[0].(*Array)
+	if !ok {
+		return newError("first argument to `sort` must be array type. got=%s", args[0].Type())
+	}
+
+	compare := func(a, b Object) (int, Object) {
+		result, err := compareValues("sort", a, b)
+		if err != nil {
+			return 0, err
+		}
+		return result, nil
+	}
+
+	if len(args) == 2 {
+		cmp := args[1]
+		if !isCallable(cmp) {
+			return newError("second argument to `sort` must be a function. got=%s", cmp.Type())
+		}
+
+		compare = func(a, b Object) (int, Object) {
+			result := caller.Call(cmp, a, b)
+			if isError(result) {
+				return 0, result
+			}
+
+			n, ok := result.(*Integer)
+			if !ok {
+				return 0, newError("comparator for `sort` must return an int. got=%s", typeOf(result))
+			}
+			return compareInts(n.Value, 0), nil
+		}
+	}
+
+	sorted, err := sortStable(arr.Elements, compare)
+	if err != nil {
+		return err
+	}
+
+	return &Array{Elements: sorted}
+}
+
+// sort_by(array, key) sorts by the key of every element, in the order of compareValues. key is called once per element
+func sortBy(caller Caller, args ...Object) Object {
+	arr, key, err := arrayAndCallback("sort_by", args)
+	if err != nil {
+		return err
+	}
+
+	keyed := make([]Object, len(arr.Elements))
+	for i, el := range arr.Elements {
+		k := caller.Call(key, el)
+		if isError(k) {
+			return k
+		}
+		keyed[i] = &Array{Elements: []Object{k, el}}
+	}
+
+	sorted, failure := sortStable(keyed, func(a, b Object) (int, Object) {
+		result, err := compareValues("sort_by", a.(*Array).Elements[0], b.(*Array).Elements[0])
+		if err != nil {
+			return 0, err
+		}
+		return result, nil
+	})
+	if failure != nil {
+		return failure
+	}
+
+	for i, pair := range sorted {
+		sorted[i] = pair.(*Array).Elements[1]
+	}
+
+	return &Array{Elements: sorted}
+}
+
+func typeOf(obj Object) ObjectType {
+	if obj == nil {
+		return NullObj
+	}
+	return obj.Type()
+}
@@ -0,0 +1,403 @@
+package object
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// maxStringLength caps the strings builtins build by repetition, so a huge count errors instead of exhausting memory
+const maxStringLength = 1 << 30
+
+// stringArgs checks that a string builtin got count arguments and that all of them are strings
+func stringArgs(name string, args []Object, count int) ([]string, *Error) {
+	if len(args) != count {
+		return nil, newError("`%s` expects %s", name, pluralArguments(count))
+	}
+
+	values := make([]string, len(args))
+	for i, arg := range args {
+		str, ok := arg.(*String)
+		if !ok {
+			return nil, newError("argument to `%s` must be string type. got=%s", name, arg.Type())
+		}
+		values[i] = str.Value
+	}
+
+	return values, nil
+}
+
+func pluralArguments(count int) string {
+	words := []string{"no arguments", "one argument", "two arguments", "three arguments"}
+	if count < len(words) {
+		return words[count]
+	}
+	return fmt.Sprintf("%d arguments", count)
+}
+
+func stringArray(values []string) *Array {
+	elements := make([]Object, len(values))
+	for i, value := range values {
+		elements[i] = &String{Value: value}
+	}
+
+	return &Array{Elements: elements}
+}
+
+func nativeBool(value bool) *Boolean {
+	if value {
+		return TRUE
+	}
+	return FALSE
+}
+
+// split(s) splits around runs of whitespace, split(s, sep) around every sep
+func stringSplit(_ Caller, args ...Object) Object {
+	if len(args) == 1 {
+		values, err := stringArgs("split", args, 1)
+		if err != nil {
+			return err
+		}
+		return stringArray(strings.Fields(values[0]))
+	}
+
+	values, err := stringArgs("split", args, 2)
+	if err != nil {
+		return err
+	}
+
+	return stringArray(strings.Split(values[0], values[1]))
+}
+
+func stringJoin(_ Caller, args ...Object) Object {
+	if len(args) != 2 {
+		return newError("`join` expects two arguments")
+	}
+
+	arr, ok := args[0].(*Array)
+	if !ok {
+		return newError("first argument to `join` must be array type. got=%s", args[0].Type())
+	}
+	sep, ok := args[1].(*String)
+	if !ok {
+		return newError("second argument to `join` must be string type. got=%s", args[1].Type())
+	}
+
+	values := make([]string, len(arr.Elements))
+	for i, el := range arr.Elements {
+		str, ok := el.(*String)
+		if !ok {
+			return newError("`join` expects an array of strings. got=%s", el.Type())
+		}
+		values[i] = str.Value
+	}
+
+	return &String{Value: strings.Join(values, sep.Value)}
+}
+
+// trimBuiltin makes trim, trim_left and trim_right. They strip whitespace, or the characters of an optional cutset
+func trimBuiltin(name string, trimSpace func(string) string, trimCutset func(string, string) string) BuiltInFunction {
+	return func(_ Caller, args ...Object) Object {
+		if len(args) == 2 {
+			values, err := stringArgs(name, args, 2)
+			if err != nil {
+				return err
+			}
+			return &String{Value: trimCutset(values[0], values[1])}
+		}
+
+		values, err := stringArgs(name, args, 1)
+		if err != nil {
+			return err
+		}
+
+		return &String{Value: trimSpace(values[0])}
+	}
+}
+
+func trimLeftSpace(s string) string {
+	return strings.TrimLeftFunc(s, unicode.IsSpace)
+}
+
+func trimRightSpace(s string) string {
+	return strings.TrimRightFunc(s, unicode.IsSpace)
+}
+
+// stringPredicate makes the builtins that test a string against another one
+func stringPredicate(name string, predicate func(string, string) bool) BuiltInFunction {
+	return func(_ Caller, args ...Object) Object {
+		values, err := stringArgs(name, args, 2)
+		if err != nil {
+			return err
+		}
+
+		return nativeBool(predicate(values[0], values[1]))
+	}
+}
+
+// stringMapping makes the builtins that turn one string into another
+func stringMapping(name string, mapping func(string) string) BuiltInFunction {
+	return func(_ Caller, args ...Object) Object {
+		values, err := stringArgs(name, args, 1)
+		if err != nil {
+			return err
+		}
+
+		return &String{Value: mapping(values[0])}
+	}
+}
+
+// index_of is the byte offset of the first occurrence of sub, like len it counts bytes. -1 if there is none
+func stringIndexOf(_ Caller, args ...Object) Object {
+	values, err := stringArgs("index_of", args, 2)
+	if err != nil {
+		return err
+	}
+
+	// the index counts characters, so that it can index and slice the string
+	idx := strings.Index(values[0], values[1])
+	if idx < 0 {
+		return &Integer{Value: -1}
+	}
+	return &Integer{Value: int64(utf8.RuneCountInString(values[0][:idx]))}
+}
+
+// replace substitutes every occurrence of old
+func stringReplace(_ Caller, args ...Object) Object {
+	values, err := stringArgs("replace", args, 3)
+	if err != nil {
+		return err
+	}
+
+	return &String{Value: strings.ReplaceAll(values[0], values[1], values[2])}
+}
+
+func stringRepeat(_ Caller, args ...Object) Object {
+	if len(args) != 2 {
+		return newError("`repeat` expects two arguments")
+	}
+
+	str, ok := args[0].(*String)
+	if !ok {
+		return newError("first argument to `repeat` must be string type. got=%s", args[0].Type())
+	}
+	count, ok := args[1].(*Integer)
+	if !ok {
+		return newError("second argument to `repeat` must be int type. got=%s", args[1].Type())
+	}
+	if count.Value < 0 {
+		return newError("negative count for `repeat`: %d", count.Value)
+	}
+	if len(str.Value) > 0 && count.Value > int64(maxStringLength/len(str.Value)) {
+		return newError("result of `repeat` is too long: %d copies of %d bytes", count.Value, len(str.Value))
+	}
+
+	return &String{Value: strings.Repeat(str.Value, int(count.Value))}
+}
+
+// chars splits a string into its characters
+func stringChars(_ Caller, args ...Object) Object {
+	values, err := stringArgs("chars", args, 1)
+	if err != nil {
+		return err
+	}
+
+	chars := make([]string, 0, utf8.RuneCountInString(values[0]))
+	for _, r := range values[0] {
+		chars = append(chars, string(r))
+	}
+
+	return stringArray(chars)
+}
+
+// reverse reverses the characters of a string, or the elements of an array
+func reverse(_ Caller, args ...Object) Object {
+	if len(args) != 1 {
+		return newError("`reverse` expects one argument")
+	}
+
+	switch arg := args[0].(type) {
+	case *String:
+		runes := []rune(arg.Value)
+		for i, j := 0, len(runes)-1; i < j; i, j = i+1, j-1 {
+			runes[i], runes[j] = runes[j], runes[i]
+		}
+		return &String{Value: string(runes)}
+	case *Array:
+		length := len(arg.Elements)
+		reversed := make([]Object, length)
+		for i, el := range arg.Elements {
+			reversed[length-1-i] = el
+		}
+		return &Array{Elements: reversed}
+	default:
+		return newError("argument to `reverse` must be string or array type. got=%s", arg.Type())
+	}
+}
+
+// format is printf for Quonk values. It supports the flags -+# 0, width and precision, including *, with the verbs
+// d, f, e, g, s, q, v, t, x, X, c and %%
+func stringFormat(_ Caller, args ...Object) Object {
+	if len(args) == 0 {
+		return newError("`format` expects at least one argument")
+	}
+
+	format, ok := args[0].(*String)
+	if !ok {
+		return newError("first argument to `format` must be string type. got=%s", args[0].Type())
+	}
+
+	f := &formatter{args: args[1:]}
+	err := f.format(format.Value)
+	if err != nil {
+		return err
+	}
+
+	if f.argIdx < len(f.args) {
+		return newError("too many arguments for `format`: want=%d, got=%d", f.argIdx, len(f.args))
+	}
+
+	return &String{Value: f.out.String()}
+}
+
+type formatter struct {
+	out    strings.Builder
+	args   []Object
+	argIdx int
+}
+
+func (f *formatter) nextArg() (Object, *Error) {
+	if f.argIdx >= len(f.args) {
+		return nil, newError("too few arguments for `format`")
+	}
+
+	arg := f.args[f.argIdx]
+	f.argIdx++
+	return arg, nil
+}
+
+func (f *formatter) format(format string) *Error {
+	for i := 0; i < len(format); i++ {
+		if format[i] != '%' {
+			f.out.WriteByte(format[i])
+			continue
+		}
+
+		// the spec is rebuilt as a Go verb, with every * replaced by its argument
+		var spec strings.Builder
+		spec.WriteByte('%')
+		i++
+
+		for i < len(format) && strings.IndexByte("-+# 0", format[i]) >= 0 {
+			spec.WriteByte(format[i])
+			i++
+		}
+
+		var err *Error
+		i, err = f.number(format, i, &spec)
+		if err != nil {
+			return err
+		}
+		if i < len(format) && format[i] == '.' {
+			spec.WriteByte('.')
+			i, err = f.number(format, i+1, &spec)
+			if err != nil {
+				return err
+			}
+		}
+
+		if i >= len(format) {
+			return newError("`format` string ends in the middle of a verb")
+		}
+
+		verb := format[i]
+		if verb == '%' {
+			f.out.WriteByte('%')
+			continue
+		}
+
+		arg, err := f.nextArg()
+		if err != nil {
+			return err
+		}
+
+		value, err := formatValue(verb, arg)
+		if err != nil {
+			return err
+		}
+
+		spec.WriteByte(verb)
+		fmt.Fprintf(&f.out, spec.String(), value)
+	}
+
+	return nil
+}
+
+// number copies a width or precision at format[i:] into spec and returns the index after it
+func (f *formatter) number(format string, i int, spec *strings.Builder) (int, *Error) {
+	if i < len(format) && format[i] == '*' {
+		arg, err := f.nextArg()
+		if err != nil {
+			return i, err
+		}
+
+		n, ok := arg.(*Integer)
+		if !ok {
+			return i, newError("`format` expects an int for *. got=%s", arg.Type())
+		}
+
+		fmt.Fprintf(spec, "%d", n.Value)
+		return i + 1, nil
+	}
+
+	for i < len(format) && format[i] >= '0' && format[i] <= '9' {
+		spec.WriteByte(format[i])
+		i++
+	}
+
+	return i, nil
+}
+
+// formatValue converts arg into the Go value the verb expects
+func formatValue(verb byte, arg Object) (interface{}, *Error) {
+	switch verb {
+	case 'd', 'c':
+		if i, ok := arg.(*Integer); ok {
+			return i.Value, nil
+		}
+	case 'f', 'e', 'g':
+		switch num := arg.(type) {
+		case *Float:
+			return num.Value, nil
+		case *Integer:
+			return float64(num.Value), nil
+		}
+	case 'x', 'X':
+		switch val := arg.(type) {
+		case *Integer:
+			return val.Value, nil
+		case *String:
+			return val.Value, nil
+		}
+	case 't':
+		if b, ok := arg.(*Boolean); ok {
+			return b.Value, nil
+		}
+	case 's', 'v':
+		// strings are formatted without quotes, everything else as it is printed
+		if str, ok := arg.(*String); ok {
+			return str.Value, nil
+		}
+		return arg.Inspect(), nil
+	case 'q':
+		if str, ok := arg.(*String); ok {
+			return str.Value, nil
+		}
+		return arg.Inspect(), nil
+	default:
+		return nil, newError("unknown `format` verb %%%c", verb)
+	}
+
+	return nil, newError("`format` verb %%%c does not accept %s", verb, arg.Type())
+}
@@ -0,0 +1,56 @@
+package object
+
+import "fmt"
+
+// FieldIndex is the slot of the field name in the structs of t
+func (t *StructType) FieldIndex(name string) (int, bool) {
+	for i, field := range t.Fields {
+		if field == name {
+			return i, true
+		}
+	}
+	return 0, false
+}
+
+// New builds a struct of t with values for the fields in names, the fields left out are null
+func (t *StructType) New(names []string, values []Object) (*Struct, error) {
+	fields := make([]Object, len(t.Fields))
+	for i := range fields {
+		fields[i] = NULL
+	}
+
+	for i, name := range names {
+		idx, ok := t.FieldIndex(name)
+		if !ok {
+			return nil, fmt.Errorf("struct %s has no field %s", t.Name, name)
+		}
+		fields[idx] = values[i]
+	}
+
+	return &Struct{StructType: t, Fields: fields}, nil
+}
+
+// Field is s.name, both engines index structs with it when they do not know the slot of the field
+func (s *Struct) Field(index Object) (Object, error) {
+	name, ok := index.(*String)
+	if !ok {
+		return nil, fmt.Errorf("struct %s has no field %s", s.StructType.Name, index.Inspect())
+	}
+
+	idx, ok := s.StructType.FieldIndex(name.Value)
+	if !ok {
+		return nil, fmt.Errorf("struct %s has no field %s", s.StructType.Name, name.Value)
+	}
+	return s.Fields[idx], nil
+}
+
+// SetField is s.name = value
+func (s *Struct) SetField(name string, value Object) error {
+	idx, ok := s.StructType.FieldIndex(name)
+	if !ok {
+		return fmt.Errorf("struct %s has no field %s", s.StructType.Name, name)
+	}
+
+	s.Fields[idx] = value
+	return nil
+}
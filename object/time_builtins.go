@@ -0,0 +1,134 @@
+package object
+
+import (
+	"math"
+	"time"
+)
+
+// Clock is where the time builtins get the time from. Tests swap in a fake one to stay deterministic
+type Clock interface {
+	// Now is the wall clock time, its Location is the one format_time and parse_time use
+	Now() time.Time
+	// Monotonic is the time elapsed since a fixed point, it never goes backwards
+	Monotonic() time.Duration
+	Sleep(d time.Duration)
+}
+
+type systemClock struct {
+	start time.Time
+}
+
+func (c systemClock) Now() time.Time {
+	return time.Now()
+}
+
+func (c systemClock) Monotonic() time.Duration {
+	return time.Since(c.start)
+}
+
+func (c systemClock) Sleep(d time.Duration) {
+	time.Sleep(d)
+}
+
+// TimeSource is the clock of the running script
+var TimeSource Clock = systemClock{start: time.Now()}
+
+// now is the wall clock time in unix nanoseconds
+func now(_ Caller, args ...Object) Object {
+	if len(args) != 0 {
+		return newError("`now` expects no arguments")
+	}
+
+	return &Integer{Value: TimeSource.Now().UnixNano()}
+}
+
+// monotonic is a nanosecond reading for measuring elapsed time, only the difference between two readings means something
+func monotonic(_ Caller, args ...Object) Object {
+	if len(args) != 0 {
+		return newError("`monotonic` expects no arguments")
+	}
+
+	return &Integer{Value: int64(TimeSource.Monotonic())}
+}
+
+func sleep(_ Caller, args ...Object) Object {
+	if len(args) != 1 {
+		return newError("`sleep` expects one argument")
+	}
+
+	ms, ok := args[0].(*Integer)
+	if !ok {
+		return newError("argument to `sleep` must be int type. got=%s", args[0].Type())
+	}
+	if ms.Value < 0 {
+		return newError("negative duration for `sleep`: %d", ms.Value)
+	}
+
+	TimeSource.Sleep(time.Duration(ms.Value) * time.Millisecond)
+	return NULL
+}
+
+// format_time(ts, layout) formats unix nanoseconds with a Go layout such as "2006-01-02 15:04:05"
+func formatTime(_ Caller, args ...Object) Object {
+	if len(args) != 2 {
+		return newError("`format_time` expects two arguments")
+	}
+
+	ts, ok := args[0].(*Integer)
+	if !ok {
+		return newError("first argument to `format_time` must be int type. got=%s", args[0].Type())
+	}
+	layout, ok := args[1].(*String)
+	if !ok {
+		return newError("second argument to `format_time` must be string type. got=%s", args[1].Type())
+	}
+
+	t := time.Unix(0, ts.Value).In(TimeSource.Now().Location())
+	return &String{Value: t.Format(layout.Value)}
+}
+
+// parse_time(str, layout) is the inverse of format_time, it returns unix nanoseconds
+func parseTime(_ Caller, args ...Object) Object {
+	values, err := stringArgs("parse_time", args, 2)
+	if err != nil {
+		return err
+	}
+
+	t, parseErr := time.ParseInLocation(values[1], values[0], TimeSource.Now().Location())
+	if parseErr != nil {
+		return newError("`parse_time` failed: %s", parseErr)
+	}
+
+	return &Integer{Value: t.UnixNano()}
+}
+
+// durationUnit makes the helpers that turn a count of some unit into nanoseconds, like seconds(1.5)
+func durationUnit(name string, unit time.Duration) BuiltInFunction {
+	return func(_ Caller, args ...Object) Object {
+		values, err := numberArgs(name, args, 1)
+		if err != nil {
+			return err
+		}
+
+		nanos := values[0] * float64(unit)
+		if math.IsNaN(nanos) || nanos < math.MinInt64 || nanos >= math.MaxInt64 {
+			return newError("`%s` of %v does not fit in an int", name, values[0])
+		}
+
+		return &Integer{Value: int64(nanos)}
+	}
+}
+
+// format_duration prints nanoseconds the way Go prints durations, like 1h2m3.5s
+func formatDuration(_ Caller, args ...Object) Object {
+	if len(args) != 1 {
+		return newError("`format_duration` expects one argument")
+	}
+
+	nanos, ok := args[0].(*Integer)
+	if !ok {
+		return newError("argument to `format_duration` must be int type. got=%s", args[0].Type())
+	}
+
+	return &String{Value: time.Duration(nanos.Value).String()}
+}
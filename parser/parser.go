@@ -2,10 +2,12 @@ package parser
 
 import (
 	"fmt"
+	"path/filepath"
 	"quonk/ast"
 	"quonk/lexer"
 	"quonk/token"
 	"strconv"
+	"strings"
 )
 
 type (
@@ -17,7 +19,8 @@ type Precedence int
 
 const (
 	LOWEST Precedence = iota + 1
-	ANDOR             // I think this is right
+	COALESCE
+	ANDOR // I think this is right
 	EQUALS
 	LESSGREATEREQUAL
 	LESSGREATER
@@ -29,6 +32,7 @@ const (
 )
 
 var precedences = map[token.TokenType]Precedence{
+	token.NullCoalesce:       COALESCE,
 	token.And:                ANDOR,
 	token.Or:                 ANDOR,
 	token.EqualTo:            EQUALS,
@@ -44,11 +48,22 @@ var precedences = map[token.TokenType]Precedence{
 	token.Modulo:             PRODUCT,
 	token.LeftParen:          CALL,
 	token.LeftSquareBracket:  INDEX,
+	token.OptionalIndex:      INDEX,
+	token.OptionalDot:        INDEX,
+	token.Dot:                INDEX,
+	token.LeftCurlyBracket:   INDEX,
 }
 
+// StrictNullPragma enables strict-null mode when it is the first statement of a program
+const StrictNullPragma = "use strict-null"
+
 type Parser struct {
 	lexer *lexer.Lexer
 
+	// in strict-null mode null literals are rejected, uninitialized variables must declare a type and index misses
+	// are errors
+	strictNull bool
+
 	currToken token.Token
 	peekToken token.Token
 
@@ -75,6 +90,7 @@ func New(l *lexer.Lexer) *Parser {
 	p.registerPrefix(token.False, p.parseBooleanLiteral)
 	p.registerPrefix(token.LeftParen, p.parseGroupedExpr)
 	p.registerPrefix(token.If, p.parseIfExpr)
+	p.registerPrefix(token.Match, p.parseMatchExpr)
 	p.registerPrefix(token.Func, p.parseFunctionLiteral)
 	p.registerPrefix(token.String, p.parseStringLiteral)
 	p.registerPrefix(token.LeftSquareBracket, p.parseArrayLiteral)
@@ -97,8 +113,13 @@ func New(l *lexer.Lexer) *Parser {
 	p.registerInfix(token.LessThan, p.parseInfixExpr)
 	p.registerInfix(token.And, p.parseInfixExpr)
 	p.registerInfix(token.Or, p.parseInfixExpr)
+	p.registerInfix(token.NullCoalesce, p.parseInfixExpr)
 	p.registerInfix(token.LeftParen, p.parseCallExpr)
 	p.registerInfix(token.LeftSquareBracket, p.parseIndexExpr)
+	p.registerInfix(token.OptionalIndex, p.parseIndexExpr)
+	p.registerInfix(token.OptionalDot, p.parseOptionalDotExpr)
+	p.registerInfix(token.Dot, p.parseDotExpr)
+	p.registerInfix(token.LeftCurlyBracket, p.parseStructLiteral)
 	return p
 }
 
@@ -106,6 +127,10 @@ func (p *Parser) Errors() []string {
 	return p.errors
 }
 
+func (p *Parser) EnableStrictNull() {
+	p.strictNull = true
+}
+
 func (p *Parser) registerPrefix(tokenType token.TokenType, fn prefixParseFn) {
 	p.prefixParseFns[tokenType] = fn
 }
@@ -170,6 +195,10 @@ func (p *Parser) ParseProgram() *ast.Program {
 	program := &ast.Program{}
 	program.Stmts = make([]ast.Stmt, 0)
 
+	if p.currTokenIs(token.String) && p.currToken.Literal == StrictNullPragma {
+		p.strictNull = true
+	}
+
 	for !p.currTokenIs(token.EOF) {
 		stmt := p.parseStatement()
 		if stmt != nil {
@@ -197,6 +226,18 @@ func (p *Parser) parseStatement() ast.Stmt {
 		}
 	case token.For:
 		return p.parseForStmt()
+	case token.Throw:
+		return p.parseThrowStmt()
+	case token.Try:
+		return p.parseTryStmt()
+	case token.Import:
+		return p.parseImportStmt()
+	case token.Export:
+		return p.parseExportStmt()
+	case token.Struct:
+		return p.parseStructStmt()
+	case token.Enum:
+		return p.parseEnumStmt()
 	default:
 		return p.parseExpressionStmt()
 	}
@@ -216,12 +257,35 @@ func (p *Parser) parseVarDeclarationStmt() *ast.VarDeclarationStmt {
 
 	stmt.Name = &ast.Identifier{Token: p.currToken, Value: p.currToken.Literal}
 
+	if p.peekTokenIs(token.Identifier) {
+		p.nextToken() // advance to type name
+		stmt.Type = &ast.Identifier{Token: p.currToken, Value: p.currToken.Literal}
+
+		if _, ok := zeroValues[stmt.Type.Value]; !ok {
+			p.errors = append(p.errors, fmt.Sprintf("Honk! unknown type %s on line %d", stmt.Type.Value, p.currToken.Line))
+			return nil
+		}
+	}
+
 	if p.peekTokenIs(token.Semicolon) {
 		if isConst {
 			p.errors = append(p.errors, fmt.Sprintf("Honk! const variable must be initialized on line %d", p.currToken.Line))
 			return nil
 		} else {
 			p.nextToken() // advance past semi
+
+			if stmt.Type != nil {
+				stmt.Value = zeroValues[stmt.Type.Value](p.currToken.Line)
+				return stmt
+			}
+
+			if p.strictNull {
+				msg := fmt.Sprintf("Honk! variable %s must be initialized or declare a type in strict-null mode on line %d",
+					stmt.Name.Value, p.currToken.Line)
+				p.errors = append(p.errors, msg)
+				return nil
+			}
+
 			// I am unsure about creating this token here, but it's not being added to the list of tokens, so it should
 			// be fine
 			stmt.Value = &ast.NullLiteral{Token: token.Token{Literal: "null", Type: token.Null, Line: p.currToken.Line}}
@@ -244,6 +308,16 @@ func (p *Parser) parseVarDeclarationStmt() *ast.VarDeclarationStmt {
 		p.nextToken()
 	}
 
+	// the type is only checked against a literal initializer, any other expression is left to run time
+	if stmt.Type != nil {
+		if literal, ok := literalType(stmt.Value); ok && literal != stmt.Type.Value {
+			msg := fmt.Sprintf("Honk! %s is declared %s but initialized with %s on line %d",
+				stmt.Name.Value, stmt.Type.Value, literal, stmt.Token.Line)
+			p.errors = append(p.errors, msg)
+			return nil
+		}
+	}
+
 	return stmt
 }
 
@@ -261,11 +335,15 @@ func (p *Parser) parseReturnStmt() *ast.ReturnStmt {
 	return stmt
 }
 
-func (p *Parser) parseExpressionStmt() *ast.ExpressionStmt {
+func (p *Parser) parseExpressionStmt() ast.Stmt {
 	stmt := &ast.ExpressionStmt{Token: p.currToken}
 
 	stmt.Expr = p.parseExpression(LOWEST)
 
+	if field, ok := stmt.Expr.(*ast.IndexExpr); ok && field.Token.Type == token.Dot && p.peekTokenIs(token.Assign) {
+		return p.parseFieldAssignmentStmt(field)
+	}
+
 	if p.peekTokenIs(token.Semicolon) {
 		p.nextToken()
 	}
@@ -288,6 +366,26 @@ func (p *Parser) parseBlockStmt() *ast.BlockStmt {
 	return block
 }
 
+// parseFieldAssignmentStmt continues a.b at the = of a.b = value
+func (p *Parser) parseFieldAssignmentStmt(field *ast.IndexExpr) ast.Stmt {
+	name := field.Index.(*ast.StringLiteral)
+	stmt := &ast.FieldAssignmentStmt{
+		Token:  field.Token,
+		Object: field.Left,
+		Field:  &ast.Identifier{Token: name.Token, Value: name.Value},
+	}
+
+	p.nextToken() // advance to =
+	p.nextToken() // advance past =
+	stmt.Value = p.parseExpression(LOWEST)
+
+	if p.peekTokenIs(token.Semicolon) {
+		p.nextToken()
+	}
+
+	return stmt
+}
+
 func (p *Parser) parseAssignmentStmt() ast.Stmt {
 
 	ident := &ast.Identifier{Token: p.currToken, Value: p.currToken.Literal}
@@ -417,19 +515,315 @@ func (p *Parser) parseIfExpr() ast.Expr {
 
 	expr.Consequence = p.parseBlockStmt()
 
-	// For else if, probably want to set the alternative to another ast.IfExpr, maybe by calling this method recursively
-	if p.peekTokenIs(token.Else) {
-		p.nextToken() // advance past else
+	for p.peekTokenIs(token.Else) || p.peekTokenIs(token.Elseif) {
+		p.nextToken() // advance to else or elseif
+
+		if p.currTokenIs(token.Elseif) || p.peekTokenIs(token.If) {
+			if p.currTokenIs(token.Else) {
+				p.nextToken() // advance to if
+			}
+
+			branch := p.parseElseIfBranch()
+			if branch == nil {
+				return nil
+			}
+			expr.ElseIfs = append(expr.ElseIfs, branch)
+			continue
+		}
+
 		if !p.expectPeek(token.LeftCurlyBracket) {
 			return nil
 		}
 
+		// a plain else ends the chain
 		expr.Alternative = p.parseBlockStmt()
+		break
+	}
+
+	return expr
+}
+
+// match (subject) { pattern => body, pattern if guard => { block }, ... }. A body that starts with { is a block, so a
+// hash literal body has to be put in parentheses
+func (p *Parser) parseMatchExpr() ast.Expr {
+	expr := &ast.MatchExpr{Token: p.currToken}
+
+	if !p.expectPeek(token.LeftParen) {
+		return nil
+	}
+	p.nextToken() // advance past (
+	expr.Subject = p.parseExpression(LOWEST)
+
+	if !p.expectPeek(token.RightParen) {
+		return nil
+	}
+	if !p.expectPeek(token.LeftCurlyBracket) {
+		return nil
 	}
 
+	errorCount := len(p.errors)
+	for !p.peekTokenIs(token.RightCurlyBracket) {
+		p.nextToken() // advance to the pattern
+		arm := p.parseMatchArm()
+		if arm == nil {
+			return nil
+		}
+		expr.Arms = append(expr.Arms, arm)
+
+		// arms are separated by commas, which an arm with a block body can leave out
+		block := arm.Body.Token.Type == token.LeftCurlyBracket
+		if p.peekTokenIs(token.Comma) {
+			p.nextToken()
+		} else if !p.peekTokenIs(token.RightCurlyBracket) && !block {
+			p.peekError(token.Comma)
+			return nil
+		}
+	}
+	p.nextToken() // advance to }
+
+	if len(p.errors) > errorCount {
+		return nil
+	}
 	return expr
 }
 
+func (p *Parser) parseMatchArm() *ast.MatchArm {
+	arm := &ast.MatchArm{Token: p.currToken}
+
+	arm.Pattern = p.parsePattern()
+	ok := arm.Pattern != nil && p.checkBindings(arm.Pattern, make(map[string]bool))
+
+	if ok && p.peekTokenIs(token.If) {
+		p.nextToken() // advance to if
+		p.nextToken() // advance past if
+		arm.Guard = p.parseExpression(LOWEST)
+	}
+
+	if ok && !p.expectPeek(token.FatArrow) {
+		ok = false
+	}
+	if !ok {
+		// a pattern cannot contain =>, so the arm is parsed on from there and the error is not followed by others
+		// about the rest of the pattern. parseMatchExpr rejects the match
+		for !p.currTokenIs(token.FatArrow) {
+			if p.currTokenIs(token.EOF) {
+				return nil
+			}
+			p.nextToken()
+		}
+	}
+	p.nextToken() // advance past =>
+
+	if p.currTokenIs(token.LeftCurlyBracket) {
+		arm.Body = p.parseBlockStmt()
+		return arm
+	}
+
+	body := &ast.ExpressionStmt{Token: p.currToken, Expr: p.parseExpression(LOWEST)}
+	arm.Body = &ast.BlockStmt{Token: body.Token, Stmts: []ast.Stmt{body}}
+	return arm
+}
+
+// parsePattern parses the pattern that starts at currToken and leaves currToken on its last token
+func (p *Parser) parsePattern() ast.Pattern {
+	switch p.currToken.Type {
+	case token.Integer, token.Float, token.String, token.True, token.False, token.Null:
+		value := p.prefixParseFns[p.currToken.Type]()
+		if value == nil {
+			return nil
+		}
+		return &ast.LiteralPattern{Token: p.currToken, Value: value}
+	case token.Minus:
+		return p.parseNegativePattern()
+	case token.Identifier:
+		if p.peekTokenIs(token.Dot) {
+			return p.parseDottedPattern()
+		}
+		if p.currToken.Literal == "_" {
+			return &ast.WildcardPattern{Token: p.currToken}
+		}
+		return &ast.BindingPattern{Token: p.currToken, Name: &ast.Identifier{Token: p.currToken, Value: p.currToken.Literal}}
+	case token.LeftSquareBracket:
+		pattern := &ast.ArrayPattern{Token: p.currToken}
+		pattern.Elements = p.parsePatternList(token.RightSquareBracket)
+		if pattern.Elements == nil {
+			return nil
+		}
+		return pattern
+	case token.LeftCurlyBracket:
+		return p.parseHashPattern()
+	default:
+		msg := fmt.Sprintf("Honk! %s cannot start a pattern on line %d", p.currToken.Literal, p.currToken.Line)
+		p.errors = append(p.errors, msg)
+		return nil
+	}
+}
+
+// checkBindings rejects a pattern that binds the same name twice, seen holds the names bound so far
+func (p *Parser) checkBindings(pattern ast.Pattern, seen map[string]bool) bool {
+	var nested []ast.Pattern
+
+	switch pattern := pattern.(type) {
+	case *ast.BindingPattern:
+		if seen[pattern.Name.Value] {
+			msg := fmt.Sprintf("Honk! %s is bound twice in one pattern on line %d", pattern.Name.Value, pattern.Token.Line)
+			p.errors = append(p.errors, msg)
+			return false
+		}
+		seen[pattern.Name.Value] = true
+	case *ast.ArrayPattern:
+		nested = pattern.Elements
+	case *ast.HashPattern:
+		nested = pattern.Values
+	case *ast.VariantPattern:
+		nested = pattern.Fields
+	}
+
+	for _, n := range nested {
+		if !p.checkBindings(n, seen) {
+			return false
+		}
+	}
+	return true
+}
+
+// -1 and -1.5 are literal patterns of their own, there are no other expressions in patterns to negate
+func (p *Parser) parseNegativePattern() ast.Pattern {
+	minus := p.currToken
+
+	p.nextToken() // advance past -
+	switch p.currToken.Type {
+	case token.Integer:
+		p.currToken.Literal = "-" + p.currToken.Literal
+		value := p.parseIntegerLiteral()
+		if value == nil {
+			return nil
+		}
+		return &ast.LiteralPattern{Token: minus, Value: value}
+	case token.Float:
+		p.currToken.Literal = "-" + p.currToken.Literal
+		value := p.parseFloatLiteral()
+		if value == nil {
+			return nil
+		}
+		return &ast.LiteralPattern{Token: minus, Value: value}
+	default:
+		msg := fmt.Sprintf("Honk! - must be followed by a number in a pattern on line %d", p.currToken.Line)
+		p.errors = append(p.errors, msg)
+		return nil
+	}
+}
+
+// Color.Red matches a value equal to it, Result.Ok(v) matches the variants built by Result.Ok
+func (p *Parser) parseDottedPattern() ast.Pattern {
+	start := p.currToken
+	var value ast.Expr = &ast.Identifier{Token: p.currToken, Value: p.currToken.Literal}
+
+	for p.peekTokenIs(token.Dot) {
+		p.nextToken() // advance to .
+		value = p.parseDotExpr(value)
+		if value == nil {
+			return nil
+		}
+	}
+
+	if !p.peekTokenIs(token.LeftParen) {
+		return &ast.ValuePattern{Token: start, Value: value}
+	}
+
+	p.nextToken() // advance to (
+	pattern := &ast.VariantPattern{Token: start, Variant: value}
+	pattern.Fields = p.parsePatternList(token.RightParen)
+	if pattern.Fields == nil {
+		return nil
+	}
+	return pattern
+}
+
+// parsePatternList parses the patterns after the opening token at currToken up to end, an empty list is not nil
+func (p *Parser) parsePatternList(end token.TokenType) []ast.Pattern {
+	list := []ast.Pattern{}
+
+	for !p.peekTokenIs(end) {
+		p.nextToken() // advance to the pattern
+		pattern := p.parsePattern()
+		if pattern == nil {
+			return nil
+		}
+		list = append(list, pattern)
+
+		if !p.peekTokenIs(end) && !p.expectPeek(token.Comma) {
+			return nil
+		}
+	}
+	p.nextToken() // advance to end
+
+	return list
+}
+
+// {"k": v} matches a hash with the key k, the keys are literals
+func (p *Parser) parseHashPattern() ast.Pattern {
+	pattern := &ast.HashPattern{Token: p.currToken}
+
+	for !p.peekTokenIs(token.RightCurlyBracket) {
+		p.nextToken() // advance to the key
+
+		var key ast.Expr
+		switch p.currToken.Type {
+		case token.String, token.Integer, token.True, token.False:
+			key = p.prefixParseFns[p.currToken.Type]()
+		default:
+			msg := fmt.Sprintf("Honk! the keys of a hash pattern must be literals, got %s on line %d", p.currToken.Literal, p.currToken.Line)
+			p.errors = append(p.errors, msg)
+			return nil
+		}
+		if key == nil {
+			return nil
+		}
+
+		if !p.expectPeek(token.Colon) {
+			return nil
+		}
+		p.nextToken() // advance past :
+
+		value := p.parsePattern()
+		if value == nil {
+			return nil
+		}
+		pattern.Keys = append(pattern.Keys, key)
+		pattern.Values = append(pattern.Values, value)
+
+		if !p.peekTokenIs(token.RightCurlyBracket) && !p.expectPeek(token.Comma) {
+			return nil
+		}
+	}
+	p.nextToken() // advance to }
+
+	return pattern
+}
+
+// currToken is the if of `else if` or the elseif keyword
+func (p *Parser) parseElseIfBranch() *ast.ElseIfBranch {
+	branch := &ast.ElseIfBranch{Token: p.currToken}
+
+	if !p.expectPeek(token.LeftParen) {
+		return nil
+	}
+	p.nextToken() // advance past (
+	branch.Condition = p.parseExpression(LOWEST)
+
+	if !p.expectPeek(token.RightParen) {
+		return nil
+	}
+	if !p.expectPeek(token.LeftCurlyBracket) {
+		return nil
+	}
+
+	branch.Consequence = p.parseBlockStmt()
+
+	return branch
+}
+
 func (p *Parser) parseFunctionLiteral() ast.Expr {
 	function := &ast.FunctionLiteral{Token: p.currToken}
 
@@ -477,6 +871,19 @@ func (p *Parser) parseFunctionParameters() []*ast.Identifier {
 }
 
 func (p *Parser) parseCallExpr(function ast.Expr) ast.Expr {
+	// a.f(args) is a method call rather than a call of a["f"], and so is a?.f(args)
+	if member, ok := function.(*ast.IndexExpr); ok && (member.Token.Type == token.Dot || member.Token.Type == token.OptionalDot) {
+		name := member.Index.(*ast.StringLiteral)
+		expr := &ast.MethodCallExpr{
+			Token:    member.Token,
+			Receiver: member.Left,
+			Method:   &ast.Identifier{Token: name.Token, Value: name.Value},
+			Optional: member.Optional,
+		}
+		expr.Arguments = p.parseExpressionList(token.RightParen)
+		return expr
+	}
+
 	expr := &ast.CallExpr{Token: p.currToken, Function: function}
 	expr.Arguments = p.parseExpressionList(token.RightParen)
 	return expr
@@ -542,13 +949,42 @@ func (p *Parser) parseExpressionList(end token.TokenType) []ast.Expr {
 	return list
 }
 
+// parses left[index], and the slice left[start:end] where either bound can be left out
 func (p *Parser) parseIndexExpr(left ast.Expr) ast.Expr {
-	expr := &ast.IndexExpr{Token: p.currToken, Left: left}
+	expr := &ast.IndexExpr{Token: p.currToken, Left: left, Optional: p.currTokenIs(token.OptionalIndex), Strict: p.strictNull}
 
 	p.nextToken() // advance past [
 
+	if p.currTokenIs(token.Colon) {
+		return p.parseSliceExpr(expr, nil)
+	}
+
 	expr.Index = p.parseExpression(LOWEST)
 
+	if p.peekTokenIs(token.Colon) {
+		p.nextToken()
+		return p.parseSliceExpr(expr, expr.Index)
+	}
+
+	if !p.expectPeek(token.RightSquareBracket) {
+		return nil
+	}
+
+	return expr
+}
+
+// parseSliceExpr continues an index expression at the colon of a slice
+func (p *Parser) parseSliceExpr(index *ast.IndexExpr, start ast.Expr) ast.Expr {
+	expr := &ast.SliceExpr{Token: index.Token, Left: index.Left, Start: start, Optional: index.Optional}
+
+	if p.peekTokenIs(token.RightSquareBracket) {
+		p.nextToken()
+		return expr
+	}
+
+	p.nextToken() // advance past :
+	expr.End = p.parseExpression(LOWEST)
+
 	if !p.expectPeek(token.RightSquareBracket) {
 		return nil
 	}
@@ -556,7 +992,38 @@ func (p *Parser) parseIndexExpr(left ast.Expr) ast.Expr {
 	return expr
 }
 
+// a.b is shorthand for a["b"], it is how the exports of a module are used
+func (p *Parser) parseDotExpr(left ast.Expr) ast.Expr {
+	expr := &ast.IndexExpr{Token: p.currToken, Left: left, Strict: p.strictNull}
+
+	if !p.expectPeek(token.Identifier) {
+		return nil
+	}
+
+	expr.Index = &ast.StringLiteral{Token: p.currToken, Value: p.currToken.Literal}
+
+	return expr
+}
+
+// a?.b is shorthand for a?["b"]
+func (p *Parser) parseOptionalDotExpr(left ast.Expr) ast.Expr {
+	expr := &ast.IndexExpr{Token: p.currToken, Left: left, Optional: true, Strict: p.strictNull}
+
+	if !p.expectPeek(token.Identifier) {
+		return nil
+	}
+
+	expr.Index = &ast.StringLiteral{Token: p.currToken, Value: p.currToken.Literal}
+
+	return expr
+}
+
 func (p *Parser) parseNullLiteral() ast.Expr {
+	if p.strictNull {
+		p.errors = append(p.errors, fmt.Sprintf("Honk! null is not allowed in strict-null mode on line %d", p.currToken.Line))
+		return nil
+	}
+
 	return &ast.NullLiteral{Token: p.currToken}
 }
 
@@ -581,6 +1048,341 @@ func (p *Parser) parseForStmt() *ast.ForStmt {
 	return forStmt
 }
 
+func (p *Parser) parseThrowStmt() *ast.ThrowStmt {
+	stmt := &ast.ThrowStmt{Token: p.currToken}
+
+	p.nextToken() // advance past throw
+
+	stmt.Value = p.parseExpression(LOWEST)
+
+	if p.peekTokenIs(token.Semicolon) {
+		p.nextToken()
+	}
+
+	return stmt
+}
+
+// parses import "path" as name, import "path" which is named after the file, and import { a, b } from "path".
+// as and from are only keywords here
+func (p *Parser) parseImportStmt() *ast.ImportStmt {
+	stmt := &ast.ImportStmt{Token: p.currToken}
+
+	if p.peekTokenIs(token.LeftCurlyBracket) {
+		p.nextToken()
+		stmt.Names = []*ast.Identifier{}
+
+		for !p.peekTokenIs(token.RightCurlyBracket) {
+			if !p.expectPeek(token.Identifier) {
+				return nil
+			}
+			stmt.Names = append(stmt.Names, &ast.Identifier{Token: p.currToken, Value: p.currToken.Literal})
+
+			if !p.peekTokenIs(token.RightCurlyBracket) && !p.expectPeek(token.Comma) {
+				return nil
+			}
+		}
+		p.nextToken() // advance to }
+
+		if !p.expectPeek(token.Identifier) || p.currToken.Literal != "from" {
+			p.errors = append(p.errors, fmt.Sprintf("Honk! expected from after the imported names on line %d", p.currToken.Line))
+			return nil
+		}
+	}
+
+	if !p.expectPeek(token.String) {
+		return nil
+	}
+	stmt.Path = p.currToken.Literal
+
+	if stmt.Names == nil {
+		if p.peekTokenIs(token.Identifier) && p.peekToken.Literal == "as" {
+			p.nextToken()
+			if !p.expectPeek(token.Identifier) {
+				return nil
+			}
+			stmt.Alias = &ast.Identifier{Token: p.currToken, Value: p.currToken.Literal}
+		} else {
+			// a std module is named after its file as well, std:list is list
+			file := filepath.Base(strings.TrimPrefix(stmt.Path, "std:"))
+			name := strings.TrimSuffix(file, filepath.Ext(file))
+			if !isIdentifier(name) {
+				p.errors = append(p.errors, fmt.Sprintf("Honk! cannot name module %s after its file, import it with as on line %d", stmt.Path, p.currToken.Line))
+				return nil
+			}
+			stmt.Alias = &ast.Identifier{Token: p.currToken, Value: name}
+		}
+	}
+
+	if p.peekTokenIs(token.Semicolon) {
+		p.nextToken()
+	}
+
+	return stmt
+}
+
+// isIdentifier reports whether name lexes as a single identifier, which is not a keyword
+func isIdentifier(name string) bool {
+	l := lexer.New(name)
+	tok := l.NextToken()
+	return tok.Type == token.Identifier && tok.Literal == name
+}
+
+func (p *Parser) parseExportStmt() *ast.ExportStmt {
+	stmt := &ast.ExportStmt{Token: p.currToken}
+
+	switch {
+	case p.peekTokenIs(token.Const):
+		p.nextToken()
+		stmt.Declaration = p.parseVarDeclarationStmt()
+	case p.peekTokenIs(token.Mut):
+		// importers get the values exports have once the module has run, a mut would not stay up to date
+		p.nextToken()
+		decl := p.parseVarDeclarationStmt()
+		if decl != nil {
+			msg := fmt.Sprintf("Honk! cannot export mut %s, only constants can be exported on line %d", decl.Name.Value, stmt.Token.Line)
+			p.errors = append(p.errors, msg)
+		}
+		return nil
+	case p.peekTokenIs(token.Struct):
+		p.nextToken()
+		stmt.Declaration = p.parseStructStmt()
+	case p.peekTokenIs(token.Enum):
+		p.nextToken()
+		stmt.Declaration = p.parseEnumStmt()
+	default:
+		msg := fmt.Sprintf("Honk! export must be followed by a const, struct or enum declaration on line %d", p.currToken.Line)
+		p.errors = append(p.errors, msg)
+		return nil
+	}
+
+	if stmt.Declaration == nil {
+		return nil
+	}
+
+	return stmt
+}
+
+// struct Point { x, y } declares the constant Point, a struct type with the fields x and y
+func (p *Parser) parseStructStmt() *ast.VarDeclarationStmt {
+	def := &ast.StructDefinition{Token: p.currToken}
+
+	if !p.expectPeek(token.Identifier) {
+		return nil
+	}
+	name := &ast.Identifier{Token: p.currToken, Value: p.currToken.Literal}
+	def.Name = name.Value
+
+	if !p.expectPeek(token.LeftCurlyBracket) {
+		return nil
+	}
+
+	// a duplicate is reported once the fields are parsed, so that parsing carries on after the }
+	errorCount := len(p.errors)
+	seen := make(map[string]bool)
+	for !p.peekTokenIs(token.RightCurlyBracket) {
+		if !p.expectPeek(token.Identifier) {
+			return nil
+		}
+
+		field := &ast.Identifier{Token: p.currToken, Value: p.currToken.Literal}
+		if seen[field.Value] {
+			msg := fmt.Sprintf("Honk! duplicate field %s in struct %s on line %d", field.Value, def.Name, p.currToken.Line)
+			p.errors = append(p.errors, msg)
+		}
+		seen[field.Value] = true
+		def.Fields = append(def.Fields, field)
+
+		if !p.peekTokenIs(token.RightCurlyBracket) && !p.expectPeek(token.Comma) {
+			return nil
+		}
+	}
+	p.nextToken() // advance to }
+
+	if p.peekTokenIs(token.Semicolon) {
+		p.nextToken()
+	}
+	if len(p.errors) > errorCount {
+		return nil
+	}
+
+	declaration := token.Token{Type: token.Const, Literal: "const", Line: def.Token.Line}
+	return &ast.VarDeclarationStmt{Token: declaration, Name: name, Value: def, Constant: true}
+}
+
+// enum Result { Ok(value), Err(msg) } declares the constant Result, an enum type with the variants Result.Ok and
+// Result.Err. A variant without parentheses carries no payload
+func (p *Parser) parseEnumStmt() *ast.VarDeclarationStmt {
+	def := &ast.EnumDefinition{Token: p.currToken}
+
+	if !p.expectPeek(token.Identifier) {
+		return nil
+	}
+	name := &ast.Identifier{Token: p.currToken, Value: p.currToken.Literal}
+	def.Name = name.Value
+
+	if !p.expectPeek(token.LeftCurlyBracket) {
+		return nil
+	}
+
+	// duplicates are reported without stopping, like in structs
+	errorCount := len(p.errors)
+	seen := make(map[string]bool)
+	for !p.peekTokenIs(token.RightCurlyBracket) {
+		if !p.expectPeek(token.Identifier) {
+			return nil
+		}
+
+		variant := &ast.EnumVariant{Name: &ast.Identifier{Token: p.currToken, Value: p.currToken.Literal}}
+		if seen[variant.Name.Value] {
+			msg := fmt.Sprintf("Honk! duplicate variant %s in enum %s on line %d", variant.Name.Value, def.Name, p.currToken.Line)
+			p.errors = append(p.errors, msg)
+		}
+		seen[variant.Name.Value] = true
+
+		if p.peekTokenIs(token.LeftParen) {
+			p.nextToken() // advance to (
+			if !p.parseVariantFields(def, variant) {
+				return nil
+			}
+		}
+		def.Variants = append(def.Variants, variant)
+
+		if !p.peekTokenIs(token.RightCurlyBracket) && !p.expectPeek(token.Comma) {
+			return nil
+		}
+	}
+	p.nextToken() // advance to }
+
+	if p.peekTokenIs(token.Semicolon) {
+		p.nextToken()
+	}
+	if len(p.errors) > errorCount {
+		return nil
+	}
+
+	declaration := token.Token{Type: token.Const, Literal: "const", Line: def.Token.Line}
+	return &ast.VarDeclarationStmt{Token: declaration, Name: name, Value: def, Constant: true}
+}
+
+// parseVariantFields parses the payload fields of a variant, from its ( to its ). It only fails on a token that
+// cannot be part of the fields, a duplicate field is reported and left for parseEnumStmt to reject
+func (p *Parser) parseVariantFields(def *ast.EnumDefinition, variant *ast.EnumVariant) bool {
+	seen := make(map[string]bool)
+	for !p.peekTokenIs(token.RightParen) {
+		if !p.expectPeek(token.Identifier) {
+			return false
+		}
+
+		field := &ast.Identifier{Token: p.currToken, Value: p.currToken.Literal}
+		if seen[field.Value] {
+			msg := fmt.Sprintf("Honk! duplicate field %s in variant %s.%s on line %d", field.Value, def.Name, variant.Name.Value, p.currToken.Line)
+			p.errors = append(p.errors, msg)
+		}
+		seen[field.Value] = true
+		variant.Fields = append(variant.Fields, field)
+
+		if !p.peekTokenIs(token.RightParen) && !p.expectPeek(token.Comma) {
+			return false
+		}
+	}
+	p.nextToken() // advance to )
+
+	return true
+}
+
+// parseStructLiteral continues the name of a struct type at the { of Point{x: 1, y: 2}
+func (p *Parser) parseStructLiteral(left ast.Expr) ast.Expr {
+	lit := &ast.StructLiteral{Token: p.currToken, Type: left}
+
+	if index, ok := left.(*ast.IndexExpr); !ok || index.Token.Type != token.Dot {
+		if _, ok := left.(*ast.Identifier); !ok {
+			msg := fmt.Sprintf("Honk! %s is not the name of a struct type on line %d", left.String(), p.currToken.Line)
+			p.errors = append(p.errors, msg)
+			return nil
+		}
+	}
+
+	errorCount := len(p.errors)
+	seen := make(map[string]bool)
+	for !p.peekTokenIs(token.RightCurlyBracket) {
+		if !p.expectPeek(token.Identifier) {
+			return nil
+		}
+
+		field := &ast.Identifier{Token: p.currToken, Value: p.currToken.Literal}
+		if seen[field.Value] {
+			// reported without stopping, so the rest of the literal is still parsed
+			p.errors = append(p.errors, fmt.Sprintf("Honk! field %s is set twice on line %d", field.Value, p.currToken.Line))
+		}
+		seen[field.Value] = true
+
+		if !p.expectPeek(token.Colon) {
+			return nil
+		}
+		p.nextToken() // advance past :
+
+		lit.Fields = append(lit.Fields, field)
+		lit.Values = append(lit.Values, p.parseExpression(LOWEST))
+
+		if !p.peekTokenIs(token.RightCurlyBracket) && !p.expectPeek(token.Comma) {
+			return nil
+		}
+	}
+
+	if !p.expectPeek(token.RightCurlyBracket) || len(p.errors) > errorCount {
+		return nil
+	}
+
+	return lit
+}
+
+func (p *Parser) parseTryStmt() *ast.TryStmt {
+	stmt := &ast.TryStmt{Token: p.currToken}
+
+	if !p.expectPeek(token.LeftCurlyBracket) {
+		return nil
+	}
+
+	stmt.Body = p.parseBlockStmt()
+
+	if p.peekTokenIs(token.Catch) {
+		p.nextToken() // advance to catch
+		if !p.expectPeek(token.LeftParen) {
+			return nil
+		}
+		if !p.expectPeek(token.Identifier) {
+			return nil
+		}
+
+		stmt.Param = &ast.Identifier{Token: p.currToken, Value: p.currToken.Literal}
+
+		if !p.expectPeek(token.RightParen) {
+			return nil
+		}
+		if !p.expectPeek(token.LeftCurlyBracket) {
+			return nil
+		}
+
+		stmt.Catch = p.parseBlockStmt()
+	}
+
+	if p.peekTokenIs(token.Finally) {
+		p.nextToken() // advance to finally
+		if !p.expectPeek(token.LeftCurlyBracket) {
+			return nil
+		}
+
+		stmt.Finally = p.parseBlockStmt()
+	}
+
+	if stmt.Catch == nil && stmt.Finally == nil {
+		p.errors = append(p.errors, fmt.Sprintf("Honk! try must be followed by catch or finally on line %d", stmt.Token.Line))
+		return nil
+	}
+
+	return stmt
+}
+
 func (p *Parser) parseHashLiteral() ast.Expr {
 	hash := &ast.HashLiteral{Token: p.currToken}
 	hash.Pairs = make(map[ast.Expr]ast.Expr)
@@ -641,3 +1443,52 @@ func (p *Parser) parseMacroLiteral() ast.Expr {
 
 	return macro
 }
+
+// literalType is the type name of a literal, as used in typed declarations
+func literalType(expr ast.Expr) (string, bool) {
+	switch expr := expr.(type) {
+	case *ast.IntegerLiteral:
+		return "int", true
+	case *ast.FloatLiteral:
+		return "float", true
+	case *ast.StringLiteral:
+		return "string", true
+	case *ast.BooleanLiteral:
+		return "bool", true
+	case *ast.ArrayLiteral:
+		return "array", true
+	case *ast.HashLiteral:
+		return "hash", true
+	case *ast.NullLiteral:
+		return "null", true
+	case *ast.FunctionLiteral:
+		return "function", true
+	case *ast.PrefixExpr:
+		if expr.Operator == "-" {
+			return literalType(expr.Right)
+		}
+	}
+	return "", false
+}
+
+// zeroValues builds the literal a typed variable declaration without a value is initialized with
+var zeroValues = map[string]func(line int) ast.Expr{
+	"int": func(line int) ast.Expr {
+		return &ast.IntegerLiteral{Token: token.Token{Literal: "0", Type: token.Integer, Line: line}, Value: 0}
+	},
+	"float": func(line int) ast.Expr {
+		return &ast.FloatLiteral{Token: token.Token{Literal: "0.0", Type: token.Float, Line: line}, Value: 0}
+	},
+	"string": func(line int) ast.Expr {
+		return &ast.StringLiteral{Token: token.Token{Literal: "", Type: token.String, Line: line}, Value: ""}
+	},
+	"bool": func(line int) ast.Expr {
+		return &ast.BooleanLiteral{Token: token.Token{Literal: "false", Type: token.False, Line: line}, Value: false}
+	},
+	"array": func(line int) ast.Expr {
+		return &ast.ArrayLiteral{Token: token.Token{Literal: "[", Type: token.LeftSquareBracket, Line: line}, Elements: []ast.Expr{}}
+	},
+	"hash": func(line int) ast.Expr {
+		return &ast.HashLiteral{Token: token.Token{Literal: "{", Type: token.LeftCurlyBracket, Line: line}, Pairs: map[ast.Expr]ast.Expr{}}
+	},
+}
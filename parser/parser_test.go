@@ -52,6 +52,109 @@ func TestVarDeclarationStmts(t *testing.T) {
 	}
 }
 
+func TestTypedVarDeclarationStmts(t *testing.T) {
+	tests := []struct {
+		source   string
+		expected string
+	}{
+		{"mut count int;", "mut count int = 0;"},
+		{"mut ratio float;", "mut ratio float = 0.0;"},
+		{"mut name string;", "mut name string = ;"},
+		{"mut done bool;", "mut done bool = false;"},
+		{"mut items array;", "mut items array = [];"},
+		{"mut lookup hash;", "mut lookup hash = {};"},
+		{"mut count int = 5;", "mut count int = 5;"},
+		{"mut count int = -5;", "mut count int = (-5);"},
+		{"mut count int = len(xs);", "mut count int = len(xs);"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.source)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		if len(program.Stmts) != 1 {
+			t.Fatalf("program.Stmts does not contain 1 statement. got=%d", len(program.Stmts))
+		}
+
+		if program.String() != tt.expected {
+			t.Errorf("wrong declaration. want=%q, got=%q", tt.expected, program.String())
+		}
+	}
+}
+
+func TestTypedVarDeclarationErrors(t *testing.T) {
+	tests := []struct {
+		source   string
+		expected string
+	}{
+		{`mut x int = "s";`, "Honk! x is declared int but initialized with string on line 1"},
+		{"mut x float = 1;", "Honk! x is declared float but initialized with int on line 1"},
+		{"mut x int = -1.5;", "Honk! x is declared int but initialized with float on line 1"},
+		{"mut x array = null;", "Honk! x is declared array but initialized with null on line 1"},
+		{"const f bool = func() { true };", "Honk! f is declared bool but initialized with function on line 1"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.source)
+		p := New(l)
+		p.ParseProgram()
+
+		errors := p.Errors()
+		if len(errors) != 1 {
+			t.Fatalf("expected one parser error for %q. got=%v", tt.source, errors)
+		}
+
+		if errors[0] != tt.expected {
+			t.Errorf("wrong error. want=%q, got=%q", tt.expected, errors[0])
+		}
+	}
+}
+
+func TestStrictNullErrors(t *testing.T) {
+	tests := []struct {
+		source   string
+		expected string
+	}{
+		{
+			"mut x;",
+			"Honk! variable x must be initialized or declare a type in strict-null mode on line 1",
+		},
+		{
+			"mut x = null;",
+			"Honk! null is not allowed in strict-null mode on line 1",
+		},
+		{
+			"mut x number;",
+			"Honk! unknown type number on line 1",
+		},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.source)
+		p := New(l)
+		p.EnableStrictNull()
+		p.ParseProgram()
+
+		errors := p.Errors()
+		if len(errors) == 0 {
+			t.Fatalf("expected parser error for %q", tt.source)
+		}
+
+		if errors[0] != tt.expected {
+			t.Errorf("wrong error. want=%q, got=%q", tt.expected, errors[0])
+		}
+	}
+
+	l := lexer.New(`"use strict-null"; mut x;`)
+	p := New(l)
+	p.ParseProgram()
+	if len(p.Errors()) != 1 {
+		t.Errorf("pragma did not enable strict-null mode. errors=%v", p.Errors())
+	}
+}
+
 func TestReturnStatements(t *testing.T) {
 	source := `
 	return 5;
@@ -353,6 +456,18 @@ func TestOperatorPrecedenceParsing(t *testing.T) {
 			"add(a * b[2], b[1], 2 * [1, 2][1])",
 			"add((a * (b[2])), (b[1]), (2 * ([1, 2][1])))",
 		},
+		{
+			"a ?? b || c",
+			"(a ?? (b || c))",
+		},
+		{
+			"a ?? b ?? c",
+			"((a ?? b) ?? c)",
+		},
+		{
+			`a?["b"]?.c ?? d`,
+			"(((a?[b])?[c]) ?? d)",
+		},
 	}
 
 	for _, tt := range tests {
@@ -495,6 +610,59 @@ func TestIfElseExpr(t *testing.T) {
 	}
 }
 
+func TestElseIfExpr(t *testing.T) {
+	tests := []struct {
+		source   string
+		expected string
+	}{
+		{"if (x < y) { x } else if (x > y) { y }", "if(x < y) xelse if(x > y) y"},
+		{"if (x < y) { x } elseif (x > y) { y } else { z }", "if(x < y) xelse if(x > y) yelse z"},
+		{"if (a) { 1 } else if (b) { 2 } elseif (c) { 3 } else { 4 }", "ifa 1else ifb 2else ifc 3else 4"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.source)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		if program.String() != tt.expected {
+			t.Errorf("expected=%q, got=%q", tt.expected, program.String())
+		}
+	}
+
+	source := `if (x < y) { x } else if (x > y) { y } elseif (x == y) { z }`
+	l := lexer.New(source)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	stmt, ok := program.Stmts[0].(*ast.ExpressionStmt)
+	if !ok {
+		t.Fatalf("program.Stmts[0] is not *ast.ExpressionStmt. got=%T", program.Stmts[0])
+	}
+
+	expr, ok := stmt.Expr.(*ast.IfExpr)
+	if !ok {
+		t.Fatalf("stmt.Expr is not *ast.IfExpr. got=%T", stmt.Expr)
+	}
+
+	if len(expr.ElseIfs) != 2 {
+		t.Fatalf("expr.ElseIfs does not contain %d branches. got=%d", 2, len(expr.ElseIfs))
+	}
+
+	if !testInfixExpr(t, expr.ElseIfs[0].Condition, "x", ">", "y") {
+		return
+	}
+	if !testInfixExpr(t, expr.ElseIfs[1].Condition, "x", "==", "y") {
+		return
+	}
+
+	if expr.Alternative != nil {
+		t.Errorf("expr.Alternative was not nil. got=%+v", expr.Alternative)
+	}
+}
+
 func TestFunctionLiteralParsing(t *testing.T) {
 	source := "func(x, y) { x + y; }"
 
@@ -747,6 +915,364 @@ func TestParsingIndexExpr(t *testing.T) {
 	}
 }
 
+func TestParsingOptionalIndexExpr(t *testing.T) {
+	tests := []struct {
+		source string
+		index  string
+	}{
+		{`arr?[1]`, "1"},
+		{`hash?.key`, "key"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.source)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		stmt := program.Stmts[0].(*ast.ExpressionStmt)
+		expr, ok := stmt.Expr.(*ast.IndexExpr)
+		if !ok {
+			t.Fatalf("expr not *ast.IndexExpr. got=%T", stmt.Expr)
+		}
+
+		if !expr.Optional {
+			t.Errorf("expr.Optional is not true")
+		}
+
+		if expr.Index.String() != tt.index {
+			t.Errorf("expr.Index wrong. want=%q, got=%q", tt.index, expr.Index.String())
+		}
+	}
+}
+
+func TestParsingSliceExpr(t *testing.T) {
+	tests := []struct {
+		source   string
+		expected string
+	}{
+		{"arr[1:2]", "(arr[1:2])"},
+		{"arr[:2]", "(arr[:2])"},
+		{"arr[1:]", "(arr[1:])"},
+		{"arr[:]", "(arr[:])"},
+		{"arr[-2:len(arr) - 1]", "(arr[(-2):(len(arr) - 1)])"},
+		{"arr?[1:]", "(arr?[1:])"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.source)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		stmt := program.Stmts[0].(*ast.ExpressionStmt)
+		if _, ok := stmt.Expr.(*ast.SliceExpr); !ok {
+			t.Fatalf("expr not *ast.SliceExpr. got=%T", stmt.Expr)
+		}
+
+		if stmt.Expr.String() != tt.expected {
+			t.Errorf("wrong slice. want=%q, got=%q", tt.expected, stmt.Expr.String())
+		}
+	}
+}
+
+func TestParsingModules(t *testing.T) {
+	tests := []struct {
+		source   string
+		expected string
+	}{
+		{`import "lib/geometry.qk" as geo;`, `import "lib/geometry.qk" as geo;`},
+		{`import "lib/geometry.qk";`, `import "lib/geometry.qk" as geometry;`},
+		{`import "std:list";`, `import "std:list" as list;`},
+		{`import { area, pi } from "geometry.qk";`, `import {area, pi} from "geometry.qk";`},
+		{"export const pi = 3;", "export const pi = 3;"},
+		{"geo.area(r)", "geo.area(r)"},
+		{"a.b.c", "((a[b])[c])"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.source)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		if program.String() != tt.expected {
+			t.Errorf("wrong program. want=%q, got=%q", tt.expected, program.String())
+		}
+	}
+}
+
+func TestModuleErrors(t *testing.T) {
+	tests := []struct {
+		source   string
+		expected string
+	}{
+		{`import "my-lib.qk";`, "Honk! cannot name module my-lib.qk after its file, import it with as on line 1"},
+		{"export func() {};", "Honk! export must be followed by a const, struct or enum declaration on line 1"},
+		{"export mut count = 0;", "Honk! cannot export mut count, only constants can be exported on line 1"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.source)
+		p := New(l)
+		p.ParseProgram()
+
+		errors := p.Errors()
+		if len(errors) == 0 {
+			t.Fatalf("expected parser error for %q", tt.source)
+		}
+
+		if errors[0] != tt.expected {
+			t.Errorf("wrong error. want=%q, got=%q", tt.expected, errors[0])
+		}
+	}
+}
+
+func TestParsingStructs(t *testing.T) {
+	tests := []struct {
+		source   string
+		expected string
+	}{
+		{"struct Point { x, y }", "const Point = struct Point {x, y};"},
+		{"struct Empty {};", "const Empty = struct Empty {};"},
+		{"export struct Point { x, y, }", "export const Point = struct Point {x, y};"},
+		{"Point{x: 1, y: a + b}", "Point{x: 1, y: (a + b)}"},
+		{"Point{}", "Point{}"},
+		{"geo.Point{x: 1}.x", "((geo[Point]){x: 1}[x])"},
+		{"p.x = p.x + 1;", "p.x = ((p[x]) + 1)"},
+		{"a.b.c = 1", "(a[b]).c = 1"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.source)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		if program.String() != tt.expected {
+			t.Errorf("wrong program. want=%q, got=%q", tt.expected, program.String())
+		}
+	}
+
+	errorTests := []struct {
+		source   string
+		expected string
+	}{
+		{"struct Point { x, x }", "Honk! duplicate field x in struct Point on line 1"},
+		{"Point{x: 1, x: 2}", "Honk! field x is set twice on line 1"},
+		{"f(){x: 1}", "Honk! f() is not the name of a struct type on line 1"},
+	}
+
+	for _, tt := range errorTests {
+		l := lexer.New(tt.source)
+		p := New(l)
+		p.ParseProgram()
+
+		errors := p.Errors()
+		if len(errors) == 0 {
+			t.Fatalf("expected parser error for %q", tt.source)
+		}
+
+		if errors[0] != tt.expected {
+			t.Errorf("wrong error. want=%q, got=%q", tt.expected, errors[0])
+		}
+	}
+}
+
+// TestErrorRecovery checks that an error in a declaration, literal or pattern is reported once, without errors about
+// the tokens after it
+func TestErrorRecovery(t *testing.T) {
+	tests := []struct {
+		source   string
+		expected string
+	}{
+		{"struct Point { x, x, y }; 1", "Honk! duplicate field x in struct Point on line 1"},
+		{"Point{x: 1, x: 2}; 1", "Honk! field x is set twice on line 1"},
+		{"enum Color { Red, Red, Green }; 1", "Honk! duplicate variant Red in enum Color on line 1"},
+		{"enum Pair { Of(a, a), Two }; 1", "Honk! duplicate field a in variant Pair.Of on line 1"},
+		{"match (x) { [a, a] => a, _ => 1 }; 1", "Honk! a is bound twice in one pattern on line 1"},
+		{"match (x) { {a: v} => v, _ => 1 }; 1", "Honk! the keys of a hash pattern must be literals, got a on line 1"},
+		{"match (x) { (a) => a }; 1", "Honk! ( cannot start a pattern on line 1"},
+		{"match (x) { a + b => 1 }; 1", "Honk! expected next token to be FatArrow, got Plus instead on line 1"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.source)
+		p := New(l)
+		p.ParseProgram()
+
+		errors := p.Errors()
+		if len(errors) != 1 {
+			t.Fatalf("expected one parser error for %q. got=%v", tt.source, errors)
+		}
+
+		if errors[0] != tt.expected {
+			t.Errorf("wrong error. want=%q, got=%q", tt.expected, errors[0])
+		}
+	}
+}
+
+func TestParsingEnums(t *testing.T) {
+	tests := []struct {
+		source   string
+		expected string
+	}{
+		{"enum Color { Red, Green, Blue }", "const Color = enum Color {Red, Green, Blue};"},
+		{"enum Result { Ok(value), Err(msg), };", "const Result = enum Result {Ok(value), Err(msg)};"},
+		{"export enum Pair { Of(a, b) }", "export const Pair = enum Pair {Of(a, b)};"},
+		{"Color.Red", "(Color[Red])"},
+		{"Result.Ok(1)", "Result.Ok(1)"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.source)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		if program.String() != tt.expected {
+			t.Errorf("wrong program. want=%q, got=%q", tt.expected, program.String())
+		}
+	}
+
+	errorTests := []struct {
+		source   string
+		expected string
+	}{
+		{"enum Color { Red, Red }", "Honk! duplicate variant Red in enum Color on line 1"},
+		{"enum Pair { Of(a, a) }", "Honk! duplicate field a in variant Pair.Of on line 1"},
+		{"enum Color { 1 }", "Honk! expected next token to be Identifier, got Number instead on line 1"},
+	}
+
+	for _, tt := range errorTests {
+		l := lexer.New(tt.source)
+		p := New(l)
+		p.ParseProgram()
+
+		errors := p.Errors()
+		if len(errors) == 0 {
+			t.Fatalf("expected parser error for %q", tt.source)
+		}
+
+		if errors[0] != tt.expected {
+			t.Errorf("wrong error. want=%q, got=%q", tt.expected, errors[0])
+		}
+	}
+}
+
+func TestParsingMatch(t *testing.T) {
+	tests := []struct {
+		source   string
+		expected string
+	}{
+		{`match (x) { 1 => "one", _ => "many" }`, "match x {1 => one, _ => many}"},
+		{"match (x) { -1 => a, -2.5 => b, null => c, true => d, }", "match x {-1 => a, -2.5 => b, null => c, true => d}"},
+		{"match (f(x)) { [a, [b, _]] => a + b }", "match f(x) {[a, [b, _]] => (a + b)}"},
+		{`match (x) { {"k": v, 2: [w]} => v }`, "match x {{k: v, 2: [w]} => v}"},
+		{"match (x) { n if n > 3 => { n * 2 } _ => 0 }", "match x {n if (n > 3) => (n * 2), _ => 0}"},
+		{"match (x) { Color.Red => 1, Result.Ok(v) => v, geo.Shape.Dot() => 0 }", "match x {(Color[Red]) => 1, (Result[Ok])(v) => v, ((geo[Shape])[Dot])() => 0}"},
+		{"const y = match (x) { _ => 1 };", "const y = match x {_ => 1};"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.source)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		if program.String() != tt.expected {
+			t.Errorf("wrong program. want=%q, got=%q", tt.expected, program.String())
+		}
+	}
+
+	errorTests := []struct {
+		source   string
+		expected string
+	}{
+		{"match (x) { a + b => 1 }", "Honk! expected next token to be FatArrow, got Plus instead on line 1"},
+		{"match (x) { 1 => 2 3 => 4 }", "Honk! expected next token to be Comma, got Number instead on line 1"},
+		{"match (x) { [a, a] => a }", "Honk! a is bound twice in one pattern on line 1"},
+		{"match (x) { (a) => a }", "Honk! ( cannot start a pattern on line 1"},
+		{"match (x) { -a => a }", "Honk! - must be followed by a number in a pattern on line 1"},
+		{"match (x) { {k: v} => v }", "Honk! the keys of a hash pattern must be literals, got k on line 1"},
+	}
+
+	for _, tt := range errorTests {
+		l := lexer.New(tt.source)
+		p := New(l)
+		p.ParseProgram()
+
+		errors := p.Errors()
+		if len(errors) == 0 {
+			t.Fatalf("expected parser error for %q", tt.source)
+		}
+
+		if errors[0] != tt.expected {
+			t.Errorf("wrong error. want=%q, got=%q", tt.expected, errors[0])
+		}
+	}
+}
+
+func TestParsingMethodCalls(t *testing.T) {
+	tests := []struct {
+		source   string
+		expected string
+	}{
+		{"xs.map(f)", "xs.map(f)"},
+		{"s.trim().upper()", "s.trim().upper()"},
+		{"a.b.c(1, 2 * 3)", "(a[b]).c(1, (2 * 3))"},
+		{"5.abs()", "5.abs()"},
+		{"a?.b()", "a?.b()"},
+		{"a?.b?.c(1)", "(a?[b])?.c(1)"},
+		{"-x.abs()", "(-x.abs())"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.source)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		if program.String() != tt.expected {
+			t.Errorf("wrong program. want=%q, got=%q", tt.expected, program.String())
+		}
+	}
+}
+
+func TestParsingTryStmts(t *testing.T) {
+	tests := []struct {
+		source   string
+		expected string
+	}{
+		{"try { x; } catch (e) { y; }", "try {x} catch (e) {y}"},
+		{"try { x; } finally { z; }", "try {x} finally {z}"},
+		{"try { x; } catch (e) { y; } finally { z; }", "try {x} catch (e) {y} finally {z}"},
+		{`throw error("boom");`, "throw error(boom);"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.source)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		if len(program.Stmts) != 1 {
+			t.Fatalf("program.Stmts does not contain 1 statement. got=%d", len(program.Stmts))
+		}
+
+		if program.String() != tt.expected {
+			t.Errorf("wrong statement. want=%q, got=%q", tt.expected, program.String())
+		}
+	}
+
+	l := lexer.New("try { x; }")
+	p := New(l)
+	p.ParseProgram()
+	if len(p.Errors()) == 0 {
+		t.Errorf("expected error for try without catch or finally")
+	}
+}
+
 func TestNullLiteral(t *testing.T) {
 	source := "null;"
 
@@ -4,12 +4,15 @@ import (
 	"bufio"
 	"fmt"
 	"io"
+	"math/rand"
+	"os"
 	"quonk/compiler"
 	"quonk/evaluator"
 	"quonk/lexer"
 	"quonk/object"
 	"quonk/parser"
 	"quonk/vm"
+	"time"
 )
 
 const PROMPT = ">>"
@@ -52,9 +55,13 @@ func StartVM(in io.Reader, out io.Writer) {
 	scanner := bufio.NewScanner(in)
 	constants := []object.Object{}
 	globals := make([]object.Object, vm.GlobalsSize)
+	// one generator for the session, so that seed on one line decides the numbers of the lines after it
+	random := rand.New(rand.NewSource(time.Now().UnixNano()))
+	capabilities := object.NewCapabilities(os.Stdin)
 	symbolTable := compiler.NewSymbolTable()
-	for i, v := range object.Builtins {
-		symbolTable.DefineBuiltin(i, v.Name)
+	modules := compiler.NewModules()
+	for i, name := range object.BuiltinNames() {
+		symbolTable.DefineBuiltin(i, name)
 	}
 
 	for {
@@ -73,14 +80,18 @@ func StartVM(in io.Reader, out io.Writer) {
 			continue
 		}
 
-		comp := compiler.NewWithState(symbolTable, constants)
+		comp := compiler.NewWithState(symbolTable, constants, modules)
 		err := comp.Compile(program)
 		if err != nil {
 			fmt.Fprintf(out, "Honk! compiler error:\n %s\n", err)
 			continue
 		}
+		for _, warning := range comp.Warnings() {
+			fmt.Fprintf(out, "Honk! warning:\n %s\n", warning)
+		}
 
-		machine := vm.NewWithGlobalStore(comp.Bytecode(), globals)
+		machine := vm.NewWithState(comp.Bytecode(), globals, random)
+		machine.SetIO(capabilities)
 		err = machine.Run()
 		if err != nil {
 			fmt.Fprintf(out, "Honk! runtime error:\n %s\n", err)
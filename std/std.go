@@ -0,0 +1,91 @@
+// Package std is the standard library written in Quonk. Its files are embedded in the binary, and a program imports
+// them like any other module, by their name after std: with or without the extension:
+//
+//	import "std:list";
+//	import { equal } from "std:assert.qk";
+//
+// list has sum, product, range, zip, flatten, unique, take, drop, includes and chunk, strings has pad_left,
+// pad_right, is_blank, capitalize, words and title, and assert has equal, not_equal, is_true and fails. The tests of
+// each file are Quonk programs in testdata, run by both engines
+package std
+
+import (
+	"embed"
+	"fmt"
+	"io/fs"
+	"path"
+	"quonk/ast"
+	"quonk/lexer"
+	"quonk/parser"
+	"strings"
+)
+
+// Prefix starts the import path of a std module. A path on disk cannot start with it, so the std modules never hide
+// a file of the program
+const Prefix = "std:"
+
+// Dir is the directory the resolved paths of std modules are in. It is not a directory on disk, every other module
+// resolves to an absolute path
+const Dir = "std"
+
+//go:embed *.qk
+var files embed.FS
+
+// programs holds the std modules parsed at startup, or the error parsing each one gave, by resolved path. The engines
+// compile or evaluate a module in the program importing it, so the parsed tree is as far as the work can be shared
+var programs = make(map[string]parsed)
+
+type parsed struct {
+	program *ast.Program
+	err     error
+}
+
+func init() {
+	names, err := fs.Glob(files, "*.qk")
+	if err != nil {
+		panic(err)
+	}
+
+	for _, name := range names {
+		source, err := files.ReadFile(name)
+		if err != nil {
+			programs[Dir+"/"+name] = parsed{err: err}
+			continue
+		}
+
+		p := parser.New(lexer.New(string(source)))
+		program := p.ParseProgram()
+		if len(p.Errors()) != 0 {
+			programs[Dir+"/"+name] = parsed{err: fmt.Errorf("%s", p.Errors()[0])}
+			continue
+		}
+		programs[Dir+"/"+name] = parsed{program: program}
+	}
+}
+
+// Path is the resolved path of the std module imported as name, if name is one
+func Path(name string) (string, bool) {
+	if !strings.HasPrefix(name, Prefix) {
+		return "", false
+	}
+
+	name = path.Clean(strings.TrimPrefix(name, Prefix))
+	if path.Ext(name) == "" {
+		name += ".qk"
+	}
+	return Dir + "/" + name, true
+}
+
+// IsModule reports whether a resolved path is the path of a std module
+func IsModule(resolved string) bool {
+	return strings.HasPrefix(resolved, Dir+"/")
+}
+
+// Program is the parsed std module at a path Path returned
+func Program(resolved string) (*ast.Program, error) {
+	parsed, ok := programs[resolved]
+	if !ok {
+		return nil, fs.ErrNotExist
+	}
+	return parsed.program, parsed.err
+}
@@ -0,0 +1,48 @@
+package std_test
+
+import (
+	"os"
+	"path/filepath"
+	"quonk/compiler"
+	"quonk/evaluator"
+	"quonk/lexer"
+	"quonk/object"
+	"quonk/parser"
+	"quonk/vm"
+	"testing"
+)
+
+// TestStd runs the Quonk tests of the std modules, which throw when an assertion fails, with both engines
+func TestStd(t *testing.T) {
+	files, err := filepath.Glob(filepath.Join("testdata", "*_test.qk"))
+	if err != nil || len(files) == 0 {
+		t.Fatalf("no std tests found: %v", err)
+	}
+
+	for _, file := range files {
+		source, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatalf("cannot read %s: %s", file, err)
+		}
+
+		p := parser.New(lexer.New(string(source)))
+		program := p.ParseProgram()
+		if len(p.Errors()) != 0 {
+			t.Fatalf("%s has parser errors: %v", file, p.Errors())
+		}
+
+		comp := compiler.New()
+		comp.SetFile(file)
+		if err := comp.Compile(program); err != nil {
+			t.Errorf("%s: compiler error: %s", file, err)
+			continue
+		}
+		if err := vm.New(comp.Bytecode()).Run(); err != nil {
+			t.Errorf("%s: vm error: %s", file, err)
+		}
+
+		if result := evaluator.Eval(program, object.NewScope()); result != nil && result.Type() == object.ErrorObj {
+			t.Errorf("%s: evaluator error: %s", file, result.Inspect())
+		}
+	}
+}
@@ -11,17 +11,26 @@ const (
 	Float      TokenType = "Float"
 
 	// Keywords
-	Mut    TokenType = "Mut"
-	Const  TokenType = "Const"
-	True   TokenType = "True"
-	False  TokenType = "False"
-	If     TokenType = "If"
-	Else   TokenType = "Else"
-	Elseif TokenType = "Elseif"
-	Func   TokenType = "Func"
-	Return TokenType = "Return"
-	For    TokenType = "For"
-	Macro  TokenType = "Macro"
+	Mut     TokenType = "Mut"
+	Const   TokenType = "Const"
+	True    TokenType = "True"
+	False   TokenType = "False"
+	If      TokenType = "If"
+	Else    TokenType = "Else"
+	Elseif  TokenType = "Elseif"
+	Func    TokenType = "Func"
+	Return  TokenType = "Return"
+	For     TokenType = "For"
+	Macro   TokenType = "Macro"
+	Try     TokenType = "Try"
+	Catch   TokenType = "Catch"
+	Finally TokenType = "Finally"
+	Throw   TokenType = "Throw"
+	Import  TokenType = "Import"
+	Export  TokenType = "Export"
+	Struct  TokenType = "Struct"
+	Enum    TokenType = "Enum"
+	Match   TokenType = "Match"
 
 	// Grouping
 	LeftParen          TokenType = "LeftParen"
@@ -53,6 +62,10 @@ const (
 	NotEqualTo         TokenType = "NotEqual"
 	And                TokenType = "And"
 	Or                 TokenType = "Or"
+	NullCoalesce       TokenType = "NullCoalesce"
+	OptionalIndex      TokenType = "OptionalIndex"
+	OptionalDot        TokenType = "OptionalDot"
+	FatArrow           TokenType = "FatArrow"
 
 	EOF     TokenType = "EOF" // End of File
 	Illegal TokenType = "Illegal"
@@ -2,12 +2,17 @@ package utils
 
 import "regexp"
 
+var (
+	alpha   = regexp.MustCompile(`^[a-zA-Z_]+$`)
+	numeric = regexp.MustCompile(`^[0-9]+$`)
+)
+
 func IsAlpha(s string) bool {
-	return regexp.MustCompile(`^[a-zA-Z_]+$`).MatchString(s)
+	return alpha.MatchString(s)
 }
 
 func IsNumeric(s string) bool {
-	return regexp.MustCompile(`^[0-9]+$`).MatchString(s)
+	return numeric.MatchString(s)
 }
 
 func IsSkipable(s string) bool {
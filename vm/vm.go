@@ -2,18 +2,21 @@ package vm
 
 import (
 	"fmt"
+	"math/rand"
+	"os"
 	"quonk/code"
 	"quonk/compiler"
 	"quonk/object"
+	"time"
 )
 
 const StackSize = 2048
 const GlobalsSize = 65536
 const MaxFrames = 1024
 
-var True = &object.Boolean{Value: true}
-var False = &object.Boolean{Value: false}
-var Null = &object.Null{}
+var True = object.TRUE
+var False = object.FALSE
+var Null = object.NULL
 
 type VM struct {
 	constants []object.Object
@@ -25,11 +28,22 @@ type VM struct {
 
 	frames      []*Frame
 	framesIndex int
+
+	// callErr is the error that stopped the last function a builtin called back, it is passed on unchanged
+	callErr error
+
+	random *rand.Rand
+	io     *object.Capabilities
 }
 
 func New(bytecode *compiler.Bytecode) *VM {
 
-	mainFn := &object.CompiledFunction{Instructions: bytecode.Instructions}
+	mainFn := &object.CompiledFunction{
+		Instructions: bytecode.Instructions,
+		Handlers:     bytecode.Handlers,
+		Lines:        bytecode.Lines,
+		NumLocals:    bytecode.NumLocals,
+	}
 	mainClosure := &object.Closure{Fn: mainFn}
 	mainFrame := NewFrame(mainClosure, 0)
 
@@ -40,21 +54,51 @@ func New(bytecode *compiler.Bytecode) *VM {
 		constants: bytecode.Constants,
 
 		stack: make([]object.Object, StackSize),
-		sp:    0,
+		sp:    mainFn.NumLocals, // reserve the slots of variables declared in blocks of the program
 
 		globals: make([]object.Object, GlobalsSize),
 
 		frames:      frames,
 		framesIndex: 1,
+
+		random: rand.New(rand.NewSource(time.Now().UnixNano())),
+		io:     object.NewCapabilities(os.Stdin),
 	}
 }
 
+// Seed makes the random builtins of this VM produce the same numbers on every run
+func (vm *VM) Seed(seed int64) {
+	vm.random.Seed(seed)
+}
+
+func (vm *VM) Random() *rand.Rand {
+	return vm.random
+}
+
+// IO is what the I/O builtins of this VM may touch
+func (vm *VM) IO() *object.Capabilities {
+	return vm.io
+}
+
+// SetIO replaces the capabilities of this VM, which grant nothing at first
+func (vm *VM) SetIO(io *object.Capabilities) {
+	vm.io = io
+}
+
 func NewWithGlobalStore(bytecode *compiler.Bytecode, globals []object.Object) *VM {
 	vm := New(bytecode)
 	vm.globals = globals
 	return vm
 }
 
+// NewWithState makes a VM that carries on the globals and the random numbers of the VMs before it, like the VM of
+// each line of the REPL
+func NewWithState(bytecode *compiler.Bytecode, globals []object.Object, random *rand.Rand) *VM {
+	vm := NewWithGlobalStore(bytecode, globals)
+	vm.random = random
+	return vm
+}
+
 func (vm *VM) StackTop() object.Object {
 	if vm.sp == 0 {
 		return nil
@@ -62,13 +106,43 @@ func (vm *VM) StackTop() object.Object {
 	return vm.stack[vm.sp-1]
 }
 
+// thrownError carries a value thrown by OpThrow until a handler catches it or it aborts Run
+type thrownError struct {
+	value object.Object
+}
+
+func (e *thrownError) Error() string {
+	return fmt.Sprintf("uncaught exception: %s", e.value.Inspect())
+}
+
+// Run executes the bytecode. Runtime errors and thrown values unwind to the innermost enclosing catch or finally
+// block, if there is none Run stops and returns the error
 func (vm *VM) Run() error {
+	return vm.execute(0)
+}
+
+// execute runs until the frame at index base returns, or for the main frame until its instructions end. Errors only
+// unwind to handlers of the frames from base up, the frames below belong to a builtin's caller
+func (vm *VM) execute(base int) error {
+	for {
+		err := vm.run(base)
+		if err == nil {
+			return nil
+		}
+
+		if !vm.handleException(err, base) {
+			return err
+		}
+	}
+}
+
+func (vm *VM) run(base int) error {
 
 	var ip int
 	var ins code.Instructions
 	var op code.Opcode
 
-	for vm.currentFrame().ip < len(vm.currentFrame().Instructions())-1 {
+	for vm.framesIndex > base && vm.currentFrame().ip < len(vm.currentFrame().Instructions())-1 {
 		vm.currentFrame().ip++
 
 		ip = vm.currentFrame().ip
@@ -136,6 +210,24 @@ func (vm *VM) Run() error {
 
 				vm.currentFrame().ip = pos - 1
 			}
+		case code.OpJumpNull:
+			pos := int(code.ReadUint16(ins[ip+1:]))
+			vm.currentFrame().ip += 2 // move past operand
+
+			// the value is only peeked, so a null stays on the stack as the result of the expression
+			if vm.head() == Null {
+				vm.currentFrame().ip = pos - 1
+			}
+		case code.OpJumpNotNull:
+			pos := int(code.ReadUint16(ins[ip+1:]))
+			vm.currentFrame().ip += 2 // move past operand
+
+			// a non null value stays on the stack as the result of the expression, a null one is discarded
+			if vm.head() != Null {
+				vm.currentFrame().ip = pos - 1
+			} else {
+				vm.pop()
+			}
 		case code.OpNull:
 			err := vm.push(Null)
 			if err != nil {
@@ -185,13 +277,90 @@ func (vm *VM) Run() error {
 			if err != nil {
 				return err
 			}
-		case code.OpIndex:
+		case code.OpIndex, code.OpStrictIndex:
 			// get index from top of stack
 			index := vm.pop()
 			// get object from top of stack
 			left := vm.pop()
 
-			err := vm.executeIndexExpression(left, index)
+			err := vm.executeIndexExpression(left, index, op == code.OpStrictIndex)
+			if err != nil {
+				return err
+			}
+		case code.OpSlice:
+			end := vm.pop()
+			start := vm.pop()
+			left := vm.pop()
+
+			sliced, err := object.Slice(left, start, end)
+			if err != nil {
+				return err
+			}
+
+			err = vm.push(sliced)
+			if err != nil {
+				return err
+			}
+		case code.OpModule:
+			// the module name is followed by a name and a value for each export
+			numExports := int(code.ReadUint16(ins[ip+1:]))
+			vm.currentFrame().ip += 2
+
+			start := vm.sp - 2*numExports
+			exports := make(map[string]object.Object, numExports)
+			for i := start; i < vm.sp; i += 2 {
+				exports[vm.stack[i].(*object.String).Value] = vm.stack[i+1]
+			}
+			mod := &object.Module{Name: vm.stack[start-1].(*object.String).Value, Exports: exports}
+
+			vm.sp = start - 1
+			err := vm.push(mod)
+			if err != nil {
+				return err
+			}
+		case code.OpStruct:
+			// the struct type is followed by a name and a value for each field set by the literal
+			numFields := int(code.ReadUint16(ins[ip+1:]))
+			vm.currentFrame().ip += 2
+
+			start := vm.sp - 2*numFields
+			built, err := vm.buildStruct(vm.stack[start-1], start, vm.sp)
+			if err != nil {
+				return err
+			}
+
+			vm.sp = start - 1
+			err = vm.push(built)
+			if err != nil {
+				return err
+			}
+		case code.OpGetField:
+			idx := int(code.ReadUint16(ins[ip+1:]))
+			vm.currentFrame().ip += 2
+
+			// the compiler only reads fields by slot from structs of a type it knows
+			target := vm.pop().(*object.Struct)
+			err := vm.push(target.Fields[idx])
+			if err != nil {
+				return err
+			}
+		case code.OpSetField:
+			idx := int(code.ReadUint16(ins[ip+1:]))
+			vm.currentFrame().ip += 2
+
+			value := vm.pop()
+			target := vm.pop().(*object.Struct)
+			target.Fields[idx] = value
+		case code.OpSetFieldByName:
+			value := vm.pop()
+			name := vm.pop().(*object.String)
+			obj := vm.pop()
+
+			target, ok := obj.(*object.Struct)
+			if !ok {
+				return fmt.Errorf("field assignment not supported: %s", obj.Type())
+			}
+			err := target.SetField(name.Value, value)
 			if err != nil {
 				return err
 			}
@@ -204,6 +373,76 @@ func (vm *VM) Run() error {
 			if err != nil {
 				return err
 			}
+		case code.OpCallMethod:
+			name := vm.constants[code.ReadUint16(ins[ip+1:])].(*object.String)
+			numArgs := code.ReadUint8(ins[ip+3:])
+			vm.currentFrame().ip += 3
+
+			err := vm.executeMethodCall(name.Value, int(numArgs))
+			if err != nil {
+				return err
+			}
+		case code.OpMatchLength:
+			length := int(code.ReadUint16(ins[ip+1:]))
+			vm.currentFrame().ip += 2
+
+			var matched bool
+			switch value := vm.pop().(type) {
+			case *object.Array:
+				matched = len(value.Elements) == length
+			case *object.Tuple:
+				matched = len(value.Elements) == length
+			}
+			err := vm.push(nativeBoolToBooleanObject(matched))
+			if err != nil {
+				return err
+			}
+		case code.OpMatchKeys:
+			// the value is below the keys it must have
+			numKeys := int(code.ReadUint16(ins[ip+1:]))
+			vm.currentFrame().ip += 2
+
+			keys := vm.stack[vm.sp-numKeys : vm.sp]
+			hash, matched := vm.stack[vm.sp-numKeys-1].(*object.Hash)
+			for _, key := range keys {
+				if !matched {
+					break
+				}
+				_, matched = hash.Pairs[key.(object.Hashable).HashKey()]
+			}
+
+			vm.sp = vm.sp - numKeys - 1
+			err := vm.push(nativeBoolToBooleanObject(matched))
+			if err != nil {
+				return err
+			}
+		case code.OpMatchVariant:
+			numFields := int(code.ReadUint8(ins[ip+1:]))
+			vm.currentFrame().ip += 1
+
+			variant := vm.pop()
+			value, matched := vm.pop().(*object.Variant)
+			if matched {
+				var err error
+				matched, err = value.Matches(variant, numFields)
+				if err != nil {
+					return err
+				}
+			}
+			err := vm.push(nativeBoolToBooleanObject(matched))
+			if err != nil {
+				return err
+			}
+		case code.OpJumpTable:
+			table := vm.constants[code.ReadUint16(ins[ip+1:])].(*object.Hash)
+			vm.currentFrame().ip += 2
+
+			// a subject the table has no arm for falls through to the jump to the default arm
+			if key, ok := object.AsHashable(vm.pop()); ok {
+				if pair, ok := table.Pairs[key.HashKey()]; ok {
+					vm.currentFrame().ip = int(pair.Value.(*object.Integer).Value) - 1
+				}
+			}
 		case code.OpReturnValue:
 			returnValue := vm.pop()
 
@@ -252,10 +491,8 @@ func (vm *VM) Run() error {
 			builtinIdx := code.ReadUint8(ins[ip+1:])
 			vm.currentFrame().ip += 1 // move past operand
 
-			// get built-in function from index and push it onto the stack
-			def := object.Builtins[builtinIdx]
-
-			err := vm.push(def.BuiltIn)
+			// get built-in function or constant from index and push it onto the stack
+			err := vm.push(object.BuiltinValue(int(builtinIdx)))
 			if err != nil {
 				return err
 			}
@@ -281,12 +518,41 @@ func (vm *VM) Run() error {
 			if err != nil {
 				return err
 			}
+		case code.OpSetFree:
+			freeIdx := code.ReadUint8(ins[ip+1:])
+			vm.currentFrame().ip += 1 // move past operand
+
+			// captured mutable variables are always cells, so the write is seen by every closure sharing it
+			cell := vm.currentFrame().cl.Free[freeIdx].(*object.Cell)
+			cell.Value = vm.pop()
+		case code.OpCell:
+			err := vm.push(&object.Cell{Value: vm.pop()})
+			if err != nil {
+				return err
+			}
+		case code.OpLoadCell:
+			cell := vm.pop().(*object.Cell)
+
+			err := vm.push(cell.Value)
+			if err != nil {
+				return err
+			}
+		case code.OpSetLocalCell:
+			localIdx := code.ReadUint8(ins[ip+1:])
+			vm.currentFrame().ip += 1 // move past operand
+
+			frame := vm.currentFrame()
+
+			cell := vm.stack[frame.basePointer+int(localIdx)].(*object.Cell)
+			cell.Value = vm.pop()
 		case code.OpCurrentClosure:
 			currentClosure := vm.currentFrame().cl
 			err := vm.push(currentClosure)
 			if err != nil {
 				return err
 			}
+		case code.OpThrow:
+			return &thrownError{value: vm.pop()}
 		}
 	}
 	return nil
@@ -409,17 +675,20 @@ func (vm *VM) executeComparison(op code.Opcode) error {
 	if leftType == object.StringObj && rightType == object.StringObj {
 		return vm.executeStringComparison(op, left, right)
 	}
-	// objects should be boolean at this point
+	// every other type is compared structurally
+	switch op {
+	case code.OpEqual:
+		return vm.push(nativeBoolToBooleanObject(object.Equal(left, right)))
+	case code.OpNotEqual:
+		return vm.push(nativeBoolToBooleanObject(!object.Equal(left, right)))
+	}
 
+	// objects should be boolean at this point
 	if left.Type() == right.Type() && left.Type() != object.BooleanObj {
 		return fmt.Errorf("unknown operation for type %s", left.Type())
 	}
 
 	switch op {
-	case code.OpEqual:
-		return vm.push(nativeBoolToBooleanObject(left == right))
-	case code.OpNotEqual:
-		return vm.push(nativeBoolToBooleanObject(left != right))
 	case code.OpAnd:
 		return vm.push(nativeBoolToBooleanObject(isTruthy(left) && isTruthy(right)))
 	case code.OpOr:
@@ -542,7 +811,7 @@ func (vm *VM) buildHash(startIndex, endIndex int) (*object.Hash, error) {
 		key := vm.stack[i]
 		value := vm.stack[i+1]
 
-		hashKey, ok := key.(object.Hashable)
+		hashKey, ok := object.AsHashable(key)
 		if !ok {
 			return nil, fmt.Errorf("unusable as hash key: %s", key.Type())
 		}
@@ -553,45 +822,122 @@ func (vm *VM) buildHash(startIndex, endIndex int) (*object.Hash, error) {
 	return &object.Hash{Pairs: hashedPairs}, nil
 }
 
-func (vm *VM) executeIndexExpression(left, index object.Object) error {
+func (vm *VM) buildStruct(structType object.Object, startIndex, endIndex int) (*object.Struct, error) {
+	t, ok := structType.(*object.StructType)
+	if !ok {
+		return nil, fmt.Errorf("not a struct type: %s", structType.Type())
+	}
+
+	names := make([]string, 0, (endIndex-startIndex)/2)
+	values := make([]object.Object, 0, (endIndex-startIndex)/2)
+	for i := startIndex; i < endIndex; i += 2 {
+		names = append(names, vm.stack[i].(*object.String).Value)
+		values = append(values, vm.stack[i+1])
+	}
+
+	return t.New(names, values)
+}
+
+// in strict-null mode an index miss is an error instead of null
+func (vm *VM) executeIndexExpression(left, index object.Object, strict bool) error {
 	switch {
 	case left.Type() == object.ArrayObj && index.Type() == object.IntegerObj:
-		return vm.executeArrayIndex(left, index)
+		return vm.executeArrayIndex(left.(*object.Array).Elements, index, strict)
+	case left.Type() == object.TupleObj && index.Type() == object.IntegerObj:
+		return vm.executeArrayIndex(left.(*object.Tuple).Elements, index, strict)
+	case left.Type() == object.StringObj && index.Type() == object.IntegerObj:
+		return vm.executeStringIndex(left.(*object.String).Value, index, strict)
 	case left.Type() == object.HashObj:
-		return vm.executeHashIndex(left, index)
+		return vm.executeHashIndex(left, index, strict)
+	case left.Type() == object.ExceptionObj:
+		return vm.executeExceptionIndex(left, index)
+	case left.Type() == object.ModuleObj:
+		export, err := left.(*object.Module).Export(index)
+		if err != nil {
+			return err
+		}
+		return vm.push(export)
+	case left.Type() == object.StructObj:
+		field, err := left.(*object.Struct).Field(index)
+		if err != nil {
+			return err
+		}
+		return vm.push(field)
+	case left.Type() == object.EnumObj:
+		variant, err := left.(*object.EnumType).Variant(index)
+		if err != nil {
+			return err
+		}
+		return vm.push(variant)
+	case left.Type() == object.VariantObj:
+		field, err := left.(*object.Variant).Field(index)
+		if err != nil {
+			return err
+		}
+		return vm.push(field)
 	default:
 		return fmt.Errorf("index operator not supported: %s", left.Type())
 	}
 }
 
-func (vm *VM) executeArrayIndex(array, index object.Object) error {
-	arrayObj := array.(*object.Array)
+// a negative index counts from the end, -1 is the last element
+func (vm *VM) executeArrayIndex(elements []object.Object, index object.Object, strict bool) error {
 	idx := index.(*object.Integer).Value
-	max := int64(len(arrayObj.Elements) - 1)
+	length := int64(len(elements))
+	if idx < 0 {
+		idx += length
+	}
 
-	if idx < 0 || idx > max {
+	if idx < 0 || idx >= length {
+		if strict {
+			return fmt.Errorf("array index out of bounds: %d", index.(*object.Integer).Value)
+		}
+		return vm.push(Null)
+	}
+
+	return vm.push(elements[idx])
+}
+
+// indexing a string gives a one character string, counting bytes like len does
+func (vm *VM) executeStringIndex(str string, index object.Object, strict bool) error {
+	char, ok := object.CharAt(str, index.(*object.Integer).Value)
+	if !ok {
+		if strict {
+			return fmt.Errorf("string index out of bounds: %d", index.(*object.Integer).Value)
+		}
 		return vm.push(Null)
 	}
 
-	return vm.push(arrayObj.Elements[idx])
+	return vm.push(char)
 }
 
-func (vm *VM) executeHashIndex(hash, index object.Object) error {
+func (vm *VM) executeHashIndex(hash, index object.Object, strict bool) error {
 	hashObject := hash.(*object.Hash)
 
-	key, ok := index.(object.Hashable)
+	key, ok := object.AsHashable(index)
 	if !ok {
 		return fmt.Errorf("unusable as hash key: %s", index.Type())
 	}
 
 	pair, ok := hashObject.Pairs[key.HashKey()]
 	if !ok {
+		if strict {
+			return fmt.Errorf("key not found: %s", index.Inspect())
+		}
 		return vm.push(Null)
 	}
 
 	return vm.push(pair.Value)
 }
 
+func (vm *VM) executeExceptionIndex(exception, index object.Object) error {
+	if key, ok := index.(*object.String); !ok || key.Value != "message" {
+		return fmt.Errorf("unknown exception field: %s", index.Inspect())
+	}
+
+	return vm.push(&object.String{Value: exception.(*object.Exception).Message})
+}
+
 func (vm *VM) executeCall(numArgs int) error {
 	// the function is at the bottom of the stack, below the args
 	callee := vm.stack[vm.sp-1-numArgs]
@@ -606,6 +952,26 @@ func (vm *VM) executeCall(numArgs int) error {
 	}
 }
 
+// executeMethodCall calls receiver.name(args). Below the receiver and the args is the function name resolved to at
+// compile time, or null, which is called with the receiver as its first argument when the receiver has no member name
+func (vm *VM) executeMethodCall(name string, numArgs int) error {
+	fnIndex := vm.sp - 2 - numArgs
+	receiver := vm.stack[fnIndex+1]
+
+	if member, ok := object.Member(receiver, name); ok {
+		// the member takes the place of the function and the receiver is dropped from the args
+		vm.stack[fnIndex] = member
+		copy(vm.stack[fnIndex+1:], vm.stack[fnIndex+2:vm.sp])
+		vm.sp--
+		return vm.executeCall(numArgs)
+	}
+
+	if vm.stack[fnIndex] == Null {
+		return fmt.Errorf("%s has no method %s", receiver.Type(), name)
+	}
+	return vm.executeCall(numArgs + 1)
+}
+
 func (vm *VM) callClosure(cl *object.Closure, numArgs int) error {
 	if numArgs != cl.Fn.NumParameters {
 		return fmt.Errorf("wrong number of arguments. want=%d, got=%d", cl.Fn.NumParameters, numArgs)
@@ -626,9 +992,21 @@ func (vm *VM) callClosure(cl *object.Closure, numArgs int) error {
 func (vm *VM) callBuiltIn(builtin *object.BuiltIn, numArgs int) error {
 	args := vm.stack[vm.sp-numArgs : vm.sp] // pull slice of args off stack
 
-	result := builtin.Fn(args...)
+	result := builtin.Fn(vm, args...)
 	vm.sp = vm.sp - numArgs - 1 // pop args and function
 
+	// an error of a function the builtin called is passed on as it is, so thrown values can still be caught
+	if vm.callErr != nil {
+		err := vm.callErr
+		vm.callErr = nil
+		return err
+	}
+
+	// builtins report errors with an error object, which stops the vm like any other runtime error
+	if errObj, ok := result.(*object.Error); ok {
+		return fmt.Errorf("%s on line %d", errObj.Message, vm.currentLine())
+	}
+
 	if result != nil {
 		vm.push(result)
 	} else {
@@ -638,6 +1016,54 @@ func (vm *VM) callBuiltIn(builtin *object.BuiltIn, numArgs int) error {
 	return nil
 }
 
+// Call runs fn to completion for a builtin and returns its result. It is re-entrant, the frames of fn are run by a
+// nested loop on top of the frame that called the builtin
+func (vm *VM) Call(fn object.Object, args ...object.Object) object.Object {
+	sp, framesIndex := vm.sp, vm.framesIndex
+
+	err := vm.call(fn, args)
+	if err != nil {
+		// leave the stack as the builtin found it, the error unwinds from the builtin's call
+		vm.sp, vm.framesIndex = sp, framesIndex
+		vm.callErr = err
+		return &object.Error{Message: err.Error()}
+	}
+
+	return vm.pop()
+}
+
+func (vm *VM) call(fn object.Object, args []object.Object) error {
+	err := vm.push(fn)
+	if err != nil {
+		return err
+	}
+	for _, arg := range args {
+		err := vm.push(arg)
+		if err != nil {
+			return err
+		}
+	}
+
+	base := vm.framesIndex
+	err = vm.executeCall(len(args))
+	if err != nil {
+		return err
+	}
+
+	// builtins have already left their result on the stack, closures have pushed a frame to run
+	if vm.framesIndex == base {
+		return nil
+	}
+
+	return vm.execute(base)
+}
+
+// currentLine is the source line of the instruction the current frame is executing
+func (vm *VM) currentLine() int {
+	frame := vm.currentFrame()
+	return frame.cl.Fn.Lines.LineAt(frame.ip)
+}
+
 func (vm *VM) pushClosure(constIdx, numFree int) error {
 	constant := vm.constants[constIdx]
 	fn, ok := constant.(*object.CompiledFunction)
@@ -655,6 +1081,37 @@ func (vm *VM) pushClosure(constIdx, numFree int) error {
 	return vm.push(closure)
 }
 
+// handleException looks for a handler covering the current instruction of each frame, starting with the innermost.
+// If one is found the frames above it are discarded and the thrown value is pushed for the handler
+func (vm *VM) handleException(err error, base int) bool {
+	var value object.Object
+	if thrown, ok := err.(*thrownError); ok {
+		value = thrown.value
+	} else {
+		value = &object.Exception{Message: err.Error()}
+	}
+
+	for i := vm.framesIndex - 1; i >= base; i-- {
+		frame := vm.frames[i]
+
+		for _, handler := range frame.cl.Fn.Handlers {
+			if frame.ip < handler.Start || frame.ip >= handler.End {
+				continue
+			}
+
+			vm.framesIndex = i + 1
+			// discard everything the frame pushed past its locals
+			vm.sp = frame.basePointer + frame.cl.Fn.NumLocals
+			// ip will be incremented as part of loop, so we set it to right before the handler
+			frame.ip = handler.Target - 1
+
+			return vm.push(value) == nil
+		}
+	}
+
+	return false
+}
+
 // utility functions
 func nativeBoolToBooleanObject(input bool) *object.Boolean {
 	if input {
@@ -2,12 +2,18 @@ package vm
 
 import (
 	"fmt"
+	"math/rand"
+	"os"
+	"path/filepath"
 	"quonk/ast"
 	"quonk/compiler"
+	"quonk/evaluator"
 	"quonk/lexer"
 	"quonk/object"
 	"quonk/parser"
+	"strings"
 	"testing"
+	"time"
 )
 
 type vmTestCase struct {
@@ -135,6 +141,11 @@ func TestConditionals(t *testing.T) {
 		{"if (1 > 2) { 10 } else { 20 }", 20},
 		{"if (1 > 2) { 10 }", Null},
 		{"if (false) { 10 }", Null},
+		{"if (false) { 10 } else if (true) { 20 } else { 30 }", 20},
+		{"if (false) { 10 } elseif (false) { 20 } else { 30 }", 30},
+		{"if (1 > 2) { 10 } else if (1 > 3) { 20 } elseif (1 < 4) { 40 }", 40},
+		{"if (false) { 10 } else if (false) { 20 }", Null},
+		{"mut x = 5; if (x < 0) { -1 } else if (x == 0) { 0 } else { 1 }", 1},
 	}
 
 	runVmTests(t, tests)
@@ -145,6 +156,9 @@ func TestGlobalVarDeclarationStatements(t *testing.T) {
 		{"mut one = 1; one", 1},
 		{"mut one = 1; const two = 2; one + two", 3},
 		{"mut one = 1; mut two = one + one; one + two", 3},
+		{"mut log = []; log = append(log, 1); log", []int{1}},
+		{"mut E = 1; E + 1", 2},
+		{"const max = func(a) { a * 2 }; const f = func() { max(5) }; f()", 10},
 	}
 
 	runVmTests(t, tests)
@@ -214,7 +228,9 @@ func TestIndexExpressions(t *testing.T) {
 		{"const i = 0; [1][i]", 1},
 		{"[][0]", Null},
 		{"[1, 2, 3][99]", Null},
-		{"[1][-1]", Null},
+		{"[1][-1]", 1},
+		{"[1, 2, 3][-3]", 1},
+		{"[1][-2]", Null},
 		{"{1: 1, 2: 2}[1]", 1},
 		{"{1: 1, 2: 2}[2]", 2},
 		{"{1: 1}[0]", Null},
@@ -224,6 +240,226 @@ func TestIndexExpressions(t *testing.T) {
 	runVmTests(t, tests)
 }
 
+func TestSliceExpressions(t *testing.T) {
+	tests := []vmTestCase{
+		{"[1, 2, 3, 4][1:3]", []int{2, 3}},
+		{"[1, 2, 3, 4][:2]", []int{1, 2}},
+		{"[1, 2, 3, 4][2:]", []int{3, 4}},
+		{"[1, 2, 3, 4][:]", []int{1, 2, 3, 4}},
+		{"[1, 2, 3, 4][-2:]", []int{3, 4}},
+		{"[1, 2, 3, 4][:-1]", []int{1, 2, 3}},
+		{"[1, 2, 3, 4][-10:10]", []int{1, 2, 3, 4}},
+		{"[1, 2, 3, 4][3:1]", []int{}},
+		{"const a = [1, 2, 3]; const i = 1; a[i:i + 1]", []int{2}},
+		{"tuple(1, 2, 3)[1:] == tuple(2, 3)", true},
+		{`"quonk"[1:3]`, "uo"},
+		{`"quonk"[-2:]`, "nk"},
+		{`"quonk"[:0]`, ""},
+		{`"quonk"[0]`, "q"},
+		{`"quonk"[-1]`, "k"},
+		{`"quonk"[5]`, Null},
+		{`"é"[0]`, "é"},
+		{`"héllo"[1:3]`, "él"},
+		{`"日本語"[-1]`, "語"},
+		{`"日本語"[3]`, Null},
+		{`len("日本語")`, 3},
+		{`const s = "naïve"; s[index_of(s, "ve"):]`, "ve"},
+		{"slice([1, 2, 3, 4], 1, 4)", []int{2, 3, 4}},
+		{"slice([1, 2, 3, 4], -3, -1)", []int{2, 3}},
+		{`slice("quonk", 0, 2)`, "qu"},
+		{"null?[1:]", Null},
+		{`"use strict-null"; "ab"[2]`, &object.Error{Message: "string index out of bounds: 2"}},
+		{`[1][true:]`, &object.Error{Message: "slice bound must be int type. got=Boolean"}},
+		{`{}[1:]`, &object.Error{Message: "slice operator not supported: Hash"}},
+	}
+
+	runVmTests(t, tests)
+}
+
+func TestStructuralEquality(t *testing.T) {
+	tests := []vmTestCase{
+		{"[1, 2] == [1, 2]", true},
+		{"[1, 2] != [1, 2]", false},
+		{"[1, [2, 3]] == [1, [2, 3]]", true},
+		{"[1, 2] == [2, 1]", false},
+		{`{"a": [1]} == {"a": [1]}`, true},
+		{`{"a": 1} == {"a": 2}`, false},
+		{"null == null", true},
+		{"[] == null", false},
+		{"tuple(1, 2) == freeze([1, 2])", true},
+		{"{1.5: 1}[1.5]", 1},
+		{"{tuple(1, 2): 3}[tuple(1, 2)]", 3},
+		{"{freeze([1, [2]]): 4}[tuple(1, tuple(2))]", 4},
+		{"tuple(1, 2)[1]", 2},
+	}
+
+	runVmTests(t, tests)
+}
+
+func TestNullSafetyOperators(t *testing.T) {
+	tests := []vmTestCase{
+		{"null ?? 1", 1},
+		{"2 ?? 1", 2},
+		{"false ?? 1", false},
+		{`{"a": 1}["b"] ?? 3`, 3},
+		{"null ?? null ?? 4", 4},
+		{"null?[0]", Null},
+		{"[1, 2]?[1]", 2},
+		{`const h = {"a": {"b": 5}}; h?["a"]?["b"]`, 5},
+		{`const h = {"a": {"b": 5}}; h?["x"]?["b"]`, Null},
+		{`const h = {"a": {"b": 5}}; h?.a?.b`, 5},
+		{`const h = {"a": {"b": 5}}; h?.x?.b ?? 6`, 6},
+		{"mut calls = 0; const f = func() { calls = calls + 1; 1 }; 1 ?? f(); calls", 0},
+	}
+
+	runVmTests(t, tests)
+}
+
+func TestZeroValues(t *testing.T) {
+	tests := []vmTestCase{
+		{"mut count int; count", 0},
+		{"mut ratio float; ratio", 0.0},
+		{`mut name string; name + "x"`, "x"},
+		{"mut done bool; done", false},
+		{"mut items array; items", []int{}},
+		{`"use strict-null"; mut count int; count = count + 1; count`, 1},
+		{`"use strict-null"; {"a": 1}["a"]`, 1},
+	}
+
+	runVmTests(t, tests)
+}
+
+func TestStrictNullIndexMisses(t *testing.T) {
+	tests := []vmTestCase{
+		{
+			source:   `"use strict-null"; {"a": 1}["b"]`,
+			expected: "key not found: b",
+		},
+		{
+			source:   `"use strict-null"; [1, 2][2]`,
+			expected: "array index out of bounds: 2",
+		},
+	}
+
+	for _, tt := range tests {
+		program := parse(tt.source)
+
+		comp := compiler.New()
+		err := comp.Compile(program)
+		if err != nil {
+			t.Fatalf("compiler error: %s", err)
+		}
+
+		vm := New(comp.Bytecode())
+		err = vm.Run()
+		if err == nil {
+			t.Fatalf("expected vm error, but got nil")
+		}
+
+		if err.Error() != tt.expected {
+			t.Fatalf("wrong error message. want=%q, got=%q", tt.expected, err.Error())
+		}
+	}
+}
+
+func TestExceptions(t *testing.T) {
+	tests := []vmTestCase{
+		{`mut r = 0; try { throw 1; r = 2; } catch (e) { r = e; } r`, 1},
+		{`mut r = 0; try { r = 2; } catch (e) { r = 3; } r`, 2},
+		{`mut r = ""; try { len(1); } catch (e) { r = e["message"]; } r`, "argument to `len` of wrong type. got=Integer on line 1"},
+		{`mut r = ""; try { throw error("boom"); } catch (e) { r = e["message"]; } r`, "boom"},
+		{`mut r = 0; try { 1 + true; } catch (e) { r = 5; } r`, 5},
+		{`mut r = 0; try { r = 1; } finally { r = r + 10; } r`, 11},
+		{`mut r = 0; try { throw 1; } catch (e) { r = 1; } finally { r = r + 10; } r`, 11},
+		{`
+		mut r = 0;
+		try {
+			try { throw 1; } finally { r = 10; }
+		} catch (e) {
+			r = r + e;
+		}
+		r`, 11},
+		{`
+		mut r = 0;
+		try {
+			try { throw 1; } catch (e) { throw e + 1; }
+		} catch (e) {
+			r = e;
+		}
+		r`, 2},
+		{`
+		const thrower = func(x) { if (x > 0) { throw x; } 0 };
+		const deep = func(x) { thrower(x) + 1 };
+		mut r = 0;
+		try { deep(7); } catch (e) { r = e; }
+		r`, 7},
+		{`
+		const safeDiv = func(a, b) {
+			try {
+				if (b == 0) { throw "division by zero"; }
+				return a / b;
+			} catch (e) {
+				return e;
+			}
+		};
+		safeDiv(1, 0) + ", " + safeDiv(6, true)["message"]`, "division by zero, unsupported types for binary operation: Integer Boolean"},
+		{`
+		const safeDiv = func(a, b) {
+			try { return a / b; } catch (e) { return 0; }
+		};
+		safeDiv(6, 3)`, 2},
+		{`
+		mut cleanups = 0;
+		const f = func() {
+			try { return 1; } finally { cleanups = cleanups + 1; }
+		};
+		f() + f() + cleanups`, 4},
+		{`
+		mut r = 0;
+		const f = func() { mut i = 0; for (i < 3) { i = i + 1; if (i == 2) { throw i; } } };
+		try { f(); } catch (e) { r = e; }
+		r`, 2},
+		{`throw error("boom")`, &object.Error{Message: "uncaught exception: Error: boom"}},
+		{`try { throw 1; } finally { 2; }`, &object.Error{Message: "uncaught exception: 1"}},
+		{`error("boom") == error("boom")`, true},
+	}
+
+	runVmTests(t, tests)
+}
+
+// TestTryValueMatchesEvaluator checks that both engines give try statements the same value
+func TestTryValueMatchesEvaluator(t *testing.T) {
+	tests := []string{
+		`const g = func() { throw 3; }; const f = func() { mut a = 1; try { g(); } catch (e) { a + e } }; f()`,
+		`[1, 2, 3].filter(func(x) { try { x + 1 } catch (e) { true } })`,
+		`const f = func() { try { 1 } finally { 2 } }; f()`,
+		`const f = func() { try { return 1; } catch (e) { 2 } }; f()`,
+		`const f = func() { try { throw 1; } catch (e) { return e + 1; } }; f()`,
+		`try { 1 } catch (e) { 2 }`,
+	}
+
+	for _, source := range tests {
+		program := parse(source)
+
+		comp := compiler.New()
+		if err := comp.Compile(program); err != nil {
+			t.Fatalf("compiler error: %s", err)
+		}
+		vm := New(comp.Bytecode())
+		if err := vm.Run(); err != nil {
+			t.Fatalf("vm error: %s", err)
+		}
+
+		evaluated := evaluator.Eval(program, object.NewScope())
+		if evaluated == nil {
+			t.Fatalf("evaluator gave no value for %s", source)
+		}
+		if got, want := vm.LastPoppedStackElem().Inspect(), evaluated.Inspect(); got != want {
+			t.Errorf("engines disagree on %s. vm=%s, evaluator=%s", source, got, want)
+		}
+	}
+}
+
 func TestCallingFunctionsWithoutArguments(t *testing.T) {
 	tests := []vmTestCase{
 		{
@@ -441,11 +677,11 @@ func TestBuiltinFunctions(t *testing.T) {
 		},
 		{
 			source:   `len(1)`,
-			expected: &object.Error{Message: "argument to `len` of wrong type. got=Integer"},
+			expected: &object.Error{Message: "argument to `len` of wrong type. got=Integer on line 1"},
 		},
 		{
 			source:   `len("one", "two")`,
-			expected: &object.Error{Message: "`len` expects one argument"},
+			expected: &object.Error{Message: "`len` expects one argument on line 1"},
 		},
 		{
 			source:   `first([1, 2, 3])`,
@@ -457,7 +693,7 @@ func TestBuiltinFunctions(t *testing.T) {
 		},
 		{
 			source:   `first(1)`,
-			expected: &object.Error{Message: "argument to `first` must be array type"},
+			expected: &object.Error{Message: "argument to `first` must be array type on line 1"},
 		},
 		{
 			source:   `last([1, 2, 3])`,
@@ -469,7 +705,7 @@ func TestBuiltinFunctions(t *testing.T) {
 		},
 		{
 			source:   `last(1)`,
-			expected: &object.Error{Message: "argument to `last` must be array type"},
+			expected: &object.Error{Message: "argument to `last` must be array type on line 1"},
 		},
 		{
 			source:   `rest([1, 2, 3])`,
@@ -485,7 +721,14 @@ func TestBuiltinFunctions(t *testing.T) {
 		},
 		{
 			source:   `append(1, 1)`,
-			expected: &object.Error{Message: "first argument to `append` must be array type"},
+			expected: &object.Error{Message: "first argument to `append` must be array type on line 1"},
+		},
+		{
+			source: `const f = func(x) {
+				len(x)
+			};
+			f(1) + 1`,
+			expected: &object.Error{Message: "argument to `len` of wrong type. got=Integer on line 2"},
 		},
 	}
 
@@ -504,6 +747,754 @@ func TestClosures(t *testing.T) {
 			`,
 			expected: 99,
 		},
+		{
+			source: `
+			const newCounter = func() {
+				mut count = 0;
+				func() { count = count + 1; count };
+			}
+			const counter = newCounter();
+			counter();
+			counter();
+			counter();
+			`,
+			expected: 3,
+		},
+		{
+			source: `
+			const newCounter = func() {
+				mut count = 0;
+				func() { count = count + 1; count };
+			}
+			const a = newCounter();
+			const b = newCounter();
+			a();
+			a();
+			b();
+			`,
+			expected: 1,
+		},
+		{
+			// closures created in the same frame share the variable with each other and with the frame
+			source: `
+			const newAccount = func() {
+				mut balance = 0;
+				const deposit = func(x) { balance = balance + x; };
+				const read = func() { balance };
+				deposit(10);
+				deposit(5);
+				balance = balance * 2;
+				read();
+			}
+			newAccount();
+			`,
+			expected: 30,
+		},
+		{
+			// a cell is passed through intermediate closures
+			source: `
+			const outer = func() {
+				mut total = 1;
+				const middle = func() {
+					func() { total = total + 10; };
+				};
+				middle()();
+				middle()();
+				total;
+			}
+			outer();
+			`,
+			expected: 21,
+		},
+		{
+			source: `
+			const f = func() {
+				mut x = 0;
+				try { throw 5; } catch (e) { x = e; }
+				const g = func() { x };
+				g();
+			}
+			f();
+			`,
+			expected: 5,
+		},
+	}
+
+	runVmTests(t, tests)
+}
+
+func TestHigherOrderBuiltins(t *testing.T) {
+	tests := []vmTestCase{
+		{"map([1, 2, 3], func(x) { x * 2 }) == [2, 4, 6]", true},
+		{"map([], func(x) { x }) == []", true},
+		{`map(["a", "bb"], len) == [1, 2]`, true},
+		{"filter([1, 2, 3, 4], func(x) { x > 2 }) == [3, 4]", true},
+		{"filter([1, null, 2], func(x) { x }) == [1, 2]", true},
+		{"reduce([1, 2, 3, 4], func(acc, x) { acc + x }, 10)", 20},
+		{"reduce([1, 2, 3, 4], func(acc, x) { acc * x })", 24},
+		{"mut total = 0; each([1, 2, 3], func(x) { total = total + x; }); total", 6},
+		{"find([1, 2, 3, 4], func(x) { x > 2 })", 3},
+		{"find([1, 2], func(x) { x > 2 }) == null", true},
+		{"any([1, 2, 3], func(x) { x > 2 })", true},
+		{"any([], func(x) { true })", false},
+		{"all([1, 2, 3], func(x) { x > 0 })", true},
+		{"all([1, 2, 3], func(x) { x > 1 })", false},
+		{"const adder = func(n) { func(x) { x + n } }; map([1, 2], adder(10)) == [11, 12]", true},
+		{"map([[1, 2], [3]], func(xs) { reduce(xs, func(a, b) { a + b }, 0) }) == [3, 3]", true},
+		{`mut r = ""; try { map([1], func(x) { throw "boom"; }); } catch (e) { r = e; } r`, "boom"},
+		{`map([1, 2], func(x) { try { throw x; } catch (e) { return e * 10; } }) == [10, 20]`, true},
+		{"map(1, func(x) { x })", &object.Error{Message: "first argument to `map` must be array type on line 1"}},
+		{"filter([1], 1)", &object.Error{Message: "second argument to `filter` must be a function. got=Integer on line 1"}},
+		{"reduce([], func(a, b) { a })", &object.Error{Message: "`reduce` of empty array with no initial value on line 1"}},
+		{"any([1])", &object.Error{Message: "`any` expects two arguments on line 1"}},
+		{"map([1], func(a, b) { a })", &object.Error{Message: "wrong number of arguments. want=2, got=1"}},
+		{`map([1, 2], func(x) {
+			len(x)
+		})`, &object.Error{Message: "argument to `len` of wrong type. got=Integer on line 2"}},
+		{`map([1, 2], func(x) { throw x; })`, &object.Error{Message: "uncaught exception: 1"}},
+	}
+
+	runVmTests(t, tests)
+}
+
+func TestStringBuiltins(t *testing.T) {
+	tests := []vmTestCase{
+		{`split("a,b,c", ",") == ["a", "b", "c"]`, true},
+		{`split("  a b   c ") == ["a", "b", "c"]`, true},
+		{`join(["a", "b", "c"], "-")`, "a-b-c"},
+		{`join([], "-")`, ""},
+		{`trim("  hi  ")`, "hi"},
+		{`trim("xxhixx", "x")`, "hi"},
+		{`trim_left("  hi  ")`, "hi  "},
+		{`trim_right("  hi  ")`, "  hi"},
+		{`trim_right("hi!!", "!")`, "hi"},
+		{`contains("quonk", "on")`, true},
+		{`contains("quonk", "x")`, false},
+		{`starts_with("quonk", "qu")`, true},
+		{`ends_with("quonk", "qu")`, false},
+		{`index_of("quonk", "on")`, 2},
+		{`index_of("quonk", "x")`, -1},
+		{`replace("a-b-c", "-", "+")`, "a+b+c"},
+		{`upper("Quonk")`, "QUONK"},
+		{`lower("Quonk")`, "quonk"},
+		{`repeat("ab", 3)`, "ababab"},
+		{`chars("héy") == ["h", "é", "y"]`, true},
+		{`reverse("héy")`, "yéh"},
+		{`reverse([1, 2, 3]) == [3, 2, 1]`, true},
+		{`format("%d apples", 3)`, "3 apples"},
+		{`format("[%5d|%-5d|%05d]", 42, 42, 42)`, "[   42|42   |00042]"},
+		{`format("%.2f %8.3f %e", 3.14159, 2, 1500.0)`, "3.14    2.000 1.500000e+03"},
+		{`format("%s=%v %q", "k", [1, "a"], "q")`, `k=[1, a] "q"`},
+		{`format("%-6s|%6s|%.2s", "ab", "cd", "xyz")`, "ab    |    cd|xy"},
+		{`format("%t %x %X %c 100%%", true, 255, 255, 65)`, "true ff FF A 100%"},
+		{`format("%*d|%.*f", 4, 7, 1, 2.25)`, "   7|2.2"},
+		{`split(1, ",")`, &object.Error{Message: "argument to `split` must be string type. got=Integer on line 1"}},
+		{`join(["a", 1], ",")`, &object.Error{Message: "`join` expects an array of strings. got=Integer on line 1"}},
+		{`upper("a", "b")`, &object.Error{Message: "`upper` expects one argument on line 1"}},
+		{`replace("a", "b")`, &object.Error{Message: "`replace` expects three arguments on line 1"}},
+		{`repeat("a", -1)`, &object.Error{Message: "negative count for `repeat`: -1 on line 1"}},
+		{`repeat("ab", 9223372036854775807)`, &object.Error{Message: "result of `repeat` is too long: 9223372036854775807 copies of 2 bytes on line 1"}},
+		{`format("%d %d", 1)`, &object.Error{Message: "too few arguments for `format` on line 1"}},
+		{`format("%d", 1, 2)`, &object.Error{Message: "too many arguments for `format`: want=1, got=2 on line 1"}},
+		{`format("%d", "a")`, &object.Error{Message: "`format` verb %d does not accept String on line 1"}},
+		{`format("%y", 1)`, &object.Error{Message: "unknown `format` verb %y on line 1"}},
+		{`format("%5", 1)`, &object.Error{Message: "`format` string ends in the middle of a verb on line 1"}},
+	}
+
+	runVmTests(t, tests)
+}
+
+func TestMathBuiltins(t *testing.T) {
+	tests := []vmTestCase{
+		{"abs(-5)", 5},
+		{"abs(-2.5)", 2.5},
+		{"floor(2.7)", 2},
+		{"ceil(2.1)", 3},
+		{"round(2.5)", 3},
+		{"round(-2.5)", -3},
+		{"floor(4)", 4},
+		{"sqrt(16)", 4.0},
+		{"pow(2, 10)", 1024},
+		{"pow(2, -1)", 0.5},
+		{"pow(-2, 63)", -9223372036854775808},
+		{"pow(3, 39)", 4052555153018976267},
+		{"pow(2.0, 0.5) == sqrt(2)", true},
+		{"log(E)", 1.0},
+		{"exp(0)", 1.0},
+		{"sin(0)", 0.0},
+		{"cos(0)", 1.0},
+		{"round(tan(PI / 4.0) * 1000.0)", 1000},
+		{"atan2(0, 1)", 0.0},
+		{"min(3, 1, 2)", 1},
+		{"max(3, 1.5, 2)", 3},
+		{"max(1, 2.5)", 2.5},
+		{"min([4, 2, 8])", 2},
+		{"PI > 3.14 && PI < 3.15", true},
+		{"INF > 1000000.0", true},
+		{"NAN == NAN", false},
+		{"sqrt(NAN) == sqrt(NAN)", false},
+		{"sqrt(-1)", &object.Error{Message: "math domain error in `sqrt` on line 1"}},
+		{"log(0)", &object.Error{Message: "math domain error in `log` on line 1"}},
+		{"asin(2)", &object.Error{Message: "math domain error in `asin` on line 1"}},
+		{"pow(-8, 0.5)", &object.Error{Message: "math domain error in `pow` on line 1"}},
+		{"floor(INF)", &object.Error{Message: "`floor` of +Inf does not fit in an int on line 1"}},
+		{`abs("a")`, &object.Error{Message: "argument to `abs` must be a number. got=String on line 1"}},
+		{"abs(-9223372036854775807 - 1)", &object.Error{Message: "`abs` of -9223372036854775808 does not fit in an int on line 1"}},
+		{"pow(2, 63)", &object.Error{Message: "`pow` of 2 and 63 does not fit in an int on line 1"}},
+		{"pow(3, 40)", &object.Error{Message: "`pow` of 3 and 40 does not fit in an int on line 1"}},
+		{"min()", &object.Error{Message: "`min` expects at least one number on line 1"}},
+		{"atan2(1)", &object.Error{Message: "`atan2` expects two arguments on line 1"}},
+	}
+
+	runVmTests(t, tests)
+}
+
+// fakeClock starts at a fixed time and only moves when the script sleeps
+type fakeClock struct {
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	return c.now
+}
+
+func (c *fakeClock) Monotonic() time.Duration {
+	return c.now.Sub(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+}
+
+func (c *fakeClock) Sleep(d time.Duration) {
+	c.now = c.now.Add(d)
+}
+
+func TestHashBuiltins(t *testing.T) {
+	tests := []vmTestCase{
+		{`keys({1.5: "a"}) == [1.5]`, true},
+		{`keys({tuple(1, 2): "a"}) == [tuple(1, 2)]`, true},
+		{`sort(keys({"b": 1, "a": 2, 3: 3})) == [3, "a", "b"]`, true},
+		{`len({"a": 1, "b": 2})`, 2},
+		{`has_key({"a": null}, "a")`, true},
+		{`has_key({"a": 1}, "b")`, false},
+		{`get({"a": 1}, "a", 5)`, 1},
+		{`get({"a": 1}, "b", 5)`, 5},
+		{`get({"a": 1}, "b")`, Null},
+		{`const h = {"a": 1, "b": 2}; delete(h, "a") == {"b": 2} && h == {"a": 1, "b": 2}`, true},
+		{`delete({"a": 1}, "z") == {"a": 1}`, true},
+		{`merge({"a": 1, "b": 1}, {"b": 2}, {"c": 3}) == {"a": 1, "b": 2, "c": 3}`, true},
+		{`sort_by(entries({"b": 2, "a": 1}), first) == [["a", 1], ["b", 2]]`, true},
+		{`from_entries([["a", 1], tuple("b", 2)]) == {"a": 1, "b": 2}`, true},
+		{`const h = {1: "x", tuple(1, 2): [3]}; from_entries(entries(h)) == h`, true},
+		{`has_key({}, [1])`, &object.Error{Message: "unusable as hash key: Array on line 1"}},
+		{`get([1], 0)`, &object.Error{Message: "first argument to `get` must be hash type. got=Array on line 1"}},
+		{`merge({}, 1)`, &object.Error{Message: "argument to `merge` must be hash type. got=Integer on line 1"}},
+		{`from_entries([[1]])`, &object.Error{Message: "`from_entries` expects [key, value] pairs. got=[1] on line 1"}},
+	}
+
+	runVmTests(t, tests)
+}
+
+func TestSortBuiltins(t *testing.T) {
+	tests := []vmTestCase{
+		{`sort([3, 1, 2])`, []int{1, 2, 3}},
+		{`sort([2.5, 1, "b", 3, "a", -1.5]) == [-1.5, 1, 2.5, 3, "a", "b"]`, true},
+		{`sort([NAN, 1, INF])[1] == INF`, true},
+		{`sort(["b", "B", "a"]) == ["B", "a", "b"]`, true},
+		{`sort([1.0, 1, 1.0])[1]`, 1},
+		{`sort([])`, []int{}},
+		{`const a = [3, 1]; sort(a); a`, []int{3, 1}},
+		{`sort([1, 3, 2], func(a, b) { b - a })`, []int{3, 2, 1}},
+		{`sort(["bb", "a", "cc", "d"], func(a, b) { len(a) - len(b) }) == ["a", "d", "bb", "cc"]`, true},
+		{`sort_by(["ccc", "a", "bb"], len) == ["a", "bb", "ccc"]`, true},
+		{`sort_by([[2, "x"], [1, "y"], [2, "a"], [1, "b"]], first) == [[1, "y"], [1, "b"], [2, "x"], [2, "a"]]`, true},
+		{`mut calls = 0; sort_by([3, 2, 1, 4], func(x) { calls = calls + 1; x }); calls`, 4},
+		{`sort([1, true])`, &object.Error{Message: "`sort` cannot order Boolean on line 1"}},
+		{`sort([1, 2], func(a, b) { "x" })`, &object.Error{Message: "comparator for `sort` must return an int. got=String on line 1"}},
+		{`sort_by([1, 2], func(x) { [x] })`, &object.Error{Message: "`sort_by` cannot order Array on line 1"}},
+		{`sort([1, 2], func(a, b) { throw "cmp" })`, &object.Error{Message: "uncaught exception: cmp"}},
+	}
+
+	runVmTests(t, tests)
+}
+
+func TestRandomBuiltins(t *testing.T) {
+	tests := []vmTestCase{
+		{`seed(7); const a = [rand_int(1, 1000), rand_float(), shuffle([1, 2, 3, 4]), choice([1, 2, 3])]; seed(7); a == [rand_int(1, 1000), rand_float(), shuffle([1, 2, 3, 4]), choice([1, 2, 3])]`, true},
+		{`seed(7); rand_int(1, 1000)`, int(1 + rand.New(rand.NewSource(7)).Int63n(1000))},
+		{`rand_int(5, 5)`, 5},
+		{`const n = rand_int(-5000000000000000000, 5000000000000000000); n >= -5000000000000000000 && n <= 5000000000000000000`, true},
+		{`const f = rand_float(); f >= 0.0 && f < 1.0`, true},
+		{`const a = [1, 2, 3]; shuffle(a); a == [1, 2, 3]`, true},
+		{`len(shuffle([1, 2, 3]))`, 3},
+		{`choice([4])`, 4},
+		{`rand_int(2, 1)`, &object.Error{Message: "empty range for `rand_int`: 2 > 1 on line 1"}},
+		{`choice([])`, &object.Error{Message: "`choice` of an empty array on line 1"}},
+		{`seed("a")`, &object.Error{Message: "argument to `seed` must be int type. got=String on line 1"}},
+	}
+
+	runVmTests(t, tests)
+}
+
+func TestSeededVMs(t *testing.T) {
+	draw := func(seed int64) object.Object {
+		vm := New(compileSource(t, `[rand_int(1, 1000000), rand_int(1, 1000000)]`))
+		vm.Seed(seed)
+		if err := vm.Run(); err != nil {
+			t.Fatalf("vm error: %s", err)
+		}
+		return vm.LastPoppedStackElem()
+	}
+
+	first := draw(42)
+	if !object.Equal(first, draw(42)) {
+		t.Errorf("VMs with the same seed drew different numbers")
+	}
+
+	// seeding one VM from a script must not change the numbers of another
+	seeded := New(compileSource(t, `seed(1)`))
+	other := New(compileSource(t, `rand_int(1, 1000000)`))
+	other.Seed(42)
+	if err := seeded.Run(); err != nil {
+		t.Fatalf("vm error: %s", err)
+	}
+	if err := other.Run(); err != nil {
+		t.Fatalf("vm error: %s", err)
+	}
+	if !object.Equal(other.LastPoppedStackElem(), first.(*object.Array).Elements[0]) {
+		t.Errorf("seeding one VM changed the numbers of another")
+	}
+
+	// VMs sharing the state of a REPL session share its generator, so a seed carries over to the next line
+	globals := make([]object.Object, GlobalsSize)
+	random := rand.New(rand.NewSource(time.Now().UnixNano()))
+	lines := []*VM{
+		NewWithState(compileSource(t, `seed(42)`), globals, random),
+		NewWithState(compileSource(t, `[rand_int(1, 1000000), rand_int(1, 1000000)]`), globals, random),
+	}
+	for _, line := range lines {
+		if err := line.Run(); err != nil {
+			t.Fatalf("vm error: %s", err)
+		}
+	}
+	if !object.Equal(lines[1].LastPoppedStackElem(), first) {
+		t.Errorf("a seed did not carry over to the next VM of the session")
+	}
+}
+
+func TestTimeBuiltins(t *testing.T) {
+	saved := object.TimeSource
+	defer func() { object.TimeSource = saved }()
+
+	start := time.Date(2024, 3, 15, 12, 30, 45, 0, time.UTC)
+	tests := []vmTestCase{
+		{`now()`, int(start.UnixNano())},
+		{`monotonic()`, int(start.Sub(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)))},
+		{`const t = monotonic(); sleep(1500); monotonic() - t`, int(1500 * time.Millisecond)},
+		{`const t = now(); sleep(20); now() - t == milliseconds(20)`, true},
+		{`format_time(now(), "2006-01-02 15:04:05")`, "2024-03-15 12:30:45"},
+		{`format_time(now() + hours(36), "Jan 2 15h")`, "Mar 17 00h"},
+		{`parse_time("2024-03-15T12:30:45Z", "2006-01-02T15:04:05Z07:00") == now()`, true},
+		{`parse_time("2024-03-16", "2006-01-02") - now()`, int(11*time.Hour + 29*time.Minute + 15*time.Second)},
+		{`seconds(1.5)`, int(1500 * time.Millisecond)},
+		{`minutes(2) == seconds(120)`, true},
+		{`format_duration(hours(1) + minutes(2) + seconds(3.5))`, "1h2m3.5s"},
+		{`format_duration(milliseconds(250))`, "250ms"},
+		{`sleep(-1)`, &object.Error{Message: "negative duration for `sleep`: -1 on line 1"}},
+		{`parse_time("soon", "2006-01-02")`, &object.Error{Message: "`parse_time` failed: parsing time \"soon\" as \"2006-01-02\": cannot parse \"soon\" as \"2006\" on line 1"}},
+		{`format_time("now", "2006")`, &object.Error{Message: "first argument to `format_time` must be int type. got=String on line 1"}},
+	}
+
+	for _, tt := range tests {
+		object.TimeSource = &fakeClock{now: start}
+		runVmTests(t, []vmTestCase{tt})
+	}
+}
+
+func TestRegexBuiltins(t *testing.T) {
+	tests := []vmTestCase{
+		{`re_match("\d+", "abc 123")`, true},
+		{`re_match("^\d+$", "abc 123")`, false},
+		{`const re = re_compile("(\w+)@(\w+)"); re_match(re, "me@host")`, true},
+		{`re_find("(\w+)@(\w+)", "mail me@host now") == ["me@host", "me", "host"]`, true},
+		{`re_find("a(x)?b", "ab") == ["ab", null]`, true},
+		{`re_find("\d", "abc")`, Null},
+		{`re_find_all("(\d)(\w)", "1a 2b 3") == [["1a", "1", "a"], ["2b", "2", "b"]]`, true},
+		{`len(re_find_all("\d", "abc"))`, 0},
+		{`re_replace("(\w+)@(\w+)", "me@host", "$2 at ${1}")`, "host at me"},
+		{`re_replace(re_compile("(?P<n>\d+)"), "a1b22", "<${n}>")`, "a<1>b<22>"},
+		{`re_split(",\s*", "a, b,c") == ["a", "b", "c"]`, true},
+		{`re_compile("a+") == re_compile("a+")`, true},
+		{`format("%v", re_compile("a+"))`, "/a+/"},
+		{`re_compile("(")`, &object.Error{Message: "invalid regular expression for `re_compile`: error parsing regexp: missing closing ): `(` on line 1"}},
+		{`re_match(1, "a")`, &object.Error{Message: "first argument to `re_match` must be regex or string type. got=Integer on line 1"}},
+		{`re_split("a")`, &object.Error{Message: "`re_split` expects two arguments on line 1"}},
+	}
+
+	runVmTests(t, tests)
+}
+
+func TestJSONBuiltins(t *testing.T) {
+	tests := []vmTestCase{
+		{`json_encode({"b": [1, 2.5, true, null], "a": "x"})`, `{"a":"x","b":[1,2.5,true,null]}`},
+		{`json_encode([1.0, "<&>"])`, `[1.0,"<&>"]`},
+		{`json_encode(tuple(1, 2))`, `[1,2]`},
+		{"json_encode({\"a\": [1]}, 2)", "{\n  \"a\": [\n    1\n  ]\n}"},
+		{`json_decode("[1, 2.0, 3e2, 12345678901234567890]")[0]`, 1},
+		{`json_decode("[1, 2.0, 3e2]")[1]`, 2.0},
+		{`json_decode("[1, 2.0, 3e2]")[2]`, 300.0},
+		{`json_decode(json_encode({"a": {"b": [true, null]}}))["a"]["b"][0]`, true},
+		{`json_decode(json_encode({"n": 1.5, "s": "q"}))["n"]`, 1.5},
+		{`json_decode("null")`, Null},
+		{`json_encode(func(x) { x })`, &object.Error{Message: "`json_encode` cannot encode Closure on line 1"}},
+		{`json_encode({1: "a"})`, &object.Error{Message: "`json_encode` needs string keys. got=Integer on line 1"}},
+		{`json_encode([NAN])`, &object.Error{Message: "`json_encode` cannot encode NaN on line 1"}},
+		{`json_encode([1], 10)`, "[\n          1\n]"},
+		{`json_encode(1, 11)`, &object.Error{Message: "indent for `json_encode` is too large: 11 on line 1"}},
+		{`json_encode(1, 9223372036854775807)`, &object.Error{Message: "indent for `json_encode` is too large: 9223372036854775807 on line 1"}},
+		{`json_encode(1, repeat(" ", 11))`, &object.Error{Message: "indent for `json_encode` is too large: 11 bytes on line 1"}},
+		{`json_decode("[1,")`, &object.Error{Message: "`json_decode` invalid JSON: unexpected EOF on line 1"}},
+		{`json_decode("1 2")`, &object.Error{Message: "`json_decode` invalid JSON: unexpected data after the value on line 1"}},
+	}
+
+	runVmTests(t, tests)
+}
+
+func TestIOBuiltins(t *testing.T) {
+	data := t.TempDir()
+	out := t.TempDir()
+	outside := t.TempDir()
+	os.WriteFile(filepath.Join(data, "in.txt"), []byte("one\ntwo\r\nthree\n"), 0o644)
+	os.WriteFile(filepath.Join(outside, "secret.txt"), []byte("secret"), 0o644)
+	os.Symlink(outside, filepath.Join(data, "link"))
+	os.Symlink(filepath.Join(outside, "planted.txt"), filepath.Join(out, "dangling"))
+	os.Symlink(outside, filepath.Join(out, "dir"))
+
+	capabilities := object.NewCapabilities(strings.NewReader("first\nsecond"))
+	capabilities.AllowRead(data)
+	capabilities.AllowRead(out)
+	capabilities.AllowWrite(out)
+
+	tests := []vmTestCase{
+		{fmt.Sprintf(`read_file("%s/in.txt")`, data), "one\ntwo\r\nthree\n"},
+		{fmt.Sprintf(`len(read_lines("%s/in.txt"))`, data), 3},
+		{fmt.Sprintf(`read_lines("%s/in.txt")[1]`, data), "two"},
+		{fmt.Sprintf(`exists("%s/in.txt")`, data), true},
+		{fmt.Sprintf(`exists("%s/missing.txt")`, data), false},
+		{fmt.Sprintf(`list_dir("%s") == ["in.txt", "link"]`, data), true},
+		{fmt.Sprintf(`write_file("%[1]s/a.txt", "a"); append_file("%[1]s/a.txt", "b"); read_file("%[1]s/a.txt")`, out), "ab"},
+		{fmt.Sprintf(`write_file("%[1]s/a.txt", "c"); read_file("%[1]s/a.txt")`, out), "c"},
+		{`read_line()`, "first"},
+		{`input("")`, "second"},
+		{`read_line()`, Null},
+		{
+			fmt.Sprintf(`read_file("%s/secret.txt")`, outside),
+			&object.Error{Message: fmt.Sprintf("`read_file` is not allowed to read %s/secret.txt, run with --allow-read on line 1", outside)},
+		},
+		{
+			fmt.Sprintf(`read_file("%s/link/secret.txt")`, data),
+			&object.Error{Message: fmt.Sprintf("`read_file` is not allowed to read %s/link/secret.txt, run with --allow-read on line 1", data)},
+		},
+		{
+			fmt.Sprintf(`read_file("%s/../secret.txt")`, out),
+			&object.Error{Message: fmt.Sprintf("`read_file` is not allowed to read %s/../secret.txt, run with --allow-read on line 1", out)},
+		},
+		{
+			fmt.Sprintf(`write_file("%s/b.txt", "b")`, data),
+			&object.Error{Message: fmt.Sprintf("`write_file` is not allowed to write %s/b.txt, run with --allow-write on line 1", data)},
+		},
+		{
+			fmt.Sprintf(`write_file("%s/dangling", "b")`, out),
+			&object.Error{Message: fmt.Sprintf("`write_file` will not write through the symlink %s/dangling on line 1", out)},
+		},
+		{
+			fmt.Sprintf(`append_file("%s/dangling", "b")`, out),
+			&object.Error{Message: fmt.Sprintf("`append_file` will not write through the symlink %s/dangling on line 1", out)},
+		},
+		{
+			fmt.Sprintf(`write_file("%s/dir/b.txt", "b")`, out),
+			&object.Error{Message: fmt.Sprintf("`write_file` is not allowed to write %s/dir/b.txt, run with --allow-write on line 1", out)},
+		},
+		{
+			fmt.Sprintf(`read_file("%s/missing.txt")`, data),
+			&object.Error{Message: "`read_file` failed: no such file or directory on line 1"},
+		},
+	}
+
+	runVmTestsWithIO(t, tests, capabilities)
+
+	// the grants belong to the VM they were given to
+	runVmTests(t, []vmTestCase{{
+		fmt.Sprintf(`read_file("%s/in.txt")`, data),
+		&object.Error{Message: fmt.Sprintf("`read_file` is not allowed to read %s/in.txt, run with --allow-read on line 1", data)},
+	}})
+
+	for _, name := range []string{"planted.txt", "b.txt"} {
+		if _, err := os.Lstat(filepath.Join(outside, name)); err == nil {
+			t.Errorf("a symlink let a script write %s outside the granted directories", name)
+		}
+	}
+}
+
+func TestModules(t *testing.T) {
+	dir := t.TempDir()
+	os.Mkdir(filepath.Join(dir, "lib"), 0o755)
+	os.WriteFile(filepath.Join(dir, "lib", "shapes.qk"), []byte(`
+import { square } from "square.qk";
+export const pi = 3;
+export const made = rand_int(0, 1000000000);
+export const area = func(r) { pi * square(r) };
+const hidden = 1;
+`), 0o644)
+	os.WriteFile(filepath.Join(dir, "lib", "square.qk"), []byte("export const square = func(x) { x * x };"), 0o644)
+	os.WriteFile(filepath.Join(dir, "a.qk"), []byte(`import "b.qk"; export const a = 1;`), 0o644)
+	os.WriteFile(filepath.Join(dir, "b.qk"), []byte(`import "a.qk"; export const b = 2;`), 0o644)
+	os.WriteFile(filepath.Join(dir, "broken.qk"), []byte("const a = 1;\nexport func() {};"), 0o644)
+	// a directory of the program named std is not the standard library
+	os.Mkdir(filepath.Join(dir, "lib", "std"), 0o755)
+	os.WriteFile(filepath.Join(dir, "lib", "std", "list.qk"), []byte("export const sum = func(a) { 100 };"), 0o644)
+	os.WriteFile(filepath.Join(dir, "lib", "sums.qk"), []byte(`
+import "std/list.qk" as mine;
+import "std:list";
+export const total = mine.sum([1]) + list.sum([1, 2]);
+`), 0o644)
+
+	tests := []vmTestCase{
+		{fmt.Sprintf(`import "%s/lib/shapes.qk"; shapes.area(2)`, dir), 12},
+		{fmt.Sprintf(`import "%s/lib/shapes.qk" as s; s.pi`, dir), 3},
+		{fmt.Sprintf(`import { area, pi } from "%s/lib/shapes.qk"; area(pi)`, dir), 27},
+		{fmt.Sprintf(`const pi = 1; import "%s/lib/shapes.qk" as s; [pi, s.pi]`, dir), []int{1, 3}},
+		{fmt.Sprintf(`import "%[1]s/lib/shapes.qk" as s; import "%[1]s/lib/shapes.qk" as t; s.made == t.made`, dir), true},
+		{fmt.Sprintf(`import "%s/lib/sums.qk"; sums.total`, dir), 103},
+		{fmt.Sprintf(`import "%s/lib/shapes.qk" as s; format("%%v", s)`, dir), "module(shapes)"},
+		{
+			fmt.Sprintf(`import "%s/lib/shapes.qk" as s; s.hidden`, dir),
+			&object.Error{Message: "module shapes has no export hidden"},
+		},
+	}
+
+	runVmTests(t, tests)
+
+	errorTests := []struct {
+		source   string
+		expected string
+	}{
+		{fmt.Sprintf(`import "%s/a.qk";`, dir), "import cycle: a.qk -> b.qk -> a.qk on line 1"},
+		{fmt.Sprintf(`import { hidden } from "%s/lib/shapes.qk";`, dir), "module shapes has no export hidden on line 1"},
+		{fmt.Sprintf(`import "%s/missing.qk";`, dir), "cannot import missing.qk: no such file or directory on line 1"},
+		{`import "std:missing";`, "cannot import missing.qk: file does not exist on line 1"},
+		{fmt.Sprintf(`import "%s/broken.qk";`, dir), "broken.qk line 2: export must be followed by a const, struct or enum declaration on line 1"},
+		{fmt.Sprintf(`if (true) { import "%s/a.qk"; }`, dir), "import must be at the top level of a file on line 1"},
+		{"func() { export const x = 1; }", "export must be at the top level of a file on line 1"},
+	}
+
+	for _, tt := range errorTests {
+		comp := compiler.New()
+		err := comp.Compile(parse(tt.source))
+		if err == nil {
+			t.Fatalf("expected compiler error %q, got nil", tt.expected)
+		}
+
+		if err.Error() != tt.expected {
+			t.Errorf("wrong compiler error. want=%q, got=%q", tt.expected, err.Error())
+		}
+	}
+
+	// the lines of a REPL session share the modules they import, which run once like in a program
+	constants := []object.Object{}
+	globals := make([]object.Object, GlobalsSize)
+	symbolTable := compiler.NewSymbolTable()
+	for i, name := range object.BuiltinNames() {
+		symbolTable.DefineBuiltin(i, name)
+	}
+	modules := compiler.NewModules()
+	lines := []string{
+		fmt.Sprintf(`import "%s/lib/shapes.qk" as s;`, dir),
+		fmt.Sprintf(`import "%s/lib/shapes.qk" as t; s.made == t.made`, dir),
+	}
+	for _, line := range lines {
+		comp := compiler.NewWithState(symbolTable, constants, modules)
+		if err := comp.Compile(parse(line)); err != nil {
+			t.Fatalf("compiler error: %s", err)
+		}
+		constants = comp.Bytecode().Constants
+		machine := NewWithGlobalStore(comp.Bytecode(), globals)
+		if err := machine.Run(); err != nil {
+			t.Fatalf("vm error: %s", err)
+		}
+		if line == lines[1] {
+			testExpectedObject(t, true, machine.LastPoppedStackElem())
+		}
+	}
+}
+
+func TestStructs(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "geo.qk"), []byte("export struct Point { x, y }"), 0o644)
+
+	tests := []vmTestCase{
+		{`struct P { x, y } const p = P{x: 1, y: 2}; p.x + p.y`, 3},
+		{`struct P { x, y } mut p = P{y: 2, x: 1}; p.x + p.y`, 3},
+		{`struct P { x, y } P{x: 1}.y == null`, true},
+		{`struct P { x } mut p = P{x: 1}; p.x = 5; p.x`, 5},
+		{`struct P { x } const p = P{x: 1}; p.x = p.x + 1; p.x`, 2},
+		{`struct C { n } const c = C{n: 0}; const inc = func() { c.n = c.n + 1; }; inc(); inc(); c.n`, 2},
+		{`struct P { x, y } format("%v", P{y: "a", x: [1]})`, "P{x: [1], y: a}"},
+		{`struct P { x } format("%v", P)`, "struct P {x}"},
+		{`struct P { x } P{x: [1]} == P{x: [1]}`, true},
+		{`struct P { x } struct Q { x } P{x: 1} == Q{x: 1}`, false},
+		{fmt.Sprintf(`import "%s/geo.qk"; const p = geo.Point{x: 1, y: 2}; p.y`, dir), 2},
+		{`struct P { x } mut p = P{x: 1}; p.z`, &object.Error{Message: "struct P has no field z"}},
+		{`mut p = 1; p.x = 2;`, &object.Error{Message: "field assignment not supported: Integer"}},
+		{`mut T = 1; T{x: 1}`, &object.Error{Message: "not a struct type: Integer"}},
+		{`struct P { x } mut T = P; T{z: 1}`, &object.Error{Message: "struct P has no field z"}},
+	}
+
+	runVmTests(t, tests)
+}
+
+func TestEnums(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "shapes.qk"), []byte("export enum Shape { Dot, Circle(r) }"), 0o644)
+
+	tests := []vmTestCase{
+		{`enum Color { Red, Green } Color.Red == Color.Red`, true},
+		{`enum Color { Red, Green } Color.Red == Color.Green`, false},
+		{`enum Color { Red } enum Other { Red } Color.Red == Other.Red`, false},
+		{`enum Color { Red, Green } format("%v %v", Color.Green, Color)`, "Color.Green enum Color {Red, Green}"},
+		{`enum Result { Ok(value), Err(msg) } format("%v", Result.Ok(3))`, "Result.Ok(3)"},
+		{`enum Result { Ok(value), Err(msg) } Result.Ok(3) == Result.Ok(3)`, true},
+		{`enum Result { Ok(value), Err(msg) } Result.Ok(3) == Result.Err(3)`, false},
+		{`enum Result { Ok(value) } Result.Ok([1, 2]) == Result.Ok([1, 2])`, true},
+		{`enum Result { Ok(value) } const r = Result.Ok(3); r.value + r["value"]`, 6},
+		{`enum Pair { Of(a, b) } const mk = Pair.Of; mk(1, 2).b`, 2},
+		{`enum Color { Red, Green } const names = {Color.Red: "red", Color.Green: "green"}; names[Color.Green]`, "green"},
+		{`enum Result { Ok(value) } const h = {Result.Ok(1): "one"}; h[Result.Ok(1)]`, "one"},
+		{`enum State { Idle, Busy } mut s = State.Idle; s = State.Busy; s == State.Busy`, true},
+		{fmt.Sprintf(`import "%s/shapes.qk"; shapes.Shape.Circle(2).r`, dir), 2},
+		{`enum Color { Red } mut c = Color; c.Purple`, &object.Error{Message: "enum Color has no variant Purple"}},
+		{`enum Result { Ok(value) } Result.Ok(1).nope`, &object.Error{Message: "variant Result.Ok has no field nope"}},
+		{`enum Result { Ok(value) } Result.Ok(1, 2)`, &object.Error{Message: "wrong number of arguments to Result.Ok. want=1, got=2 on line 1"}},
+		{`enum Result { Ok(value) } format("%v", Result.Ok)`, "Result.Ok"},
+		{`enum Color { Red } Color.Red(1)`, &object.Error{Message: "calling non-function Color.Red"}},
+		{`enum Result { Ok(value) } Result.Ok({"a": 1}).value["a"]`, 1},
+		{`enum Result { Ok(value) } Result.Ok(null).value`, Null},
+		{`enum Result { Ok(value) } const r = Result.Ok(func(x) { x * 2 }); r.value(4)`, 8},
+		{`enum Result { Ok(value) } Result.Ok([1, 2]).value`, []int{1, 2}},
+		{`enum Result { Ok(value) } {Result.Ok({}): 1}`, &object.Error{Message: "unusable as hash key: Variant"}},
+		{`enum Result { Ok(value) } {}[Result.Ok(Result.Ok(null))]`, &object.Error{Message: "unusable as hash key: Variant"}},
+		{`enum Result { Ok(value) } tuple(Result.Ok(null))`, &object.Error{Message: "unusable as tuple element: Variant on line 1"}},
+		{`enum Result { Ok(value) } match (Result.Ok([1])) { 1 => 1, 2 => 2, 3 => 3, 4 => 4, _ => 0 }`, 0},
+	}
+
+	runVmTests(t, tests)
+}
+
+func TestMatch(t *testing.T) {
+	tests := []vmTestCase{
+		{`match (2) { 1 => "one", 2 => "two", _ => "many" }`, "two"},
+		{`match (5) { 1 => "one", 2 => "two", _ => "many" }`, "many"},
+		{`match (5) { 1 => "one" }`, Null},
+		{`match (-1.5) { 1.5 => 1, -1.5 => 2 }`, 2},
+		{`match (null) { null => 1, _ => 2 }`, 1},
+		{`match (1) { 1.0 => "float", 1 => "int" }`, "int"},
+		{`match ([1, 2]) { [a] => a, [a, b] => a + b }`, 3},
+		{`match ([1, [2, 3]]) { [a, [b, c]] => a + b + c }`, 6},
+		{`match (tuple(1, 2)) { [_, b] => b }`, 2},
+		{`match ("ab") { [a, b] => 1, _ => 2 }`, 2},
+		{`match ({"k": 5, "z": 1}) { {"k": v} if v > 10 => 0, {"k": v} => v }`, 5},
+		{`match ({"a": 1}) { {"b": v} => v, {} => "any hash" }`, "any hash"},
+		{`match ({1: [2]}) { {1: [x]} => x }`, 2},
+		{`match (7) { x if x > 3 => { mut y = x * 2; y } }`, 14},
+		{`match (2) { x if x > 3 => "big", x => "small" }`, "small"},
+		{`match (1) { 1 => { mut y = 1; } }`, Null},
+		{`mut x = 1; match (5) { x => x }; x`, 1},
+		{`mut r = [match (1) { 1 => { mut q = 1; } }]; r == [null]`, true},
+		{`enum R { Ok(v), Err(m) } match (R.Err("bad")) { R.Ok(v) => v, R.Err(m) => m }`, "bad"},
+		{`enum C { Red, Green } match (C.Green) { C.Red => 1, C.Green => 2 }`, 2},
+		{`enum C { Red, Green } match (C.Green) { C.Red() => 1, C.Green() => 2 }`, 2},
+		{`enum O { Some(v), None } match (O.Some([1, 2])) { O.Some([a, b]) => a + b, O.None => 0 }`, 3},
+		{`enum O { Some(v) } match (3) { O.Some(v) => v, _ => 0 }`, 0},
+		{`const f = func(x) { match (x) { [a, [b, c]] => a + b + c, _ => 0 } }; f([1, [2, 3]]) + f([1, 2])`, 6},
+		{`const fs = [1, 2].map(func(x) { match (x) { n => func() { n } } }); fs[0]() + fs[1]()`, 3},
+		{`match (match (1) { 1 => 2 }) { 2 => "nested" }`, "nested"},
+		// enough literal arms for a jump table
+		{`match ("c") { "a" => 1, "b" => 2, "c" => 3, "d" => 4 }`, 3},
+		{`match ("z") { "a" => 1, "b" => 2, "c" => 3, "d" => 4 }`, Null},
+		{`match (9) { 1 => 1, 2 => 2, 3 => 3, 4 => 4, n => n * 10 }`, 90},
+		{`match ([1]) { 1 => 1, 2 => 2, 3 => 3, 4 => 4, _ => "array" }`, "array"},
+		{`match (2) { 1 => 1, 2 => "first", 2 => "second", 4 => 4 }`, "first"},
+		{`const f = func(x) { match (x) { 1 => 10, 2 => 20, 3 => 30, 4 => 40 } }; f(4) + f(1)`, 50},
+		{`enum R { Ok(v) } mut S = R; match (R.Ok(1)) { S.Ok(a, b) => 1 }`, &object.Error{Message: "wrong number of fields in pattern for R.Ok. want=1, got=2"}},
+	}
+
+	runVmTests(t, tests)
+}
+
+func TestMethodCalls(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "geo.qk"), []byte("export const area = func(r) { r * r };"), 0o644)
+
+	tests := []vmTestCase{
+		{`[1, 2, 3].len()`, 3},
+		{`"honk".upper() == "HONK"`, true},
+		{`const double = func(x) { x * 2 }; [1, 2].map(double).reduce(func(a, b) { a + b })`, 6},
+		{`const add = func(a, b) { a + b }; 5.add(2)`, 7},
+		{`-3.abs()`, -3},
+		{`(-3).abs()`, 3},
+		{`[3, 1, 2].sort().first()`, 1},
+		{`struct C { n, next } const c = C{n: 1, next: func(x) { x + 1 }}; c.next(c.n)`, 2},
+		{`const h = {"len": func() { 42 }}; [h.len(), [1].len()] == [42, 1]`, true},
+		{`const f = func() { const twice = func(x) { x * 2 }; func(y) { y.twice() } }; f()(4)`, 8},
+		{fmt.Sprintf(`import "%s/geo.qk"; geo.area(3)`, dir), 9},
+		{`mut f = func(a) { 1 }; const r = func() { f = func(a) { 2 }; [0] }; r().f()`, 1},
+		{`mut m = null; m?.len()`, Null},
+		{`mut m = [1, 2]; m?.len()`, 2},
+		{`mut h = {"f": func(x) { x + 1 }}; h?.f(1)`, 2},
+		{`mut called = false; const mark = func() { called = true; 1 }; mut m = null; m?.len(mark()); called`, false},
+		{`mut x = 5; x.nope()`, &object.Error{Message: "Integer has no method nope"}},
+		{`[1].join(5)`, &object.Error{Message: "second argument to `join` must be string type. got=Integer on line 1"}},
+	}
+
+	runVmTests(t, tests)
+}
+
+func TestBlockScoping(t *testing.T) {
+	tests := []vmTestCase{
+		{"mut x = 1; if (true) { mut x = 2; } x", 1},
+		{"if (true) { mut y = 1; } if (true) { mut y = 2; y }", 2},
+		{"if (true) { mut y = 1; }", Null},
+		{"mut x = 1; if (true) { x = 2; } x", 2},
+		{"if (false) { 1 } else if (true) { mut z = 3; z } else { mut z = 4; z }", 3},
+		{"mut i = 0; mut total = 0; for (i < 3) { mut sq = i * i; total = total + sq; i = i + 1; } total", 5},
+		{"func() { mut a = 1; if (true) { mut b = 2; if (true) { mut c = 3; a + b + c } } }()", 6},
+		{"func() { mut a = 1; if (true) { mut a = 2; } if (true) { mut b = 3; } a }()", 1},
+		{"mut e = 1; try { throw 2; } catch (e) { e; } e", 1},
+		{
+			// every iteration declares a new variable, so each closure sees its own
+			source: `
+			mut fns = [];
+			mut i = 0;
+			for (i < 3) {
+				const j = i;
+				mut k = i * 10;
+				fns = append(fns, func() { j + k });
+				i = i + 1;
+			}
+			fns[0]() + fns[2]();
+			`,
+			expected: 22,
+		},
+		{
+			// the slot of a captured block variable can be reused by a sibling block without affecting the closure
+			source: `
+			const f = func() {
+				mut fns = [];
+				if (true) { mut a = 1; fns = append(fns, func() { a = a + 1; a }); }
+				if (true) { mut b = 10; }
+				fns[0]();
+			}
+			f();
+			`,
+			expected: 2,
+		},
 	}
 
 	runVmTests(t, tests)
@@ -587,6 +1578,12 @@ func TestRecursiveFibonacci(t *testing.T) {
 
 func runVmTests(t *testing.T, tests []vmTestCase) {
 	t.Helper()
+	runVmTestsWithIO(t, tests, nil)
+}
+
+// runVmTestsWithIO runs the tests in VMs given capabilities, unless it is nil
+func runVmTestsWithIO(t *testing.T, tests []vmTestCase, capabilities *object.Capabilities) {
+	t.Helper()
 
 	for _, tt := range tests {
 		program := parse(tt.source)
@@ -599,7 +1596,22 @@ func runVmTests(t *testing.T, tests []vmTestCase) {
 		}
 
 		vm := New(comp.Bytecode())
+		if capabilities != nil {
+			vm.SetIO(capabilities)
+		}
 		err = vm.Run()
+
+		// an expected error object means the program must stop with that runtime error
+		if expectedErr, ok := tt.expected.(*object.Error); ok {
+			if err == nil {
+				t.Fatalf("expected vm error %q, but got nil", expectedErr.Message)
+			}
+			if err.Error() != expectedErr.Message {
+				t.Errorf("wrong vm error. want=%q, got=%q", expectedErr.Message, err.Error())
+			}
+			continue
+		}
+
 		if err != nil {
 			t.Fatalf("vm error: %s", err)
 		}
@@ -702,6 +1714,16 @@ func parse(source string) *ast.Program {
 	return p.ParseProgram()
 }
 
+func compileSource(t *testing.T, source string) *compiler.Bytecode {
+	t.Helper()
+
+	comp := compiler.New()
+	if err := comp.Compile(parse(source)); err != nil {
+		t.Fatalf("compiler error: %s", err)
+	}
+	return comp.Bytecode()
+}
+
 func testIntegerObject(expected int64, actual object.Object) error {
 	result, ok := actual.(*object.Integer)
 	if !ok {